@@ -0,0 +1,130 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// A token to verify along with the provider for its key
+type BatchItem struct {
+	Token    string
+	Provider KeyProvider
+}
+
+// Outcome of verifying a single token in a batch
+type BatchResult struct {
+	Payload []byte
+	Header  Header
+	Err     error
+}
+
+// Verify a batch of tokens concurrently against a single key provider,
+// using one worker per CPU. Results are returned in the same order as
+// tokens; the error is only non-nil if the batch could not be run.
+func VerifyBatch(tokens []string, kp KeyProvider, opts ...VerifyOption) ([]BatchResult, error) {
+	return VerifyBatchContext(context.Background(), tokens, kp, opts...)
+}
+
+// As VerifyBatch, stopping early if ctx is done. Tokens that were not
+// verified before cancellation have Err set to ctx.Err(), which is also
+// returned.
+func VerifyBatchContext(ctx context.Context, tokens []string, kp KeyProvider, opts ...VerifyOption) ([]BatchResult, error) {
+	if kp == nil {
+		return nil, errors.New("No key provider for batch")
+	}
+
+	config := newVerifyConfig(opts)
+	results := make([]BatchResult, len(tokens))
+	err := runBatch(ctx, results, runtime.NumCPU(), func(index int, result *BatchResult) {
+		result.Header, result.Payload, result.Err = verifyAndDecode([]byte(tokens[index]), kp, config)
+	})
+	return results, err
+}
+
+// Verify a batch of tokens concurrently, each against its own key
+// provider. At most parallelism verifications run at once; values less
+// than 1 use one worker per CPU. Results are returned in the same order
+// as items.
+func VerifyBatchItems(items []BatchItem, parallelism int) []BatchResult {
+	if parallelism < 1 {
+		parallelism = runtime.NumCPU()
+	}
+
+	results := make([]BatchResult, len(items))
+	runBatch(context.Background(), results, parallelism, func(index int, result *BatchResult) {
+		item := items[index]
+		if item.Provider == nil {
+			result.Err = errors.New("No key provider for token")
+			return
+		}
+		result.Header, result.Payload, result.Err = VerifyAndDecodeWithHeader(item.Token, item.Provider)
+	})
+	return results
+}
+
+// fill in results using a pool of parallelism workers
+func runBatch(ctx context.Context, results []BatchResult, parallelism int, verify func(index int, result *BatchResult)) error {
+	if parallelism > len(results) {
+		parallelism = len(results)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(parallelism)
+	for i := 0; i < parallelism; i++ {
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				if err := ctx.Err(); err != nil {
+					results[index].Err = err
+					continue
+				}
+				verify(index, &results[index])
+			}
+		}()
+	}
+
+	next := 0
+dispatch:
+	for ; next < len(results); next++ {
+		select {
+		case jobs <- next:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// anything never handed to a worker was skipped
+	for ; next < len(results); next++ {
+		results[next].Err = ctx.Err()
+	}
+	return ctx.Err()
+}
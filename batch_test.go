@@ -0,0 +1,154 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestVerifyBatchItems(t *testing.T) {
+	hmacKey := []byte("batch-secret")
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	hmacToken, err := Sign(signTestPayload, ALG_HS256, hmacKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	ecToken, err := Sign(signTestPayload, ALG_ES256, ecKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	items := []BatchItem{
+		{Token: hmacToken, Provider: ProviderFromKey(hmacKey)},
+		{Token: ecToken, Provider: ProviderFromKey(&ecKey.PublicKey)},
+		{Token: hmacToken, Provider: ProviderFromKey([]byte("wrong"))},
+		{Token: "not.a.jws.token", Provider: ProviderFromKey(hmacKey)},
+		{Token: ecToken},
+	}
+
+	for _, parallelism := range []int{0, 1, 2, 16} {
+		results := VerifyBatchItems(items, parallelism)
+		if len(results) != len(items) {
+			t.Fatalf("Expected %d results. Got %d", len(items), len(results))
+		}
+
+		for i, expectOK := range []bool{true, true, false, false, false} {
+			if expectOK {
+				if results[i].Err != nil {
+					t.Fatalf("Item %d: %v", i, results[i].Err)
+				}
+				if !bytes.Equal(results[i].Payload, signTestPayload) {
+					t.Fatalf("Item %d: unexpected payload: %v", i, results[i].Payload)
+				}
+			} else if results[i].Err == nil {
+				t.Fatalf("Item %d verified unexpectedly", i)
+			}
+		}
+
+		if results[1].Header.Alg != ALG_ES256 {
+			t.Fatalf("Unexpected header: %+v", results[1].Header)
+		}
+	}
+}
+
+func TestVerifyBatchItems_Empty(t *testing.T) {
+	if results := VerifyBatchItems(nil, 4); len(results) != 0 {
+		t.Fatalf("Unexpected results: %v", results)
+	}
+}
+
+func TestVerifyBatch(t *testing.T) {
+	key := []byte("batch-secret")
+	token, err := Sign(signTestPayload, ALG_HS256, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	none, err := Sign(signTestPayload, ALG_NONE, NoneKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	tokens := []string{token, "garbage", none, token}
+	results, err := VerifyBatch(tokens, ProviderFromKey(key), WithDeniedAlgorithms(ALG_NONE))
+	if err != nil {
+		t.Fatal("VerifyBatch: ", err)
+	}
+	if len(results) != len(tokens) {
+		t.Fatalf("Expected %d results. Got %d", len(tokens), len(results))
+	}
+	for _, i := range []int{0, 3} {
+		if results[i].Err != nil {
+			t.Fatalf("Token %d: %v", i, results[i].Err)
+		}
+		if !bytes.Equal(results[i].Payload, signTestPayload) {
+			t.Fatalf("Token %d: unexpected payload: %v", i, results[i].Payload)
+		}
+	}
+	if results[1].Err == nil {
+		t.Fatal("Malformed token verified")
+	}
+	if !errors.Is(results[2].Err, ErrAlgorithmDenied) {
+		t.Fatalf("Expected options to apply. Got %v", results[2].Err)
+	}
+
+	if _, err := VerifyBatch(tokens, nil); err == nil {
+		t.Fatal("Expected an error without a key provider")
+	}
+}
+
+func TestVerifyBatchContext_Canceled(t *testing.T) {
+	key := []byte("batch-secret")
+	token, err := Sign(signTestPayload, ALG_HS256, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tokens := []string{token, token, token}
+	results, err := VerifyBatchContext(ctx, tokens, ProviderFromKey(key))
+	if err != context.Canceled {
+		t.Fatalf("Expected context.Canceled. Got %v", err)
+	}
+	if len(results) != len(tokens) {
+		t.Fatalf("Expected %d results. Got %d", len(tokens), len(results))
+	}
+	for i, result := range results {
+		if result.Err != context.Canceled {
+			t.Fatalf("Token %d: expected context.Canceled. Got %v", i, result.Err)
+		}
+	}
+}
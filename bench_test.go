@@ -0,0 +1,163 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+var benchPayloadSizes = []int{64, 1024, 64 * 1024}
+
+var (
+	benchKeysOnce sync.Once
+	benchRSAKey   *rsa.PrivateKey
+	benchECKey    *ecdsa.PrivateKey
+	benchHMACKey  = []byte("0123456789abcdef0123456789abcdef")
+)
+
+// generate keys once so key generation isn't measured
+func benchKeys(b *testing.B, alg Algorithm) (crypto.PrivateKey, crypto.PublicKey) {
+	benchKeysOnce.Do(func() {
+		var err error
+		benchRSAKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic(err)
+		}
+		benchECKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	switch alg {
+	case ALG_HS256:
+		return benchHMACKey, benchHMACKey
+	case ALG_RS256, ALG_PS256:
+		return benchRSAKey, &benchRSAKey.PublicKey
+	case ALG_ES256:
+		return benchECKey, &benchECKey.PublicKey
+	}
+
+	b.Fatalf("No benchmark key for %s", alg)
+	return nil, nil
+}
+
+func benchmarkSign(b *testing.B, alg Algorithm) {
+	privKey, _ := benchKeys(b, alg)
+	for _, size := range benchPayloadSizes {
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			payload := make([]byte, size)
+			b.ReportAllocs()
+			b.SetBytes(int64(size))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := Sign(payload, alg, privKey); err != nil {
+					b.Fatal("Sign: ", err)
+				}
+			}
+		})
+	}
+}
+
+func benchmarkVerify(b *testing.B, alg Algorithm) {
+	privKey, pubKey := benchKeys(b, alg)
+	kp := ProviderFromKey(pubKey)
+	for _, size := range benchPayloadSizes {
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			jws, err := Sign(make([]byte, size), alg, privKey)
+			if err != nil {
+				b.Fatal("Sign: ", err)
+			}
+
+			b.ReportAllocs()
+			b.SetBytes(int64(size))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := VerifyAndDecode(jws, kp); err != nil {
+					b.Fatal("Verify: ", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkVerifyBytes_HS256(b *testing.B) {
+	privKey, pubKey := benchKeys(b, ALG_HS256)
+	kp := ProviderFromKey(pubKey)
+	for _, size := range benchPayloadSizes {
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			jws, err := Sign(make([]byte, size), ALG_HS256, privKey)
+			if err != nil {
+				b.Fatal("Sign: ", err)
+			}
+			token := []byte(jws)
+
+			b.ReportAllocs()
+			b.SetBytes(int64(size))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := VerifyAndDecodeBytesWithHeader(token, kp); err != nil {
+					b.Fatal("Verify: ", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSign_HS256(b *testing.B) { benchmarkSign(b, ALG_HS256) }
+func BenchmarkSign_RS256(b *testing.B) { benchmarkSign(b, ALG_RS256) }
+func BenchmarkSign_ES256(b *testing.B) { benchmarkSign(b, ALG_ES256) }
+func BenchmarkSign_PS256(b *testing.B) { benchmarkSign(b, ALG_PS256) }
+
+func BenchmarkSignTo_HS256(b *testing.B) {
+	privKey, _ := benchKeys(b, ALG_HS256)
+	for _, size := range append(benchPayloadSizes, 1024*1024) {
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			payload := make([]byte, size)
+			header := Header{Alg: ALG_HS256}
+			b.ReportAllocs()
+			b.SetBytes(int64(size))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := SignTo(io.Discard, payload, header, privKey); err != nil {
+					b.Fatal("SignTo: ", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkVerify_HS256(b *testing.B) { benchmarkVerify(b, ALG_HS256) }
+func BenchmarkVerify_RS256(b *testing.B) { benchmarkVerify(b, ALG_RS256) }
+func BenchmarkVerify_ES256(b *testing.B) { benchmarkVerify(b, ALG_ES256) }
+func BenchmarkVerify_PS256(b *testing.B) { benchmarkVerify(b, ALG_PS256) }
@@ -0,0 +1,178 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Assembles and signs a JWT, always stamping iat and exp so tokens
+// can't be issued without an expiry
+type TokenBuilder struct {
+	payload  interface{}
+	issuer   string
+	subject  string
+	audience []string
+	ttl      time.Duration
+	header   Header
+	now      func() time.Time
+}
+
+// start building a JWT
+func Builder() *TokenBuilder {
+	return &TokenBuilder{now: time.Now}
+}
+
+// Application claims, which must marshal to a JSON object. Registered
+// claims set on the builder take precedence over those in v.
+func (b *TokenBuilder) Payload(v interface{}) *TokenBuilder {
+	b.payload = v
+	return b
+}
+
+// set the iss claim
+func (b *TokenBuilder) Issuer(s string) *TokenBuilder {
+	b.issuer = s
+	return b
+}
+
+// set the aud claim
+func (b *TokenBuilder) Audience(a ...string) *TokenBuilder {
+	b.audience = append([]string(nil), a...)
+	return b
+}
+
+// set the sub claim
+func (b *TokenBuilder) Subject(s string) *TokenBuilder {
+	b.subject = s
+	return b
+}
+
+// Lifetime of the token from the time it is signed. Required.
+func (b *TokenBuilder) TTL(d time.Duration) *TokenBuilder {
+	b.ttl = d
+	return b
+}
+
+// JWS header for the token. The signature algorithm is taken from
+// h.Alg.
+func (b *TokenBuilder) Header(h Header) *TokenBuilder {
+	b.header = h
+	return b
+}
+
+// Serialize the claims and sign them with key
+func (b *TokenBuilder) Sign(key crypto.PrivateKey) (string, error) {
+	if b.ttl <= 0 {
+		return "", errors.New("Token TTL must be positive")
+	}
+	if b.header.Alg == "" {
+		return "", errors.New("No signature algorithm in token header")
+	}
+
+	claims := make(map[string]interface{})
+	if b.payload != nil {
+		data, err := json.Marshal(b.payload)
+		if err != nil {
+			return "", fmt.Errorf("Failed to encode payload: %v", err)
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+			return "", errors.New("Token payload must be a JSON object")
+		}
+		for name, value := range fields {
+			claims[name] = value
+		}
+	}
+
+	if b.issuer != "" {
+		claims["iss"] = b.issuer
+	}
+	if b.subject != "" {
+		claims["sub"] = b.subject
+	}
+	if len(b.audience) > 0 {
+		claims["aud"] = Audience(b.audience)
+	}
+
+	now := b.now()
+	claims["iat"] = NewNumericDate(now)
+	claims["exp"] = NewNumericDate(now.Add(b.ttl))
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode payload: %v", err)
+	}
+
+	return SignWithHeader(payload, b.header, key)
+}
+
+// Supplies additional claims, such as roles or groups, for a token
+// about to be issued
+type ClaimsEnricher interface {
+	EnrichClaims(ctx context.Context, base StandardClaims) (map[string]interface{}, error)
+}
+
+// Sign a JWT carrying base merged with the claims returned by enricher
+// (which may be nil). The enricher can add claims but not replace any
+// set in base; attempting to do so is an error.
+func BuildToken(ctx context.Context, base StandardClaims, enricher ClaimsEnricher, header Header, key crypto.PrivateKey) (string, error) {
+	data, err := json.Marshal(base)
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode claims: %v", err)
+	}
+
+	var claims map[string]interface{}
+	err = json.Unmarshal(data, &claims)
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode claims: %v", err)
+	}
+
+	if enricher != nil {
+		extra, err := enricher.EnrichClaims(ctx, base)
+		if err != nil {
+			return "", fmt.Errorf("Failed to enrich claims: %w", err)
+		}
+		for name, value := range extra {
+			if _, ok := claims[name]; ok {
+				return "", fmt.Errorf("Enricher cannot replace the %q claim", name)
+			}
+			claims[name] = value
+		}
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode claims: %v", err)
+	}
+
+	return SignWithHeader(payload, header, key)
+}
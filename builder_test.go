@@ -0,0 +1,171 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBuilder(t *testing.T) {
+	key := []byte("builder-secret")
+	issued := time.Unix(1500000000, 0)
+
+	b := Builder().
+		Payload(map[string]interface{}{"scope": "read", "iss": "overridden"}).
+		Issuer("joe").
+		Subject("alice").
+		Audience("api").
+		TTL(time.Hour).
+		Header(Header{Alg: ALG_HS256, Typ: "JWT"})
+	b.now = func() time.Time { return issued }
+
+	jws, err := b.Sign(key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	header, payload, err := VerifyAndDecodeWithHeader(jws, ProviderFromKey(key))
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if header.Typ != "JWT" {
+		t.Fatalf("Unexpected header %+v", header)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+	expected := map[string]interface{}{
+		"scope": "read",
+		"iss":   "joe",
+		"sub":   "alice",
+		"aud":   "api",
+		"iat":   float64(1500000000),
+		"exp":   float64(1500003600),
+	}
+	if len(claims) != len(expected) {
+		t.Fatalf("Unexpected claims %v", claims)
+	}
+	for name, value := range expected {
+		if claims[name] != value {
+			t.Fatalf("Claim %s: expected %v. Got %v", name, value, claims[name])
+		}
+	}
+}
+
+func TestTokenBuilder_Audiences(t *testing.T) {
+	key := []byte("builder-secret")
+	jws, err := Builder().Audience("a", "b").TTL(time.Minute).Header(Header{Alg: ALG_HS256}).Sign(key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	_, _, claims, err := VerifyJWT(jws, ProviderFromKey(key))
+	if err != nil {
+		t.Fatal("VerifyJWT: ", err)
+	}
+	if len(claims.Audience) != 2 || claims.Audience[0] != "a" || claims.Audience[1] != "b" {
+		t.Fatalf("Unexpected audience %v", claims.Audience)
+	}
+}
+
+func TestTokenBuilder_Errors(t *testing.T) {
+	key := []byte("builder-secret")
+	if _, err := Builder().Header(Header{Alg: ALG_HS256}).Sign(key); err == nil {
+		t.Fatal("Signed a token without a TTL")
+	}
+	if _, err := Builder().TTL(time.Minute).Sign(key); err == nil {
+		t.Fatal("Signed a token without an algorithm")
+	}
+	if _, err := Builder().Payload([]string{"not", "an", "object"}).TTL(time.Minute).Header(Header{Alg: ALG_HS256}).Sign(key); err == nil {
+		t.Fatal("Signed a token with a non-object payload")
+	}
+}
+
+// ClaimsEnricher returning fixed claims, or err
+type testEnricher struct {
+	claims map[string]interface{}
+	err    error
+	seen   StandardClaims
+}
+
+func (e *testEnricher) EnrichClaims(ctx context.Context, base StandardClaims) (map[string]interface{}, error) {
+	e.seen = base
+	return e.claims, e.err
+}
+
+func TestBuildToken(t *testing.T) {
+	key := []byte("builder-secret")
+	base := StandardClaims{Issuer: "joe", Subject: "alice", ExpiresAt: NewNumericDate(time.Now().Add(time.Hour))}
+	enricher := &testEnricher{claims: map[string]interface{}{"roles": []string{"admin"}, "tenant": "acme"}}
+
+	token, err := BuildToken(context.Background(), base, enricher, Header{Alg: ALG_HS256}, key)
+	if err != nil {
+		t.Fatal("BuildToken: ", err)
+	}
+	if enricher.seen.Subject != "alice" {
+		t.Fatalf("Enricher saw %+v", enricher.seen)
+	}
+
+	_, payload, claims, err := VerifyJWT(token, ProviderFromKey(key))
+	if err != nil {
+		t.Fatal("VerifyJWT: ", err)
+	}
+	if claims.Issuer != "joe" || claims.Subject != "alice" {
+		t.Fatalf("Unexpected claims %+v", claims)
+	}
+	var extra struct {
+		Roles  []string `json:"roles"`
+		Tenant string   `json:"tenant"`
+	}
+	if err := json.Unmarshal(payload, &extra); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+	if len(extra.Roles) != 1 || extra.Roles[0] != "admin" || extra.Tenant != "acme" {
+		t.Fatalf("Unexpected enriched claims %s", payload)
+	}
+
+	// no enricher signs the base claims alone
+	if _, err := BuildToken(context.Background(), base, nil, Header{Alg: ALG_HS256}, key); err != nil {
+		t.Fatal("BuildToken: ", err)
+	}
+
+	// base claims can't be replaced
+	override := &testEnricher{claims: map[string]interface{}{"sub": "admin"}}
+	if _, err := BuildToken(context.Background(), base, override, Header{Alg: ALG_HS256}, key); err == nil {
+		t.Fatal("Enricher replaced the sub claim")
+	}
+
+	errLookup := errors.New("lookup failed")
+	failing := &testEnricher{err: errLookup}
+	if _, err := BuildToken(context.Background(), base, failing, Header{Alg: ALG_HS256}, key); !errors.Is(err, errLookup) {
+		t.Fatalf("Expected the enricher's error. Got %v", err)
+	}
+}
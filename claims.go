@@ -0,0 +1,203 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JWT timestamp, encoded in JSON as seconds since the Unix epoch (RFC
+// 7519 Section 2). Decoded values are always in UTC.
+type NumericDate struct {
+	time.Time
+}
+
+// create a NumericDate from a time, in UTC with whole second precision
+func NewNumericDate(t time.Time) *NumericDate {
+	return &NumericDate{t.Truncate(time.Second).UTC()}
+}
+
+// Encode as an integer number of seconds. Fractional seconds are
+// dropped.
+func (d NumericDate) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, d.Unix(), 10), nil
+}
+
+// Decode from either an integer or a floating point number of seconds
+func (d *NumericDate) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	s := string(data)
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		d.Time = time.Unix(seconds, 0).UTC()
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid NumericDate %s", data)
+	}
+	if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return fmt.Errorf("NumericDate %s out of range", data)
+	}
+
+	seconds, frac := math.Modf(f)
+	d.Time = time.Unix(int64(seconds), int64(frac*1e9)).UTC()
+	return nil
+}
+
+// The aud claim, which RFC 7519 Section 4.1.3 allows to be either a
+// single string or an array of strings
+type Audience []string
+
+// Encode a single audience as a string and several as an array
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// Decode either a string or an array of strings
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	// an explicit null is an absent claim, not an empty audience name
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*a = nil
+		return nil
+	}
+
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("Invalid audience %s", data)
+	}
+	*a = multiple
+	return nil
+}
+
+// Report whether aud is one of the audiences
+func (a Audience) Contains(aud string) bool {
+	for _, candidate := range a {
+		if candidate == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// How an expected audience is compared with the aud claim
+type AudienceMatchMode int
+
+const (
+	// The audience must equal the expected value
+	AudienceMatchExact AudienceMatchMode = iota
+
+	// The audience must equal the expected value or extend it with a
+	// path, so "https://api.example.com" matches
+	// "https://api.example.com/v1" but not
+	// "https://api.example.com.evil.com"
+	AudienceMatchPrefix
+)
+
+// Report whether any of the audiences matches expected under mode
+func (a Audience) Matches(expected string, mode AudienceMatchMode) bool {
+	for _, candidate := range a {
+		if candidate == expected {
+			return true
+		}
+		if mode == AudienceMatchPrefix && strings.HasPrefix(candidate, expected) {
+			if strings.HasSuffix(expected, "/") || candidate[len(expected)] == '/' {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Registered JWT claims from RFC 7519 Section 4.1. Embed in an
+// application's claims struct to decode these alongside its own.
+type StandardClaims struct {
+	Issuer    string       `json:"iss,omitempty"`
+	Subject   string       `json:"sub,omitempty"`
+	Audience  Audience     `json:"aud,omitempty"`
+	ExpiresAt *NumericDate `json:"exp,omitempty"`
+	NotBefore *NumericDate `json:"nbf,omitempty"`
+	IssuedAt  *NumericDate `json:"iat,omitempty"`
+	ID        string       `json:"jti,omitempty"`
+}
+
+// Unmarshal a verified JSON payload into a claims value of type T
+func DecodeClaims[T any](payload []byte) (T, error) {
+	var claims T
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, fmt.Errorf("Failed to decode claims: %v", err)
+	}
+	return claims, nil
+}
+
+// Verify the authenticity of a JWS signature and decode its payload
+// into a claims value of type T
+func VerifyAndDecodeClaims[T any](token string, kp KeyProvider, opts ...VerifyOption) (header Header, claims T, err error) {
+	header, payload, err := VerifyAndDecodeWithHeader(token, kp, opts...)
+	if err != nil {
+		return
+	}
+
+	claims, err = DecodeClaims[T](payload)
+	return
+}
+
+// Remove the named top-level claims from a JSON payload, e.g. before
+// writing it to an audit log. Nested values are kept as they are; the
+// remaining claims are re-encoded in sorted order.
+func RedactClaims(payload []byte, fields []string) ([]byte, error) {
+	var claims map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("Failed to decode claims: %v", err)
+	}
+	if claims == nil {
+		return nil, errors.New("Claims are not a JSON object")
+	}
+
+	for _, field := range fields {
+		delete(claims, field)
+	}
+
+	return json.Marshal(claims)
+}
@@ -0,0 +1,252 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNumericDate_Unmarshal(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{`1300819380`, time.Unix(1300819380, 0)},
+		{`1300819380.5`, time.Unix(1300819380, 5e8)},
+		{`1.30081938e9`, time.Unix(1300819380, 0)},
+		{`-1`, time.Unix(-1, 0)},
+	}
+
+	for _, tt := range tests {
+		var d NumericDate
+		if err := json.Unmarshal([]byte(tt.in), &d); err != nil {
+			t.Fatalf("Unmarshal %s: %v", tt.in, err)
+		}
+		if !d.Equal(tt.want) {
+			t.Fatalf("Unmarshal %s: expected %v. Got %v", tt.in, tt.want, d.Time)
+		}
+		if d.Location() != time.UTC {
+			t.Fatalf("Unmarshal %s: expected UTC. Got %v", tt.in, d.Location())
+		}
+	}
+
+	for _, in := range []string{`"1300819380"`, `true`, `1e300`} {
+		var d NumericDate
+		if err := json.Unmarshal([]byte(in), &d); err == nil {
+			t.Fatalf("Unmarshal %s: expected an error", in)
+		}
+	}
+}
+
+func TestNumericDate_Marshal(t *testing.T) {
+	d := NumericDate{time.Unix(1300819380, 75e7)}
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal("Marshal: ", err)
+	}
+	if string(data) != "1300819380" {
+		t.Fatalf("Expected 1300819380. Got %s", data)
+	}
+
+	local := time.Date(2011, 3, 22, 18, 43, 0, 5, time.FixedZone("X", 3600))
+	if nd := NewNumericDate(local); nd.Location() != time.UTC || nd.Nanosecond() != 0 || !nd.Equal(local.Truncate(time.Second)) {
+		t.Fatalf("NewNumericDate: unexpected %v", nd.Time)
+	}
+}
+
+func TestStandardClaims(t *testing.T) {
+	// Claims from RFC 7519 Section 3.1
+	var claims struct {
+		StandardClaims
+		Root bool `json:"http://example.com/is_root"`
+	}
+	err := json.Unmarshal([]byte(`{"iss":"joe","exp":1300819380,"http://example.com/is_root":true}`), &claims)
+	if err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+	if claims.Issuer != "joe" || !claims.Root {
+		t.Fatalf("Unexpected claims %+v", claims)
+	}
+	if claims.ExpiresAt == nil || claims.ExpiresAt.Unix() != 1300819380 {
+		t.Fatalf("Unexpected exp %v", claims.ExpiresAt)
+	}
+	if claims.NotBefore != nil || claims.IssuedAt != nil {
+		t.Fatal("Absent claims were decoded")
+	}
+
+	data, err := json.Marshal(StandardClaims{Subject: "alice", IssuedAt: NewNumericDate(time.Unix(1500000000, 0))})
+	if err != nil {
+		t.Fatal("Marshal: ", err)
+	}
+	if string(data) != `{"sub":"alice","iat":1500000000}` {
+		t.Fatalf("Unexpected encoding %s", data)
+	}
+}
+
+func TestAudience(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected Audience
+	}{
+		{`"api"`, Audience{"api"}},
+		{`["api","web"]`, Audience{"api", "web"}},
+		{`[]`, Audience{}},
+	}
+	for _, tt := range tests {
+		var claims StandardClaims
+		if err := json.Unmarshal([]byte(`{"aud":`+tt.in+`}`), &claims); err != nil {
+			t.Fatalf("Unmarshal %s: %v", tt.in, err)
+		}
+		if len(claims.Audience) != len(tt.expected) {
+			t.Fatalf("Unmarshal %s: unexpected audience %v", tt.in, claims.Audience)
+		}
+		for i := range tt.expected {
+			if claims.Audience[i] != tt.expected[i] {
+				t.Fatalf("Unmarshal %s: unexpected audience %v", tt.in, claims.Audience)
+			}
+		}
+	}
+
+	var aud Audience
+	if err := json.Unmarshal([]byte(`42`), &aud); err == nil {
+		t.Fatal("Decoded a numeric audience")
+	}
+
+	// null clears the audience rather than producing an empty name
+	aud = Audience{"api"}
+	if err := json.Unmarshal([]byte(`null`), &aud); err != nil || aud != nil {
+		t.Fatalf("Unexpected audience %q for null: %v", aud, err)
+	}
+
+	for expected, a := range map[string]Audience{`"api"`: {"api"}, `["api","web"]`: {"api", "web"}} {
+		data, err := json.Marshal(a)
+		if err != nil {
+			t.Fatal("Marshal: ", err)
+		}
+		if string(data) != expected {
+			t.Fatalf("Expected %s. Got %s", expected, data)
+		}
+	}
+
+	// usable anywhere a []string is
+	var list []string = Audience{"api", "web"}
+	if !Audience(list).Contains("web") || Audience(list).Contains("admin") {
+		t.Fatal("Contains returned the wrong result")
+	}
+}
+
+func TestAudience_Matches(t *testing.T) {
+	aud := Audience{"https://api.example.com/v1", "web"}
+
+	tests := []struct {
+		expected string
+		mode     AudienceMatchMode
+		ok       bool
+	}{
+		{"web", AudienceMatchExact, true},
+		{"https://api.example.com/v1", AudienceMatchExact, true},
+		{"https://api.example.com", AudienceMatchExact, false},
+		{"https://api.example.com", AudienceMatchPrefix, true},
+		{"https://api.example.com/", AudienceMatchPrefix, true},
+		{"https://api.example", AudienceMatchPrefix, false},
+		{"we", AudienceMatchPrefix, false},
+	}
+	for _, tt := range tests {
+		if aud.Matches(tt.expected, tt.mode) != tt.ok {
+			t.Fatalf("Matches(%q, %d): expected %v", tt.expected, tt.mode, tt.ok)
+		}
+	}
+}
+
+type testAppClaims struct {
+	StandardClaims
+	Scope string `json:"scope"`
+}
+
+func TestDecodeClaims(t *testing.T) {
+	claims, err := DecodeClaims[testAppClaims]([]byte(`{"sub":"alice","scope":"read"}`))
+	if err != nil {
+		t.Fatal("DecodeClaims: ", err)
+	}
+	if claims.Subject != "alice" || claims.Scope != "read" {
+		t.Fatalf("Unexpected claims %+v", claims)
+	}
+
+	if _, err := DecodeClaims[testAppClaims]([]byte("not json")); err == nil {
+		t.Fatal("Decoded claims from a non-JSON payload")
+	}
+}
+
+func TestVerifyAndDecodeClaims(t *testing.T) {
+	key := []byte("claims-secret")
+	jws := signTestClaims(t, key, testAppClaims{StandardClaims{Issuer: "joe"}, "write"})
+
+	header, claims, err := VerifyAndDecodeClaims[testAppClaims](jws, ProviderFromKey(key))
+	if err != nil {
+		t.Fatal("VerifyAndDecodeClaims: ", err)
+	}
+	if header.Alg != ALG_HS256 || claims.Issuer != "joe" || claims.Scope != "write" {
+		t.Fatalf("Unexpected result %+v %+v", header, claims)
+	}
+
+	if _, _, err := VerifyAndDecodeClaims[testAppClaims](jws, ProviderFromKey([]byte("wrong"))); err == nil {
+		t.Fatal("Decoded claims with the wrong key")
+	}
+}
+
+func TestRedactClaims(t *testing.T) {
+	tests := []struct {
+		in     string
+		fields []string
+		want   string
+	}{
+		{`{"sub":"joe","iss":"example"}`, []string{"sub"}, `{"iss":"example"}`},
+		{`{"sub":"joe","email":"joe@example.com","ssn":"078-05-1120"}`, []string{"email", "ssn", "sub"}, `{}`},
+		{`{"sub":"joe"}`, []string{"missing"}, `{"sub":"joe"}`},
+		{`{"sub":"joe"}`, nil, `{"sub":"joe"}`},
+
+		// only top-level claims are removed
+		{`{"sub":"joe","profile":{"sub":"nested","tags":[1,2]}}`, []string{"sub"}, `{"profile":{"sub":"nested","tags":[1,2]}}`},
+		{`{"big":12345678901234567890,"sub":"joe"}`, []string{"sub"}, `{"big":12345678901234567890}`},
+	}
+
+	for _, tt := range tests {
+		got, err := RedactClaims([]byte(tt.in), tt.fields)
+		if err != nil {
+			t.Fatalf("RedactClaims %s: %v", tt.in, err)
+		}
+		if string(got) != tt.want {
+			t.Fatalf("RedactClaims %s: expected %s. Got %s", tt.in, tt.want, got)
+		}
+	}
+
+	for _, in := range []string{``, `null`, `[1,2]`, `"sub"`, `{"sub":`} {
+		if _, err := RedactClaims([]byte(in), []string{"sub"}); err == nil {
+			t.Fatalf("RedactClaims %q: expected an error", in)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithClock(t *testing.T) {
+	// signTestPayload carries the RFC 7519 example exp of 1300819380
+	key := []byte("clock-secret")
+	jws, err := Sign(signTestPayload, ALG_HS256, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	kp := ProviderFromKey(key)
+
+	if _, _, _, err := VerifyJWT(jws, kp); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("Expected ErrTokenExpired. Got %v", err)
+	}
+
+	before := FixedClock(time.Unix(1300819379, 0))
+	if _, _, _, err := VerifyJWT(jws, kp, WithClock(before)); err != nil {
+		t.Fatal("VerifyJWT: ", err)
+	}
+
+	at := FixedClock(time.Unix(1300819380, 0))
+	if _, _, _, err := VerifyJWT(jws, kp, WithClock(at)); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("Expected ErrTokenExpired. Got %v", err)
+	}
+}
+
+func TestValidateClaims(t *testing.T) {
+	claims := StandardClaims{
+		NotBefore: NewNumericDate(time.Unix(1000, 0)),
+		ExpiresAt: NewNumericDate(time.Unix(2000, 0)),
+	}
+
+	if err := ValidateClaims(claims, FixedClock(time.Unix(1500, 0))); err != nil {
+		t.Fatal("ValidateClaims: ", err)
+	}
+	if err := ValidateClaims(claims, FixedClock(time.Unix(999, 0))); err != ErrTokenNotYetValid {
+		t.Fatalf("Expected ErrTokenNotYetValid. Got %v", err)
+	}
+	if err := ValidateClaims(claims, nil); err != ErrTokenExpired {
+		t.Fatalf("Expected ErrTokenExpired from the system clock. Got %v", err)
+	}
+
+	if d := time.Since(SystemClock{}.Now()); d < 0 || d > time.Minute {
+		t.Fatalf("SystemClock is off by %v", d)
+	}
+}
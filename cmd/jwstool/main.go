@@ -0,0 +1,320 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Command jwstool inspects, signs and verifies compact JWS tokens.
+//
+//	jwstool info
+//	jwstool inspect <token>
+//	jwstool sign --alg RS256 --key key.pem [--kid id] [--typ JWT] [--payload payload.json]
+//	jwstool verify --alg RS256 --key pub.pem <token>
+//
+// Keys are read from a file, or from an environment variable when
+// given as env:NAME. HMAC algorithms take the raw secret; other
+// algorithms take a PEM encoded key. verify only accepts tokens signed
+// with the given --alg; the token's own header never decides how the
+// key is read. A payload or token of "-" is read
+// from standard input. info, sign and verify write their results to
+// standard output as JSON; info lists the supported algorithms.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mendsley/gojws"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+const usage = `usage:
+  jwstool info
+  jwstool inspect <token>
+  jwstool sign --alg ALG --key KEY [--kid ID] [--typ TYP] [--payload FILE]
+  jwstool verify --alg ALG --key KEY <token>
+
+KEY is a file name, or env:NAME to read the key from an environment
+variable. FILE and <token> may be - to read from standard input.
+`
+
+// exit codes
+const (
+	exitOK      = 0
+	exitFailed  = 1
+	exitUsage   = 2
+	exitInvalid = 3
+)
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	var err error
+	switch args[0] {
+	case "info":
+		err = info(args[1:], stdout, stderr)
+	case "inspect":
+		err = inspect(args[1:], stdin, stdout, stderr)
+	case "sign":
+		err = sign(args[1:], stdin, stdout, stderr)
+	case "verify":
+		var valid bool
+		valid, err = verify(args[1:], stdin, stdout, stderr)
+		if err == nil && !valid {
+			return exitInvalid
+		}
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "jwstool: unknown command %q\n%s", args[0], usage)
+		return exitUsage
+	}
+
+	// the flag package has already reported its own errors
+	if err == flag.ErrHelp || err == errFlags {
+		return exitUsage
+	}
+	if errors.As(err, new(usageError)) {
+		fmt.Fprintf(stderr, "jwstool: %v\n%s", err, usage)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "jwstool: %v\n", err)
+		return exitFailed
+	}
+	return exitOK
+}
+
+var errFlags = errors.New("Invalid flags")
+
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+func info(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("info", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return usageError("info takes no arguments")
+	}
+
+	return writeJSON(stdout, gojws.AlgorithmInventory())
+}
+
+func inspect(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("inspect", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("inspect takes a single token")
+	}
+
+	token, err := readToken(fs.Arg(0), stdin)
+	if err != nil {
+		return err
+	}
+	return gojws.PrettyPrint(token, stdout)
+}
+
+func sign(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("sign", stderr)
+	alg := fs.String("alg", "", "signature algorithm, e.g. RS256")
+	keySpec := fs.String("key", "", "private key file, or env:NAME")
+	kid := fs.String("kid", "", "kid header")
+	typ := fs.String("typ", "", "typ header")
+	payloadFile := fs.String("payload", "-", "payload file, or - for standard input")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return usageError("sign takes no arguments")
+	}
+	if *alg == "" || *keySpec == "" {
+		return usageError("sign requires --alg and --key")
+	}
+
+	algorithm := gojws.Algorithm(*alg)
+	key, err := loadPrivateKey(*keySpec, algorithm)
+	if err != nil {
+		return err
+	}
+
+	payload, err := readInput(*payloadFile, stdin)
+	if err != nil {
+		return err
+	}
+
+	token, err := gojws.SignWithHeader(payload, gojws.Header{Alg: algorithm, Kid: *kid, Typ: *typ}, key)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(stdout, struct {
+		Token string `json:"token"`
+	}{token})
+}
+
+// result of the verify command
+type verifyResult struct {
+	Valid   bool            `json:"valid"`
+	Error   string          `json:"error,omitempty"`
+	Header  *gojws.Header   `json:"header,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+func verify(args []string, stdin io.Reader, stdout, stderr io.Writer) (bool, error) {
+	fs := newFlagSet("verify", stderr)
+	keySpec := fs.String("key", "", "public key file, or env:NAME")
+	alg := fs.String("alg", "", "expected signature algorithm, e.g. RS256")
+	if err := parseFlags(fs, args); err != nil {
+		return false, err
+	}
+	if fs.NArg() != 1 {
+		return false, usageError("verify takes a single token")
+	}
+	if *alg == "" || *keySpec == "" {
+		return false, usageError("verify requires --alg and --key")
+	}
+
+	token, err := readToken(fs.Arg(0), stdin)
+	if err != nil {
+		return false, err
+	}
+
+	// the key is read for the expected algorithm, never for the one
+	// named by the (unverified) token header, and only that algorithm
+	// is accepted
+	algorithm := gojws.Algorithm(*alg)
+	key, err := loadPublicKey(*keySpec, algorithm)
+	if err != nil {
+		return false, err
+	}
+
+	header, payload, err := gojws.VerifyAndDecodeWithHeader(token, gojws.ProviderFromKey(key), gojws.WithAllowedAlgorithms(algorithm))
+	if err != nil {
+		return false, writeJSON(stdout, verifyResult{Error: err.Error()})
+	}
+
+	result := verifyResult{Valid: true, Header: &header, Payload: payload}
+	if !json.Valid(payload) {
+		result.Payload, _ = json.Marshal(string(payload))
+	}
+	return true, writeJSON(stdout, result)
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil && err != flag.ErrHelp {
+		err = errFlags
+	}
+	return err
+}
+
+// read a key from a file, or from an environment variable for env:NAME
+func readKey(spec string) ([]byte, error) {
+	if name, ok := strings.CutPrefix(spec, "env:"); ok {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("Environment variable %s is not set", name)
+		}
+		return []byte(value), nil
+	}
+
+	return os.ReadFile(spec)
+}
+
+func loadPrivateKey(spec string, alg gojws.Algorithm) (interface{}, error) {
+	data, err := readKey(spec)
+	if err != nil {
+		return nil, err
+	}
+	if alg.IsSymmetric() {
+		return gojws.HMACKeyFromReader(bytes.NewReader(data))
+	}
+	return gojws.PrivateKeyFromPEM(data)
+}
+
+func loadPublicKey(spec string, alg gojws.Algorithm) (interface{}, error) {
+	data, err := readKey(spec)
+	if err != nil {
+		return nil, err
+	}
+	if alg.IsSymmetric() {
+		// a PEM public key is not a secret; refuse to use it as one
+		if block, _ := pem.Decode(data); block != nil {
+			return nil, fmt.Errorf("%s requires a raw secret, not a PEM encoded key", alg)
+		}
+		return gojws.HMACKeyFromReader(bytes.NewReader(data))
+	}
+	return gojws.PublicKeyFromPEM(data)
+}
+
+// read a file, or standard input for "-"
+func readInput(name string, stdin io.Reader) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// a token argument, or standard input for "-"
+func readToken(arg string, stdin io.Reader) (string, error) {
+	if arg != "-" {
+		return arg, nil
+	}
+
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,241 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mendsley/gojws"
+)
+
+func runTest(t *testing.T, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestSignVerify_HMAC(t *testing.T) {
+	t.Setenv("JWSTOOL_TEST_SECRET", "secret\n")
+
+	code, out, errOut := runTest(t, `{"iss":"joe"}`, "sign", "--alg", "HS256", "--key", "env:JWSTOOL_TEST_SECRET", "--kid", "k1")
+	if code != exitOK {
+		t.Fatalf("sign exited %d: %s", code, errOut)
+	}
+
+	var signed struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal([]byte(out), &signed); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+	if _, err := gojws.VerifyAndDecode(signed.Token, gojws.ProviderFromKey([]byte("secret"))); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	code, out, errOut = runTest(t, "", "verify", "--alg", "HS256", "--key", "env:JWSTOOL_TEST_SECRET", signed.Token)
+	if code != exitOK {
+		t.Fatalf("verify exited %d: %s", code, errOut)
+	}
+
+	var result verifyResult
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+	var payload struct {
+		Issuer string `json:"iss"`
+	}
+	if err := json.Unmarshal(result.Payload, &payload); err != nil {
+		t.Fatal("Unmarshal payload: ", err)
+	}
+	if !result.Valid || result.Header.Kid != "k1" || payload.Issuer != "joe" {
+		t.Fatalf("Unexpected result %s", out)
+	}
+
+	// pinning a different algorithm fails verification
+	code, out, _ = runTest(t, "", "verify", "--key", "env:JWSTOOL_TEST_SECRET", "--alg", "HS512", signed.Token)
+	if code != exitInvalid || !strings.Contains(out, `"valid": false`) {
+		t.Fatalf("Expected an invalid result. Got %d %s", code, out)
+	}
+}
+
+func TestSignVerify_PEM(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal("MarshalPKCS8PrivateKey: ", err)
+	}
+	pubPEM, err := gojws.PublicKeyToPEM(&key.PublicKey)
+	if err != nil {
+		t.Fatal("PublicKeyToPEM: ", err)
+	}
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.pem")
+	pubFile := filepath.Join(dir, "pub.pem")
+	payloadFile := filepath.Join(dir, "payload.json")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal("WriteFile: ", err)
+	}
+	if err := os.WriteFile(pubFile, pubPEM, 0644); err != nil {
+		t.Fatal("WriteFile: ", err)
+	}
+	if err := os.WriteFile(payloadFile, []byte("not json"), 0644); err != nil {
+		t.Fatal("WriteFile: ", err)
+	}
+
+	code, out, errOut := runTest(t, "", "sign", "--alg", "ES256", "--key", keyFile, "--payload", payloadFile)
+	if code != exitOK {
+		t.Fatalf("sign exited %d: %s", code, errOut)
+	}
+	var signed struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal([]byte(out), &signed); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+
+	// token from standard input; non-JSON payloads come back as strings
+	code, out, errOut = runTest(t, signed.Token+"\n", "verify", "--alg", "ES256", "--key", pubFile, "-")
+	if code != exitOK {
+		t.Fatalf("verify exited %d: %s", code, errOut)
+	}
+	if !strings.Contains(out, `"payload": "not json"`) {
+		t.Fatalf("Unexpected result %s", out)
+	}
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	otherPEM, err := gojws.PublicKeyToPEM(&other.PublicKey)
+	if err != nil {
+		t.Fatal("PublicKeyToPEM: ", err)
+	}
+	t.Setenv("JWSTOOL_TEST_PUB", string(otherPEM))
+	code, out, _ = runTest(t, "", "verify", "--alg", "ES256", "--key", "env:JWSTOOL_TEST_PUB", signed.Token)
+	if code != exitInvalid || !strings.Contains(out, `"valid": false`) {
+		t.Fatalf("Expected an invalid result. Got %d %s", code, out)
+	}
+}
+
+func TestVerify_AlgorithmConfusion(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	pubPEM, err := gojws.PublicKeyToPEM(&key.PublicKey)
+	if err != nil {
+		t.Fatal("PublicKeyToPEM: ", err)
+	}
+	pubFile := filepath.Join(t.TempDir(), "pub.pem")
+	if err := os.WriteFile(pubFile, pubPEM, 0644); err != nil {
+		t.Fatal("WriteFile: ", err)
+	}
+
+	// forge an HMAC token using the public key as the secret
+	forged, err := gojws.Sign([]byte(`{"iss":"mallory"}`), gojws.ALG_HS256, pubPEM)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	code, out, _ := runTest(t, "", "verify", "--alg", "ES256", "--key", pubFile, forged)
+	if code != exitInvalid || !strings.Contains(out, `"valid": false`) {
+		t.Fatalf("Expected an invalid result. Got %d %s", code, out)
+	}
+
+	if code, _, _ := runTest(t, "", "verify", "--alg", "HS256", "--key", pubFile, forged); code != exitFailed {
+		t.Fatalf("Expected a PEM key to be refused as an HMAC secret. Got %d", code)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	code, out, errOut := runTest(t, "", "info")
+	if code != exitOK {
+		t.Fatalf("info exited %d: %s", code, errOut)
+	}
+
+	var inventory []gojws.AlgorithmInfo
+	if err := json.Unmarshal([]byte(out), &inventory); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+	if len(inventory) != len(gojws.AlgorithmInventory()) || inventory[1].Algorithm != gojws.ALG_HS256 {
+		t.Fatalf("Unexpected inventory %s", out)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	token, err := gojws.Sign([]byte(`{"iss":"joe"}`), gojws.ALG_HS256, []byte("secret"))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	code, out, errOut := runTest(t, "", "inspect", token)
+	if code != exitOK {
+		t.Fatalf("inspect exited %d: %s", code, errOut)
+	}
+	if !strings.Contains(out, "NOT been verified") || !strings.Contains(out, `"iss": "joe"`) {
+		t.Fatalf("Unexpected output %s", out)
+	}
+
+	if code, _, _ := runTest(t, "", "inspect", "garbage"); code != exitFailed {
+		t.Fatalf("Expected failure for a malformed token. Got %d", code)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"bogus"},
+		{"info", "extra"},
+		{"inspect"},
+		{"sign", "--alg", "HS256"},
+		{"verify", "token"},
+		{"verify", "--key", "k", "a", "b"},
+		{"verify", "--key", "k", "token"},
+		{"sign", "--nonsense"},
+	}
+	for _, args := range tests {
+		if code, _, _ := runTest(t, "", args...); code != exitUsage {
+			t.Fatalf("%q: expected usage exit code. Got %d", args, code)
+		}
+	}
+
+	if code, _, _ := runTest(t, "", "sign", "--alg", "HS256", "--key", "env:JWSTOOL_TEST_UNSET"); code != exitFailed {
+		t.Fatalf("Expected failure for a missing key. Got %d", code)
+	}
+}
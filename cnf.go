@@ -0,0 +1,92 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// The cnf claim of a proof-of-possession token (RFC 7800), naming the
+// key the presenter must hold
+type ConfirmationClaim struct {
+	// The key itself
+	JWK *json.RawMessage `json:"jwk,omitempty"`
+
+	// Identifier of a key known to the recipient
+	KID string `json:"kid,omitempty"`
+
+	// RFC 7638 thumbprint of the key, as used by DPoP (RFC 9449)
+	JKT string `json:"jkt,omitempty"`
+}
+
+// Registered claims plus the cnf claim
+type ConfirmationClaims struct {
+	StandardClaims
+	Confirmation *ConfirmationClaim `json:"cnf,omitempty"`
+}
+
+// Check that presentedKey, e.g. the key that signed a DPoP proof, is
+// the key the token's cnf claim is bound to, by comparing RFC 7638
+// thumbprints with the claim's jkt or jwk. Fails with
+// ErrConfirmationMismatch if it is not. A cnf with only a kid can't be
+// checked without the recipient's key store, and is an error.
+func VerifyCNFBinding(claims ConfirmationClaims, presentedKey crypto.PublicKey) error {
+	cnf := claims.Confirmation
+	if cnf == nil {
+		return errors.New("Token has no cnf claim")
+	}
+
+	var expected string
+	switch {
+	case cnf.JKT != "":
+		expected = cnf.JKT
+
+	case cnf.JWK != nil:
+		key, err := PublicKeyFromJWK(*cnf.JWK)
+		if err != nil {
+			return fmt.Errorf("Invalid cnf jwk: %v", err)
+		}
+		expected, err = JWKThumbprint(key)
+		if err != nil {
+			return fmt.Errorf("Invalid cnf jwk: %v", err)
+		}
+
+	default:
+		return errors.New("cnf claim has no jkt or jwk to check")
+	}
+
+	presented, err := JWKThumbprint(presentedKey)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(presented), []byte(expected)) != 1 {
+		return ErrConfirmationMismatch
+	}
+	return nil
+}
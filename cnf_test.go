@@ -0,0 +1,104 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// RFC 7638 Section 3.1
+func TestJWKThumbprint(t *testing.T) {
+	const jwk = `{"kty":"RSA","n":"0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw","e":"AQAB","alg":"RS256","kid":"2011-04-29"}`
+
+	key, err := PublicKeyFromJWK([]byte(jwk))
+	if err != nil {
+		t.Fatal("PublicKeyFromJWK: ", err)
+	}
+	thumbprint, err := JWKThumbprint(key)
+	if err != nil {
+		t.Fatal("JWKThumbprint: ", err)
+	}
+	if thumbprint != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Fatalf("Unexpected thumbprint %s", thumbprint)
+	}
+
+	if _, err := JWKThumbprint([]byte("secret")); err == nil {
+		t.Fatal("Computed a thumbprint for a symmetric key")
+	}
+}
+
+func TestVerifyCNFBinding(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	jkt, err := JWKThumbprint(&key.PublicKey)
+	if err != nil {
+		t.Fatal("JWKThumbprint: ", err)
+	}
+	data, err := PublicKeyToJWK(&key.PublicKey)
+	if err != nil {
+		t.Fatal("PublicKeyToJWK: ", err)
+	}
+	jwk := json.RawMessage(data)
+
+	// the claim survives a round trip through a token payload
+	var claims ConfirmationClaims
+	if err := json.Unmarshal([]byte(`{"sub":"alice","cnf":{"jkt":"`+jkt+`"}}`), &claims); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+	if claims.Subject != "alice" || claims.Confirmation == nil || claims.Confirmation.JKT != jkt {
+		t.Fatalf("Unexpected claims %+v", claims)
+	}
+
+	for _, cnf := range []*ConfirmationClaim{{JKT: jkt}, {JWK: &jwk}} {
+		claims := ConfirmationClaims{Confirmation: cnf}
+		if err := VerifyCNFBinding(claims, &key.PublicKey); err != nil {
+			t.Fatalf("%+v: %v", cnf, err)
+		}
+		if err := VerifyCNFBinding(claims, key); err != nil {
+			t.Fatalf("%+v: private key: %v", cnf, err)
+		}
+		if err := VerifyCNFBinding(claims, &other.PublicKey); !errors.Is(err, ErrConfirmationMismatch) {
+			t.Fatalf("%+v: expected ErrConfirmationMismatch. Got %v", cnf, err)
+		}
+	}
+
+	for _, claims := range []ConfirmationClaims{{}, {Confirmation: &ConfirmationClaim{KID: "k1"}}} {
+		if err := VerifyCNFBinding(claims, &key.PublicKey); err == nil {
+			t.Fatalf("%+v: expected an error", claims)
+		}
+	}
+}
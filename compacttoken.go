@@ -0,0 +1,67 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+// A JWS in compact serialization. The accessors decode the token
+// WITHOUT verifying its signature; use VerifyAndDecodeWithHeader
+// before trusting anything they return. They share their parsing with
+// UnsafeParseWithoutVerification.
+type CompactToken string
+
+func (t CompactToken) String() string {
+	return string(t)
+}
+
+// The base64url encoded header, payload and signature segments
+func (t CompactToken) Parts() (header, payload, sig string, err error) {
+	return splitCompact(string(t))
+}
+
+// Decode the (unverified) header
+func (t CompactToken) Header() (Header, error) {
+	encoded, _, _, err := t.Parts()
+	if err != nil {
+		return Header{}, err
+	}
+	return decodeHeader(encoded)
+}
+
+// Decode the (unverified) payload
+func (t CompactToken) Payload() ([]byte, error) {
+	_, encoded, _, err := t.Parts()
+	if err != nil {
+		return nil, err
+	}
+	return decodeSegment(encoded, "payload")
+}
+
+// Decode the raw signature
+func (t CompactToken) Signature() ([]byte, error) {
+	_, _, encoded, err := t.Parts()
+	if err != nil {
+		return nil, err
+	}
+	return decodeSegment(encoded, "signature")
+}
@@ -0,0 +1,91 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompactToken(t *testing.T) {
+	key := []byte("compact-secret")
+	jws, err := SignWithHeader(signTestPayload, Header{Alg: ALG_HS256, Kid: "k1"}, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	token := CompactToken(jws)
+
+	if token.String() != jws {
+		t.Fatalf("Unexpected string %q", token.String())
+	}
+
+	header, err := token.Header()
+	if err != nil {
+		t.Fatal("Header: ", err)
+	}
+	if header.Alg != ALG_HS256 || header.Kid != "k1" {
+		t.Fatalf("Unexpected header %+v", header)
+	}
+
+	payload, err := token.Payload()
+	if err != nil {
+		t.Fatal("Payload: ", err)
+	}
+	if !bytes.Equal(payload, signTestPayload) {
+		t.Fatalf("Unexpected payload %q", payload)
+	}
+
+	signature, err := token.Signature()
+	if err != nil {
+		t.Fatal("Signature: ", err)
+	}
+	_, _, expected, err := UnsafeParseWithoutVerification(jws)
+	if err != nil {
+		t.Fatal("Parse: ", err)
+	}
+	if !bytes.Equal(signature, expected) {
+		t.Fatal("Unexpected signature")
+	}
+
+	h, p, s, err := token.Parts()
+	if err != nil {
+		t.Fatal("Parts: ", err)
+	}
+	if h+"."+p+"."+s != jws {
+		t.Fatal("Parts do not reassemble the token")
+	}
+
+	for _, bad := range []CompactToken{"", "a.b", "a.b.c.d", "!.!.!"} {
+		if _, err := bad.Header(); err == nil {
+			t.Fatalf("Decoded header of %q", bad)
+		}
+		if _, err := bad.Payload(); err == nil {
+			t.Fatalf("Decoded payload of %q", bad)
+		}
+		if _, err := bad.Signature(); err == nil {
+			t.Fatalf("Decoded signature of %q", bad)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// JWT payload of a signed cookie. The cookie name is signed along with
+// the value so a token can't be replayed under another name.
+type cookieClaims struct {
+	StandardClaims
+	Name  string `json:"cookie"`
+	Value []byte `json:"value"`
+}
+
+// Sign value as a JWT that expires after ttl, returning a cookie
+// carrying the token. The cookie is HttpOnly, Secure and SameSite=Lax
+// with a path of "/"; adjust these before sending it if needed.
+func SignCookie(name string, value []byte, ttl time.Duration, key crypto.PrivateKey, alg Algorithm) (*http.Cookie, error) {
+	if name == "" {
+		return nil, errors.New("Cookie name is empty")
+	}
+
+	expires := time.Now().Add(ttl)
+	token, err := Builder().
+		Payload(cookieClaims{Name: name, Value: value}).
+		TTL(ttl).
+		Header(Header{Alg: alg}).
+		Sign(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Cookie{
+		Name:     name,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		MaxAge:   int(ttl / time.Second),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}, nil
+}
+
+// Verify a cookie created by SignCookie, returning its value. Fails if
+// the token has expired or was signed for a cookie of another name.
+func VerifyCookie(c *http.Cookie, kp KeyProvider, opts ...VerifyOption) ([]byte, error) {
+	if c == nil {
+		return nil, errors.New("No cookie")
+	}
+
+	_, payload, _, err := VerifyJWT(c.Value, kp, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims cookieClaims
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode cookie claims: %v", err)
+	}
+	if claims.Name != c.Name {
+		return nil, fmt.Errorf("Token was signed for cookie %q, not %q", claims.Name, c.Name)
+	}
+	if claims.Value == nil {
+		claims.Value = []byte{}
+	}
+	return claims.Value, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSignCookie(t *testing.T) {
+	key := []byte("cookie-secret")
+	kp := ProviderFromKey(key)
+
+	cookie, err := SignCookie("session", []byte("user=42"), time.Hour, key, ALG_HS256)
+	if err != nil {
+		t.Fatal("SignCookie: ", err)
+	}
+	if cookie.Name != "session" || !cookie.HttpOnly || !cookie.Secure || cookie.MaxAge != 3600 {
+		t.Fatalf("Unexpected cookie %+v", cookie)
+	}
+
+	value, err := VerifyCookie(cookie, kp)
+	if err != nil {
+		t.Fatal("VerifyCookie: ", err)
+	}
+	if string(value) != "user=42" {
+		t.Fatalf("Unexpected value %q", value)
+	}
+
+	// the token survives a round trip through the Cookie header
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.AddCookie(cookie)
+	received, err := req.Cookie("session")
+	if err != nil {
+		t.Fatal("Cookie: ", err)
+	}
+	if _, err := VerifyCookie(received, kp); err != nil {
+		t.Fatal("VerifyCookie: ", err)
+	}
+
+	// bound to the cookie name
+	renamed := *cookie
+	renamed.Name = "admin"
+	if _, err := VerifyCookie(&renamed, kp); err == nil {
+		t.Fatal("Verified a cookie under another name")
+	}
+
+	tampered := *cookie
+	tampered.Value += "x"
+	if _, err := VerifyCookie(&tampered, kp); err == nil {
+		t.Fatal("Verified a tampered cookie")
+	}
+
+	clock := FixedClock(time.Now().Add(2 * time.Hour))
+	if _, err := VerifyCookie(cookie, kp, WithClock(clock)); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("Expected ErrTokenExpired. Got %v", err)
+	}
+
+	if _, err := SignCookie("", nil, time.Hour, key, ALG_HS256); err == nil {
+		t.Fatal("Signed a cookie without a name")
+	}
+	if _, err := SignCookie("session", nil, 0, key, ALG_HS256); err == nil {
+		t.Fatal("Signed a cookie without a TTL")
+	}
+}
@@ -25,15 +25,64 @@
 package gojws
 
 import (
+	"bytes"
 	"encoding/base64"
+	"io"
 	"strings"
 )
 
+// RFC 7515 Section 2 defines base64url encoding with all trailing '='
+// characters omitted, so padded input is rejected. This used to pad
+// its input and accept either form; lenientDecode keeps that behavior
+// for callers that opt in.
 func safeDecode(str string) ([]byte, error) {
-	lenMod4 := len(str) % 4
-	if lenMod4 > 0 {
-		str = str + strings.Repeat("=", 4-lenMod4)
+	return base64.RawURLEncoding.DecodeString(str)
+}
+
+// tolerate producers that pad their base64url output
+func lenientDecode(str string) ([]byte, error) {
+	return safeDecode(strings.TrimRight(str, "="))
+}
+
+func safeEncode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func safeDecodeBytes(src []byte) ([]byte, error) {
+	dst := make([]byte, base64.RawURLEncoding.DecodedLen(len(src)))
+	n, err := base64.RawURLEncoding.Decode(dst, src)
+	if err != nil {
+		return nil, err
 	}
 
-	return base64.URLEncoding.DecodeString(str)
+	return dst[:n], nil
+}
+
+func lenientDecodeBytes(src []byte) ([]byte, error) {
+	return safeDecodeBytes(bytes.TrimRight(src, "="))
+}
+
+// Encode data as unpadded base64url, as used throughout JOSE (RFC 7515
+// Section 2)
+func Base64URLEncode(data []byte) string {
+	return safeEncode(data)
+}
+
+// Decode unpadded base64url, as used throughout JOSE. Padded or
+// standard base64 input is rejected.
+func Base64URLDecode(s string) ([]byte, error) {
+	return safeDecode(s)
+}
+
+// Decode base64url, tolerating the trailing '=' padding some producers
+// emit. The standard base64 alphabet is still rejected.
+func Base64URLDecodeLenient(s string) ([]byte, error) {
+	return lenientDecode(s)
+}
+
+// Stream unpadded base64url to w. Close must be called to flush the
+// final partial block; it does not close w. SignReaderTo uses this to
+// sign payloads without reading them into memory.
+func NewBase64URLEncoder(w io.Writer) io.WriteCloser {
+	return base64.NewEncoder(base64.RawURLEncoding, w)
 }
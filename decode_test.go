@@ -0,0 +1,97 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBase64URL(t *testing.T) {
+	// bytes that differ between the standard and URL alphabets
+	data := []byte{0xfb, 0xff, 0xbf, 0x01}
+	encoded := Base64URLEncode(data)
+	if encoded != "-_-_AQ" {
+		t.Fatalf("Expected -_-_AQ. Got %s", encoded)
+	}
+
+	decoded, err := Base64URLDecode(encoded)
+	if err != nil {
+		t.Fatal("Base64URLDecode: ", err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Fatalf("Unexpected round trip %v", decoded)
+	}
+
+	for _, bad := range []string{"-_-_AQ==", "+/+/AQ", "!"} {
+		if _, err := Base64URLDecode(bad); err == nil {
+			t.Fatalf("Decoded %q", bad)
+		}
+	}
+}
+
+func TestNewBase64URLEncoder(t *testing.T) {
+	data := bytes.Repeat([]byte{0xfb, 0xff, 0xbf, 0x01, 0x02}, 1000)
+
+	// written in uneven pieces so blocks straddle writes
+	var buf bytes.Buffer
+	enc := NewBase64URLEncoder(&buf)
+	for rest := data; len(rest) > 0; {
+		n := 7
+		if n > len(rest) {
+			n = len(rest)
+		}
+		if _, err := enc.Write(rest[:n]); err != nil {
+			t.Fatal("Write: ", err)
+		}
+		rest = rest[n:]
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal("Close: ", err)
+	}
+
+	if buf.String() != Base64URLEncode(data) {
+		t.Fatal("Streamed encoding differs from Base64URLEncode")
+	}
+}
+
+func TestBase64URLDecodeLenient(t *testing.T) {
+	data := []byte{0xfb, 0xff, 0xbf, 0x01}
+	for _, in := range []string{"-_-_AQ", "-_-_AQ=", "-_-_AQ=="} {
+		decoded, err := Base64URLDecodeLenient(in)
+		if err != nil {
+			t.Fatalf("%q: %v", in, err)
+		}
+		if !bytes.Equal(decoded, data) {
+			t.Fatalf("%q: unexpected result %v", in, decoded)
+		}
+	}
+
+	for _, bad := range []string{"+/+/AQ==", "-_=-_AQ", "!"} {
+		if _, err := Base64URLDecodeLenient(bad); err == nil {
+			t.Fatalf("Decoded %q", bad)
+		}
+	}
+}
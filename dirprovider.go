@@ -0,0 +1,148 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Load public keys from the *.pem and *.pub files in dir, using each
+// file's name (without extension) as its kid. Files that can't be read
+// or parsed are skipped. When refresh is positive the directory is
+// re-scanned, at most once per interval, as keys are requested; a scan
+// that finds no keys leaves the previous set in place.
+func NewDirectoryKeyProvider(dir string, refresh time.Duration) (KeyProvider, error) {
+	dp := &directoryProvider{
+		dir:     dir,
+		refresh: refresh,
+		now:     time.Now,
+	}
+
+	keys, err := scanKeyDirectory(dir)
+	if err != nil {
+		return nil, err
+	}
+	dp.keys = keys
+	dp.lastScan = dp.now()
+	return dp, nil
+}
+
+type directoryProvider struct {
+	dir     string
+	refresh time.Duration
+	now     func() time.Time
+
+	mu       sync.Mutex
+	keys     map[string]crypto.PublicKey
+	lastScan time.Time
+}
+
+func (dp *directoryProvider) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	keys := dp.currentKeys()
+
+	if h.Kid == "" {
+		// unambiguous without a kid only if there's a single key
+		if len(keys) == 1 {
+			for _, key := range keys {
+				return key, nil
+			}
+		}
+		return nil, errors.New("JWS header has no kid")
+	}
+
+	key, ok := keys[h.Kid]
+	if !ok {
+		return nil, fmt.Errorf("No key file for kid %q", h.Kid)
+	}
+	return key, nil
+}
+
+// the current key set, re-scanning the directory first if the refresh
+// interval has passed. The scan runs without holding dp.mu so lookups
+// aren't blocked on file I/O; the caller that claims the rescan by
+// advancing lastScan is the only one to perform it.
+func (dp *directoryProvider) currentKeys() map[string]crypto.PublicKey {
+	dp.mu.Lock()
+	keys := dp.keys
+	rescan := false
+	if now := dp.now(); dp.refresh > 0 && now.Sub(dp.lastScan) >= dp.refresh {
+		dp.lastScan = now
+		rescan = true
+	}
+	dp.mu.Unlock()
+
+	if !rescan {
+		return keys
+	}
+
+	scanned, err := scanKeyDirectory(dp.dir)
+	if err != nil {
+		return keys
+	}
+
+	dp.mu.Lock()
+	dp.keys = scanned
+	dp.mu.Unlock()
+	return scanned
+}
+
+// parse the public keys in a directory, indexed by file name
+func scanKeyDirectory(dir string) (map[string]crypto.PublicKey, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read key directory: %v", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := filepath.Ext(name)
+		if entry.IsDir() || (ext != ".pem" && ext != ".pub") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		key, err := PublicKeyFromPEM(data)
+		if err != nil {
+			continue
+		}
+		keys[strings.TrimSuffix(name, ext)] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("No readable key files in %s", dir)
+	}
+	return keys, nil
+}
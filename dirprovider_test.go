@@ -0,0 +1,123 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// write the public half of a new P-256 key into dir
+func writeTestKeyFile(t *testing.T, dir, name string) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	data, err := PublicKeyToPEM(key)
+	if err != nil {
+		t.Fatal("PublicKeyToPEM: ", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+		t.Fatal("WriteFile: ", err)
+	}
+	return key
+}
+
+func signTestKid(t *testing.T, key *ecdsa.PrivateKey, kid string) string {
+	jws, err := SignWithHeader(signTestPayload, Header{Alg: ALG_ES256, Kid: kid}, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	return jws
+}
+
+func TestDirectoryKeyProvider(t *testing.T) {
+	dir := t.TempDir()
+	key1 := writeTestKeyFile(t, dir, "key1.pem")
+	key2 := writeTestKeyFile(t, dir, "key2.pub")
+	writeTestKeyFile(t, dir, "ignored.txt")
+	if err := os.WriteFile(filepath.Join(dir, "broken.pem"), []byte("not pem"), 0600); err != nil {
+		t.Fatal("WriteFile: ", err)
+	}
+
+	kp, err := NewDirectoryKeyProvider(dir, time.Minute)
+	if err != nil {
+		t.Fatal("NewDirectoryKeyProvider: ", err)
+	}
+	now := time.Now()
+	kp.(*directoryProvider).now = func() time.Time { return now }
+
+	if _, err := VerifyAndDecode(signTestKid(t, key1, "key1"), kp); err != nil {
+		t.Fatal("Verify key1: ", err)
+	}
+	if _, err := VerifyAndDecode(signTestKid(t, key2, "key2"), kp); err != nil {
+		t.Fatal("Verify key2: ", err)
+	}
+	if _, err := VerifyAndDecode(signTestKid(t, key1, "key2"), kp); err == nil {
+		t.Fatal("Verified with the wrong kid")
+	}
+	if _, err := VerifyAndDecode(signTestKid(t, key1, "ignored"), kp); err == nil {
+		t.Fatal("Loaded a file without a key extension")
+	}
+	if _, err := VerifyAndDecode(signTestKid(t, key1, ""), kp); err == nil {
+		t.Fatal("Verified without a kid among several keys")
+	}
+
+	// new keys appear once the refresh interval passes
+	key3 := writeTestKeyFile(t, dir, "key3.pem")
+	token := signTestKid(t, key3, "key3")
+	if _, err := VerifyAndDecode(token, kp); err == nil {
+		t.Fatal("Re-scanned before the refresh interval")
+	}
+	now = now.Add(time.Minute)
+	if _, err := VerifyAndDecode(token, kp); err != nil {
+		t.Fatal("Verify key3: ", err)
+	}
+}
+
+func TestDirectoryKeyProvider_Empty(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewDirectoryKeyProvider(dir, 0); err == nil {
+		t.Fatal("Expected an error for an empty directory")
+	}
+	if _, err := NewDirectoryKeyProvider(filepath.Join(dir, "missing"), 0); err == nil {
+		t.Fatal("Expected an error for a missing directory")
+	}
+
+	// a lone key doesn't need a kid
+	key := writeTestKeyFile(t, dir, "only.pem")
+	kp, err := NewDirectoryKeyProvider(dir, 0)
+	if err != nil {
+		t.Fatal("NewDirectoryKeyProvider: ", err)
+	}
+	if _, err := VerifyAndDecode(signTestKid(t, key, ""), kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+}
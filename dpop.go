@@ -0,0 +1,276 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// typ header of a DPoP proof (RFC 9449 Section 4.2)
+const DPoPProofType = "dpop+jwt"
+
+// how old a DPoP proof's iat may be when no WithMaxAge is given
+const dpopMaxAge = 5 * time.Minute
+
+// Claims of a DPoP proof JWT (RFC 9449 Section 4.2)
+type DPoPClaims struct {
+	ID         string       `json:"jti"`
+	HTTPMethod string       `json:"htm"`
+	HTTPURL    string       `json:"htu"`
+	IssuedAt   *NumericDate `json:"iat"`
+	Nonce      string       `json:"nonce,omitempty"`
+}
+
+// Assembles and signs a DPoP proof, binding a request to the holder of
+// a private key. The public key is embedded in the jwk header.
+type DPoPProofBuilder struct {
+	alg    Algorithm
+	method string
+	url    string
+	nonce  string
+	now    func() time.Time
+}
+
+// start building a DPoP proof
+func NewDPoPProof() *DPoPProofBuilder {
+	return &DPoPProofBuilder{now: time.Now}
+}
+
+// set the htm claim, e.g. "POST". Required.
+func (b *DPoPProofBuilder) WithHTTPMethod(method string) *DPoPProofBuilder {
+	b.method = method
+	return b
+}
+
+// Set the htu claim. Any query or fragment is removed, as RFC 9449
+// requires. Required.
+func (b *DPoPProofBuilder) WithHTTPURL(url string) *DPoPProofBuilder {
+	b.url = url
+	return b
+}
+
+// set the nonce claim to a value previously supplied by the server
+func (b *DPoPProofBuilder) WithNonce(nonce string) *DPoPProofBuilder {
+	b.nonce = nonce
+	return b
+}
+
+// Sign with alg instead of the key's default (see defaultAlgorithm)
+func (b *DPoPProofBuilder) WithAlgorithm(alg Algorithm) *DPoPProofBuilder {
+	b.alg = alg
+	return b
+}
+
+// Generate a fresh jti and sign the proof with key, which may also be
+// a crypto.Signer
+func (b *DPoPProofBuilder) Build(key crypto.PrivateKey) (string, error) {
+	if b.method == "" {
+		return "", errors.New("DPoP proof requires an HTTP method")
+	}
+	htu, err := dpopTargetURI(b.url)
+	if err != nil {
+		return "", err
+	}
+
+	pub := crypto.PublicKey(key)
+	if signer, ok := hardwareSigner(key); ok {
+		pub = signer.Public()
+	}
+	pub, err = NormalizeToPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+
+	alg := b.alg
+	if alg == "" {
+		alg, err = defaultAlgorithm(pub)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// PublicKeyToJWK refuses symmetric keys, which DPoP can't use
+	jwk, err := PublicKeyToJWK(pub)
+	if err != nil {
+		return "", err
+	}
+
+	jti, err := GenerateJTI()
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(DPoPClaims{
+		ID:         jti,
+		HTTPMethod: b.method,
+		HTTPURL:    htu,
+		IssuedAt:   NewNumericDate(b.now()),
+		Nonce:      b.nonce,
+	})
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode DPoP claims: %v", err)
+	}
+
+	return SignWithHeader(payload, Header{Alg: alg, Typ: DPoPProofType, Jwk: jwk}, key)
+}
+
+// the htu form of a request URL: absolute, without query or fragment
+func dpopTargetURI(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("Invalid DPoP URL: %v", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("DPoP URL %q is not absolute", rawURL)
+	}
+
+	// scheme and host are case insensitive
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.RawQuery, u.ForceQuery = "", false
+	u.Fragment, u.RawFragment = "", ""
+	return u.String(), nil
+}
+
+// Verify a DPoP proof (RFC 9449 Section 4.3) for a request with the
+// given method and URL. The signature is checked against the public
+// key in the proof's jwk header, so a valid proof only shows the
+// sender holds that key; binding it to an access token is up to the
+// caller. Proofs must have been issued within the last five minutes,
+// or the WithMaxAge window, and the clock, WithClockSkew, WithJTIStore
+// and WithDPoPNonce options apply.
+func VerifyDPoP(proof string, method string, url string, opts ...VerifyOption) (claims DPoPClaims, err error) {
+	config := newVerifyConfig(opts)
+
+	header, _, _, err := UnsafeParseWithoutVerification(proof)
+	if err != nil {
+		return
+	}
+	if header.Typ != DPoPProofType {
+		err = fmt.Errorf("Expected typ %s. Got %q", DPoPProofType, header.Typ)
+		return
+	}
+	if !header.Alg.IsAsymmetric() {
+		err = fmt.Errorf("DPoP proof cannot use algorithm %s", header.Alg)
+		return
+	}
+
+	pub, err := dpopPublicKey(header.Jwk)
+	if err != nil {
+		return
+	}
+
+	_, payload, err := verifyAndDecode([]byte(proof), ProviderFromKey(pub), config)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		err = fmt.Errorf("Failed to decode DPoP claims: %v", err)
+		return
+	}
+	if claims.ID == "" || claims.IssuedAt == nil {
+		err = errors.New("DPoP proof requires jti and iat claims")
+		return
+	}
+
+	if claims.HTTPMethod != method {
+		err = ErrDPoPHTMMismatch
+		return
+	}
+	want, err := dpopTargetURI(url)
+	if err != nil {
+		return
+	}
+	if got, uerr := dpopTargetURI(claims.HTTPURL); uerr != nil || got != want {
+		err = ErrDPoPHTUMismatch
+		return
+	}
+
+	if config.dpopNonce != "" && claims.Nonce != config.dpopNonce {
+		err = ErrDPoPNonceMismatch
+		return
+	}
+
+	clock := config.clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	maxAge := config.maxAge
+	if maxAge <= 0 {
+		maxAge = dpopMaxAge
+	}
+	now := clock.Now()
+	if now.Sub(claims.IssuedAt.Time) > maxAge+config.clockSkew {
+		err = ErrTokenTooOld
+		return
+	}
+	if claims.IssuedAt.Sub(now) > config.clockSkew {
+		err = ErrTokenNotYetValid
+		return
+	}
+
+	if config.jtiStore != nil {
+		var seen bool
+		seen, err = config.jtiStore.HasAndStore(claims.ID, claims.IssuedAt.Add(maxAge+config.clockSkew))
+		if err != nil {
+			err = fmt.Errorf("Failed to check jti: %v", err)
+			return
+		}
+		if seen {
+			err = ErrTokenReplayed
+			return
+		}
+	}
+	return
+}
+
+// the public key embedded in a DPoP proof, which must be asymmetric
+// and must not carry private parameters
+func dpopPublicKey(data json.RawMessage) (crypto.PublicKey, error) {
+	if len(data) == 0 {
+		return nil, errors.New("DPoP proof has no jwk header")
+	}
+
+	var jwk jsonWebKey
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, fmt.Errorf("Failed to decode JWK: %v", err)
+	}
+	if jwk.Kty == "oct" {
+		return nil, errors.New("DPoP proof jwk must not be a symmetric key")
+	}
+	if jwk.D != "" || jwk.P != "" || jwk.Q != "" || jwk.Dp != "" || jwk.Dq != "" || jwk.Qi != "" || jwk.K != "" {
+		return nil, errors.New("DPoP proof jwk must not contain a private key")
+	}
+
+	return jwk.publicKey()
+}
@@ -0,0 +1,305 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDPoPProofBuilder(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	now := time.Unix(1300819380, 0)
+	b := NewDPoPProof().
+		WithHTTPMethod("POST").
+		WithHTTPURL("https://server.example.com/token?x=1#frag").
+		WithNonce("server-nonce")
+	b.now = func() time.Time { return now }
+
+	proof, err := b.Build(key)
+	if err != nil {
+		t.Fatal("Build: ", err)
+	}
+
+	header, _, _, err := UnsafeParseWithoutVerification(proof)
+	if err != nil {
+		t.Fatal("Parse: ", err)
+	}
+	if header.Alg != ALG_ES256 || header.Typ != DPoPProofType {
+		t.Fatalf("Unexpected header %+v", header)
+	}
+
+	pub, err := PublicKeyFromJWK(header.Jwk)
+	if err != nil {
+		t.Fatal("PublicKeyFromJWK: ", err)
+	}
+	payload, err := VerifyAndDecode(proof, ProviderFromKey(pub))
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	var claims DPoPClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+	if claims.HTTPMethod != "POST" || claims.HTTPURL != "https://server.example.com/token" || claims.Nonce != "server-nonce" {
+		t.Fatalf("Unexpected claims %+v", claims)
+	}
+	if claims.ID == "" || claims.IssuedAt == nil || !claims.IssuedAt.Equal(now) {
+		t.Fatalf("Unexpected jti or iat %+v", claims)
+	}
+
+	// every proof carries a new jti
+	again, err := b.Build(key)
+	if err != nil {
+		t.Fatal("Build: ", err)
+	}
+	_, payload, _, err = UnsafeParseWithoutVerification(again)
+	if err != nil {
+		t.Fatal("Parse: ", err)
+	}
+	var second DPoPClaims
+	if err := json.Unmarshal(payload, &second); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+	if second.ID == claims.ID {
+		t.Fatal("Proofs share a jti")
+	}
+}
+
+func TestDPoPProofBuilder_Keys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	tests := []struct {
+		key  interface{}
+		alg  Algorithm
+		want Algorithm
+	}{
+		{rsaKey, "", ALG_RS256},
+		{rsaKey, ALG_PS256, ALG_PS256},
+		{ecKey, "", ALG_ES384},
+		{opaqueSigner{ecKey}, "", ALG_ES384},
+	}
+
+	for _, tt := range tests {
+		proof, err := NewDPoPProof().
+			WithHTTPMethod("GET").
+			WithHTTPURL("https://resource.example.org/protected").
+			WithAlgorithm(tt.alg).
+			Build(tt.key)
+		if err != nil {
+			t.Fatalf("Build %T: %v", tt.key, err)
+		}
+
+		header, _, _, err := UnsafeParseWithoutVerification(proof)
+		if err != nil {
+			t.Fatal("Parse: ", err)
+		}
+		if header.Alg != tt.want {
+			t.Fatalf("Expected %s. Got %s", tt.want, header.Alg)
+		}
+	}
+}
+
+func TestDPoPProofBuilder_Errors(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	if _, err := NewDPoPProof().WithHTTPURL("https://server.example.com/").Build(key); err == nil {
+		t.Fatal("Built a proof without a method")
+	}
+	if _, err := NewDPoPProof().WithHTTPMethod("GET").Build(key); err == nil {
+		t.Fatal("Built a proof without a URL")
+	}
+	if _, err := NewDPoPProof().WithHTTPMethod("GET").WithHTTPURL("/token").Build(key); err == nil {
+		t.Fatal("Built a proof with a relative URL")
+	}
+
+	b := NewDPoPProof().WithHTTPMethod("GET").WithHTTPURL("https://server.example.com/")
+	if _, err := b.Build([]byte("secret")); err == nil {
+		t.Fatal("Built a proof with a symmetric key")
+	}
+	if _, err := b.WithAlgorithm(ALG_ES384).Build(key); err == nil {
+		t.Fatal("Built a proof with the wrong curve")
+	}
+}
+
+func TestVerifyDPoP(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	now := time.Unix(1300819380, 0)
+	build := func(method, url, nonce string, iat time.Time) string {
+		b := NewDPoPProof().WithHTTPMethod(method).WithHTTPURL(url).WithNonce(nonce)
+		b.now = func() time.Time { return iat }
+		proof, err := b.Build(key)
+		if err != nil {
+			t.Fatal("Build: ", err)
+		}
+		return proof
+	}
+	clock := WithClock(FixedClock(now))
+
+	proof := build("POST", "https://server.example.com/token", "n1", now)
+	claims, err := VerifyDPoP(proof, "POST", "https://Server.Example.com/token?grant_type=x", clock)
+	if err != nil {
+		t.Fatal("VerifyDPoP: ", err)
+	}
+	if claims.HTTPMethod != "POST" || claims.Nonce != "n1" {
+		t.Fatalf("Unexpected claims %+v", claims)
+	}
+
+	if _, err := VerifyDPoP(proof, "GET", "https://server.example.com/token", clock); !errors.Is(err, ErrDPoPHTMMismatch) {
+		t.Fatalf("Expected ErrDPoPHTMMismatch. Got %v", err)
+	}
+	if _, err := VerifyDPoP(proof, "POST", "https://server.example.com/other", clock); !errors.Is(err, ErrDPoPHTUMismatch) {
+		t.Fatalf("Expected ErrDPoPHTUMismatch. Got %v", err)
+	}
+
+	if _, err := VerifyDPoP(proof, "POST", "https://server.example.com/token", clock, WithDPoPNonce("n1")); err != nil {
+		t.Fatal("VerifyDPoP: ", err)
+	}
+	if _, err := VerifyDPoP(proof, "POST", "https://server.example.com/token", clock, WithDPoPNonce("n2")); !errors.Is(err, ErrDPoPNonceMismatch) {
+		t.Fatalf("Expected ErrDPoPNonceMismatch. Got %v", err)
+	}
+
+	stale := build("POST", "https://server.example.com/token", "", now.Add(-10*time.Minute))
+	if _, err := VerifyDPoP(stale, "POST", "https://server.example.com/token", clock); !errors.Is(err, ErrTokenTooOld) {
+		t.Fatalf("Expected ErrTokenTooOld. Got %v", err)
+	}
+	if _, err := VerifyDPoP(stale, "POST", "https://server.example.com/token", clock, WithMaxAge(time.Hour)); err != nil {
+		t.Fatal("VerifyDPoP: ", err)
+	}
+
+	future := build("POST", "https://server.example.com/token", "", now.Add(time.Minute))
+	if _, err := VerifyDPoP(future, "POST", "https://server.example.com/token", clock); !errors.Is(err, ErrTokenNotYetValid) {
+		t.Fatalf("Expected ErrTokenNotYetValid. Got %v", err)
+	}
+
+	store := NewInMemoryJTIStore()
+	store.now = func() time.Time { return now }
+	if _, err := VerifyDPoP(proof, "POST", "https://server.example.com/token", clock, WithJTIStore(store)); err != nil {
+		t.Fatal("VerifyDPoP: ", err)
+	}
+	if _, err := VerifyDPoP(proof, "POST", "https://server.example.com/token", clock, WithJTIStore(store)); !errors.Is(err, ErrTokenReplayed) {
+		t.Fatalf("Expected ErrTokenReplayed. Got %v", err)
+	}
+}
+
+func TestVerifyDPoP_Rejected(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	now := time.Now()
+	payload, err := json.Marshal(DPoPClaims{
+		ID:         "jti-1",
+		HTTPMethod: "GET",
+		HTTPURL:    "https://resource.example.org/",
+		IssuedAt:   NewNumericDate(now),
+	})
+	if err != nil {
+		t.Fatal("Marshal: ", err)
+	}
+
+	pubJWK, err := PublicKeyToJWK(&key.PublicKey)
+	if err != nil {
+		t.Fatal("PublicKeyToJWK: ", err)
+	}
+	privJWK, err := PrivateKeyToJWK(key)
+	if err != nil {
+		t.Fatal("PrivateKeyToJWK: ", err)
+	}
+	octJWK, err := PrivateKeyToJWK([]byte("secret"))
+	if err != nil {
+		t.Fatal("PrivateKeyToJWK: ", err)
+	}
+
+	tests := []struct {
+		name   string
+		header Header
+		key    interface{}
+	}{
+		{"wrong typ", Header{Alg: ALG_ES256, Typ: "JWT", Jwk: pubJWK}, key},
+		{"no jwk", Header{Alg: ALG_ES256, Typ: DPoPProofType}, key},
+		{"private jwk", Header{Alg: ALG_ES256, Typ: DPoPProofType, Jwk: privJWK}, key},
+		{"symmetric", Header{Alg: ALG_HS256, Typ: DPoPProofType, Jwk: octJWK}, []byte("secret")},
+		{"wrong key", Header{Alg: ALG_ES256, Typ: DPoPProofType, Jwk: pubJWK}, other},
+	}
+
+	for _, tt := range tests {
+		proof, err := SignWithHeader(payload, tt.header, tt.key)
+		if err != nil {
+			t.Fatalf("%s: Sign: %v", tt.name, err)
+		}
+		if _, err := VerifyDPoP(proof, "GET", "https://resource.example.org/"); err == nil {
+			t.Fatalf("%s: proof verified", tt.name)
+		}
+	}
+
+	// plaintext is refused even when explicitly allowed
+	proof, err := SignWithHeader(payload, Header{Alg: ALG_NONE, Typ: DPoPProofType, Jwk: pubJWK}, NoneKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyDPoP(proof, "GET", "https://resource.example.org/", WithNoneAllowed()); err == nil {
+		t.Fatal("Plaintext proof verified")
+	}
+
+	// and the well-formed proof is accepted
+	proof, err = SignWithHeader(payload, Header{Alg: ALG_ES256, Typ: DPoPProofType, Jwk: pubJWK}, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyDPoP(proof, "GET", "https://resource.example.org/"); err != nil {
+		t.Fatal("VerifyDPoP: ", err)
+	}
+}
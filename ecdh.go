@@ -0,0 +1,60 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+)
+
+// Compute the ECDH shared secret (the x coordinate of the shared
+// point) between an ephemeral private key and a recipient's public
+// key, as ECDH-ES does (RFC 7518 Section 4.6). The secret is not
+// uniformly random; derive keys from it with e.g. HMACKeyFromHKDF.
+// Only the P-256, P-384 and P-521 curves are supported.
+func ECDHEphemeralKey(privKey *ecdsa.PrivateKey, recipientPub *ecdsa.PublicKey) ([]byte, error) {
+	if privKey == nil || recipientPub == nil {
+		return nil, errors.New("Missing ECDH key")
+	}
+	if privKey.Curve != recipientPub.Curve {
+		return nil, errors.New("ECDH keys are on different curves")
+	}
+
+	priv, err := privKey.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("Unsupported ECDH private key: %v", err)
+	}
+	pub, err := recipientPub.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	secret, err := priv.ECDH(pub)
+	if err != nil {
+		return nil, fmt.Errorf("ECDH failed: %v", err)
+	}
+	return secret, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+)
+
+func TestECDHEphemeralKey(t *testing.T) {
+	recipient, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	ephemeral, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	secret, err := ECDHEphemeralKey(ephemeral, &recipient.PublicKey)
+	if err != nil {
+		t.Fatal("ECDHEphemeralKey: ", err)
+	}
+	if len(secret) != 32 {
+		t.Fatalf("Unexpected secret length %d", len(secret))
+	}
+
+	// the recipient recovers the ephemeral key from the header and
+	// arrives at the same secret
+	header, err := NewHeader(ALG_HS256).WithEphemeralJWK(&ephemeral.PublicKey).Build()
+	if err != nil {
+		t.Fatal("Build: ", err)
+	}
+	jws, err := SignWithHeader([]byte("payload"), header, secret)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	parsed, err := CompactToken(jws).Header()
+	if err != nil {
+		t.Fatal("Header: ", err)
+	}
+	epk, err := PublicKeyFromJWK(parsed.Jwk)
+	if err != nil {
+		t.Fatal("PublicKeyFromJWK: ", err)
+	}
+	recovered, err := ECDHEphemeralKey(recipient, epk.(*ecdsa.PublicKey))
+	if err != nil {
+		t.Fatal("ECDHEphemeralKey: ", err)
+	}
+	if !bytes.Equal(secret, recovered) {
+		t.Fatal("Parties derived different secrets")
+	}
+
+	other, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	if _, err := ECDHEphemeralKey(ephemeral, &other.PublicKey); err == nil {
+		t.Fatal("Agreed on a secret across curves")
+	}
+	if _, err := ECDHEphemeralKey(nil, &recipient.PublicKey); err == nil {
+		t.Fatal("Agreed on a secret without a private key")
+	}
+}
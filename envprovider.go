@@ -0,0 +1,88 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Create a provider that reads keys from environment variables named
+// by the token's kid: ${prefix}_${KID}, with the kid upper-cased and
+// any character other than a letter, digit or underscore replaced by
+// '_'. Tokens without a kid use ${prefix}_DEFAULT.
+//
+// Each value is base64url encoded. Once decoded, a PEM block is parsed
+// with PublicKeyFromPEM; anything else is used as a symmetric key.
+// Variables are read on every lookup, so changes take effect without a
+// restart.
+func ProviderFromEnv(prefix string) KeyProvider {
+	return envProvider{prefix: prefix}
+}
+
+type envProvider struct {
+	prefix string
+}
+
+func (ep envProvider) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	name := ep.prefix + "_DEFAULT"
+	if h.Kid != "" {
+		name = ep.prefix + "_" + envKeyName(h.Kid)
+	}
+
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return nil, fmt.Errorf("Environment variable %s is not set", name)
+	}
+
+	data, err := lenientDecode(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("Environment variable %s is not base64url: %v", name, err)
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := PublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("Environment variable %s: %w", name, err)
+		}
+		return key, nil
+	}
+	return data, nil
+}
+
+func envKeyName(kid string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, kid)
+}
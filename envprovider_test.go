@@ -0,0 +1,88 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestEnvKeyName(t *testing.T) {
+	tests := map[string]string{
+		"k1":            "K1",
+		"signing-2024":  "SIGNING_2024",
+		"a.b/c":         "A_B_C",
+		"Already_UPPER": "ALREADY_UPPER",
+		"ключ":          "____",
+	}
+	for kid, want := range tests {
+		if got := envKeyName(kid); got != want {
+			t.Fatalf("envKeyName %q: expected %q. Got %q", kid, want, got)
+		}
+	}
+}
+
+func TestProviderFromEnv(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	pubPEM, err := PublicKeyToPEM(&ecKey.PublicKey)
+	if err != nil {
+		t.Fatal("PublicKeyToPEM: ", err)
+	}
+
+	t.Setenv("GOJWS_TEST_KEYS_SIGNING_1", Base64URLEncode(pubPEM))
+	t.Setenv("GOJWS_TEST_KEYS_DEFAULT", Base64URLEncode([]byte("env-secret"))+"==\n")
+	t.Setenv("GOJWS_TEST_KEYS_BAD", "!!!")
+	kp := ProviderFromEnv("GOJWS_TEST_KEYS")
+
+	jws, err := SignWithHeader(signTestPayload, Header{Alg: ALG_ES256, Kid: "signing-1"}, ecKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	jws, err = Sign(signTestPayload, ALG_HS256, []byte("env-secret"))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	_, err = kp.GetJWSKey(Header{Alg: ALG_ES256, Kid: "missing"})
+	if err == nil || !strings.Contains(err.Error(), "GOJWS_TEST_KEYS_MISSING") {
+		t.Fatalf("Expected the variable name in the error. Got %v", err)
+	}
+	if _, err := kp.GetJWSKey(Header{Alg: ALG_ES256, Kid: "bad"}); err == nil {
+		t.Fatal("Accepted a value that is not base64url")
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"errors"
+)
+
+var (
+	// The token's x5c certificate chain could not be parsed or did not
+	// verify against the trusted roots
+	ErrCertificateChainInvalid = errors.New("Invalid x5c certificate chain")
+
+	// The token's typ header is not the one the verifier requires
+	ErrTypMismatch = errors.New("Token typ does not match")
+
+	// The token's algorithm is on the verifier's deny-list
+	ErrAlgorithmDenied = errors.New("Signature algorithm is not allowed")
+
+	// The token uses the "none" algorithm, which is only verified in
+	// binaries built with -tags jwsnone
+	ErrAlgorithmForbidden = errors.New("Plaintext JWS support is not compiled in")
+
+	// The ECDSA signature is valid but not in the canonical low-S form
+	ErrSignatureMalleability = errors.New("ECDSA signature is not in low-S form")
+
+	// The JWT's exp claim is in the past
+	ErrTokenExpired = errors.New("Token has expired")
+
+	// The JWT expired longer ago than the renewal grace period
+	ErrTokenExpiredBeyondRenewalWindow = errors.New("Token expired beyond the renewal window")
+
+	// The JWT's nbf claim is in the future
+	ErrTokenNotYetValid = errors.New("Token is not yet valid")
+
+	// The JWT's aud claim does not include the expected audience
+	ErrAudienceMismatch = errors.New("Token audience does not match")
+
+	// The JWT's iat claim is further in the past than the verifier's
+	// maximum token age
+	ErrTokenTooOld = errors.New("Token is too old")
+
+	// The JWT's jti claim has already been seen by the JTIStore
+	ErrTokenReplayed = errors.New("Token has already been used")
+
+	// The token's jku URL is not on the fetcher's allow-list
+	ErrJKUNotAllowed = errors.New("jku URL is not allowed")
+
+	// No key source is registered for the token's iss claim
+	ErrUnknownIssuer = errors.New("Unknown token issuer")
+
+	// The RevocationChecker reported the token's jti as revoked
+	ErrTokenRevoked = errors.New("Token has been revoked")
+
+	// The certificate's key usage does not include digital signatures
+	ErrKeyUsageMismatch = errors.New("Certificate key usage does not allow signing")
+
+	// The key is not an RSA, ECDSA or Ed25519 key
+	ErrUnsupportedKeyType = errors.New("Unsupported key type")
+
+	// The DPoP proof's htm claim does not match the request method
+	ErrDPoPHTMMismatch = errors.New("DPoP proof htm does not match request method")
+
+	// The DPoP proof's htu claim does not match the request URL
+	ErrDPoPHTUMismatch = errors.New("DPoP proof htu does not match request URL")
+
+	// The DPoP proof's nonce claim is missing or not the expected value
+	ErrDPoPNonceMismatch = errors.New("DPoP proof nonce does not match")
+
+	// The key presented with a proof-of-possession token is not the one
+	// its cnf claim is bound to
+	ErrConfirmationMismatch = errors.New("Presented key does not match the token's cnf claim")
+
+	// The public key is malformed, e.g. an ECDSA point not on its curve
+	ErrInvalidKey = errors.New("Invalid public key")
+)
@@ -0,0 +1,207 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+// Minimal secp256k1 implementation (y^2 = x^3 + 7) used to exercise
+// the ES256K paths. Affine arithmetic, not constant time, tests only.
+type testSecp256k1Curve struct {
+	params *elliptic.CurveParams
+}
+
+var testSecp256k1 = func() *testSecp256k1Curve {
+	params := &elliptic.CurveParams{
+		P:       new(big.Int).Set(secp256k1P),
+		N:       new(big.Int).Set(secp256k1N),
+		B:       new(big.Int).Set(secp256k1B),
+		BitSize: 256,
+		Name:    "secp256k1",
+	}
+	params.Gx, _ = new(big.Int).SetString("79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798", 16)
+	params.Gy, _ = new(big.Int).SetString("483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8", 16)
+	return &testSecp256k1Curve{params: params}
+}()
+
+func (c *testSecp256k1Curve) Params() *elliptic.CurveParams {
+	return c.params
+}
+
+func (c *testSecp256k1Curve) IsOnCurve(x, y *big.Int) bool {
+	p := c.params.P
+	lhs := new(big.Int).Mul(y, y)
+	lhs.Mod(lhs, p)
+
+	rhs := new(big.Int).Mul(x, x)
+	rhs.Mul(rhs, x)
+	rhs.Add(rhs, c.params.B)
+	rhs.Mod(rhs, p)
+
+	return lhs.Cmp(rhs) == 0
+}
+
+func (c *testSecp256k1Curve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	p := c.params.P
+	if x1.Sign() == 0 && y1.Sign() == 0 {
+		return new(big.Int).Set(x2), new(big.Int).Set(y2)
+	}
+	if x2.Sign() == 0 && y2.Sign() == 0 {
+		return new(big.Int).Set(x1), new(big.Int).Set(y1)
+	}
+	if x1.Cmp(x2) == 0 {
+		if y1.Cmp(y2) == 0 {
+			return c.Double(x1, y1)
+		}
+		return new(big.Int), new(big.Int)
+	}
+
+	l := new(big.Int).Sub(x2, x1)
+	l.Mod(l, p)
+	l.ModInverse(l, p)
+	l.Mul(l, new(big.Int).Sub(y2, y1))
+	l.Mod(l, p)
+
+	return c.finish(l, x1, y1, x2)
+}
+
+func (c *testSecp256k1Curve) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
+	p := c.params.P
+	if y1.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+
+	l := new(big.Int).Lsh(y1, 1)
+	l.ModInverse(l, p)
+	l.Mul(l, new(big.Int).Mul(big.NewInt(3), new(big.Int).Mul(x1, x1)))
+	l.Mod(l, p)
+
+	return c.finish(l, x1, y1, x1)
+}
+
+// x3 = l^2 - x1 - x2, y3 = l(x1 - x3) - y1
+func (c *testSecp256k1Curve) finish(l, x1, y1, x2 *big.Int) (*big.Int, *big.Int) {
+	p := c.params.P
+	x3 := new(big.Int).Mul(l, l)
+	x3.Sub(x3, x1)
+	x3.Sub(x3, x2)
+	x3.Mod(x3, p)
+
+	y3 := new(big.Int).Sub(x1, x3)
+	y3.Mul(y3, l)
+	y3.Sub(y3, y1)
+	y3.Mod(y3, p)
+
+	return x3, y3
+}
+
+func (c *testSecp256k1Curve) ScalarMult(bx, by *big.Int, k []byte) (*big.Int, *big.Int) {
+	x, y := new(big.Int), new(big.Int)
+	for _, b := range k {
+		for bit := 7; bit >= 0; bit-- {
+			x, y = c.Double(x, y)
+			if b&(1<<uint(bit)) != 0 {
+				x, y = c.Add(x, y, bx, by)
+			}
+		}
+	}
+	return x, y
+}
+
+func (c *testSecp256k1Curve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return c.ScalarMult(c.params.Gx, c.params.Gy, k)
+}
+
+func TestSign_ES256K(t *testing.T) {
+	privKey, err := ecdsa.GenerateKey(testSecp256k1, rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	testSignAndVerify(t, ALG_ES256K, privKey, &privKey.PublicKey)
+}
+
+func TestSign_ES256K_LowS(t *testing.T) {
+	privKey, err := ecdsa.GenerateKey(testSecp256k1, rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	halfN := new(big.Int).Rsh(secp256k1N, 1)
+	for i := 0; i < 16; i++ {
+		jws, err := Sign(signTestPayload, ALG_ES256K, privKey)
+		if err != nil {
+			t.Fatal("Sign: ", err)
+		}
+
+		_, _, signature, err := splitTestJWS(jws)
+		if err != nil {
+			t.Fatal("split: ", err)
+		}
+
+		s := new(big.Int).SetBytes(signature[32:])
+		if s.Cmp(halfN) > 0 {
+			t.Fatal("Signature is not in low-S form")
+		}
+	}
+}
+
+func TestVerify_ES256K_CurveMismatch(t *testing.T) {
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	k1Key, err := ecdsa.GenerateKey(testSecp256k1, rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	if _, err := Sign(signTestPayload, ALG_ES256K, p256Key); err == nil {
+		t.Fatal("Signed ES256K with a P-256 key")
+	}
+	if _, err := Sign(signTestPayload, ALG_ES256, k1Key); err == nil {
+		t.Fatal("Signed ES256 with a secp256k1 key")
+	}
+
+	// an ES256K token must not verify against a P-256 key, even when
+	// the signature itself was made with that key
+	jws, err := Sign(signTestPayload, ALG_ES256, p256Key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	_, payload, signature, err := splitTestJWS(jws)
+	if err != nil {
+		t.Fatal("split: ", err)
+	}
+	forged := safeEncode([]byte(`{"alg":"ES256K"}`)) + "." + payload + "." + safeEncode(signature)
+	if _, err := VerifyAndDecode(forged, ProviderFromKey(&p256Key.PublicKey)); err == nil {
+		t.Fatal("Verified ES256K token with a P-256 key")
+	}
+}
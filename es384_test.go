@@ -0,0 +1,122 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// RFC 6979 Appendix A.2.6: ECDSA, 384 bits (prime field)
+const (
+	rfc6979P384D  = "6B9D3DAD2E1B8C1C05B19875B6659F4DE23C3B667BF297BA9AA47740787137D896D5724E4C70A825F872C9EA60D2EDF5"
+	rfc6979P384Ux = "EC3A4E415B4E19A4568618029F427FA5DA9A8BC4AE92E02E06AAE5286B300C64DEF8F0EA9055866064A254515480BC13"
+	rfc6979P384Uy = "8015D9B72D7D57244EA8EF9AC0C621896708A59367F9DFB9F54CA84B3F1C9DB1288B231C3AE0D4FE7344FD2533264720"
+
+	// With SHA-384, message = "sample"
+	rfc6979P384R = "94EDBB92A5ECB8AAD4736E56C691916B3F88140666CE9FA73D64C4EA95AD133C81A648152E44ACF96E36DD1E80FABE46"
+	rfc6979P384S = "99EF4AEB15F178CEA1FE40DB2603138F130E740A19624526203B6351D0A3A94FA329C145786E679E7B82C71A38628AC8"
+)
+
+func rfc6979P384Key(t *testing.T) *ecdsa.PrivateKey {
+	d, _ := new(big.Int).SetString(rfc6979P384D, 16)
+	x, _ := new(big.Int).SetString(rfc6979P384Ux, 16)
+	y, _ := new(big.Int).SetString(rfc6979P384Uy, 16)
+
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: elliptic.P384(), X: x, Y: y},
+		D:         d,
+	}
+
+	// make sure the vector was transcribed correctly
+	gx, gy := elliptic.P384().ScalarBaseMult(d.Bytes())
+	if gx.Cmp(x) != 0 || gy.Cmp(y) != 0 {
+		t.Fatal("RFC 6979 P-384 public key does not match private key")
+	}
+	return key
+}
+
+func TestVerify_ES384_RFC6979(t *testing.T) {
+	key := rfc6979P384Key(t)
+
+	signature, err := hex.DecodeString(rfc6979P384R + rfc6979P384S)
+	if err != nil {
+		t.Fatal("DecodeString: ", err)
+	}
+
+	config := newVerifyConfig(nil)
+	if err := verifySignature(Header{Alg: ALG_ES384}, &key.PublicKey, []byte("sample"), signature, config); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	// the signature is over the SHA-384 digest; any other hash fails
+	for _, alg := range []Algorithm{ALG_ES256, ALG_ES512} {
+		if err := verifySignature(Header{Alg: alg}, &key.PublicKey, []byte("sample"), signature, config); err == nil {
+			t.Fatalf("Verified ES384 signature as %s", alg)
+		}
+	}
+	if err := verifySignature(Header{Alg: ALG_ES384}, &key.PublicKey, []byte("Sample"), signature, config); err == nil {
+		t.Fatal("Verified signature over a different message")
+	}
+}
+
+func TestSign_ES384_RoundTrip(t *testing.T) {
+	for _, key := range []*ecdsa.PrivateKey{rfc6979P384Key(t), nil} {
+		if key == nil {
+			var err error
+			key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+			if err != nil {
+				t.Fatal("GenerateKey: ", err)
+			}
+		}
+
+		jws, err := Sign(signTestPayload, ALG_ES384, key)
+		if err != nil {
+			t.Fatal("Sign: ", err)
+		}
+		_, _, signature, err := splitTestJWS(jws)
+		if err != nil {
+			t.Fatal("Split: ", err)
+		}
+		if len(signature) != 96 {
+			t.Fatalf("Expected a 96 byte signature. Got %d", len(signature))
+		}
+
+		testSignAndVerify(t, ALG_ES384, key, &key.PublicKey)
+
+		// the same key through an opaque crypto.Signer
+		jws, err = Sign(signTestPayload, ALG_ES384, opaqueSigner{key})
+		if err != nil {
+			t.Fatal("Sign: ", err)
+		}
+		if err := VerifyWithKey(jws, &key.PublicKey); err != nil {
+			t.Fatal("Verify: ", err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"encoding/json"
+)
+
+// Estimate the length of the compact JWS produced by signing a payload
+// of payloadLen bytes with Sign, which writes a header containing only
+// "alg". RSA signatures are sized for a 2048 bit key; larger keys
+// produce proportionally longer tokens. Returns -1 for unknown
+// algorithms.
+func EstimateTokenSize(payloadLen int, alg Algorithm) int {
+	var signatureLen int
+	switch alg {
+	case ALG_NONE:
+		signatureLen = 0
+	case ALG_HS256:
+		signatureLen = 32
+	case ALG_HS384:
+		signatureLen = 48
+	case ALG_HS512:
+		signatureLen = 64
+	case ALG_RS256, ALG_RS384, ALG_RS512, ALG_PS256, ALG_PS384, ALG_PS512:
+		signatureLen = 2048 / 8
+	case ALG_ES256, ALG_ES256K, ALG_EdDSA:
+		signatureLen = 64
+	case ALG_ES384:
+		signatureLen = 96
+	case ALG_ES512:
+		signatureLen = 132
+	default:
+		return -1
+	}
+
+	header, err := json.Marshal(Header{Alg: alg})
+	if err != nil {
+		return -1
+	}
+
+	return encodedLen(len(header)) + 1 + encodedLen(payloadLen) + 1 + encodedLen(signatureLen)
+}
+
+// length of unpadded base64url output for n bytes of input
+func encodedLen(n int) int {
+	return (n*8 + 5) / 6
+}
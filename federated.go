@@ -0,0 +1,85 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// An issuer and the location of its signing keys
+type JWKSSource struct {
+	Issuer  string
+	JWKSURL string
+}
+
+// Selects keys from the JWKS of the issuer named by the token's iss
+// claim. Each issuer's JWKS is fetched and cached as by
+// NewRemoteJWKSProvider.
+type FederatedJWKSProvider struct {
+	now     func() time.Time
+	sources map[string]*remoteJWKS
+}
+
+// create a provider for tokens from any of sources. Key sets are
+// fetched with client; nil uses a client with a 10 second timeout.
+func NewFederatedJWKSProvider(sources []JWKSSource, client *http.Client) *FederatedJWKSProvider {
+	p := &FederatedJWKSProvider{
+		now:     time.Now,
+		sources: make(map[string]*remoteJWKS, len(sources)),
+	}
+	now := func() time.Time { return p.now() }
+	for _, source := range sources {
+		p.sources[source.Issuer] = newRemoteJWKS(source.JWKSURL, client, now)
+	}
+	return p
+}
+
+// The issuer can only be found in the payload, so this always fails;
+// verification calls GetJWSKeyForPayload instead
+func (p *FederatedJWKSProvider) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	return nil, errors.New("Federated JWKS provider requires the token payload")
+}
+
+// select the key from the JWKS of the payload's issuer
+func (p *FederatedJWKSProvider) GetJWSKeyForPayload(h Header, payload []byte) (crypto.PublicKey, error) {
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("Failed to decode claims: %v", err)
+	}
+
+	source, ok := p.sources[claims.Issuer]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownIssuer, claims.Issuer)
+	}
+
+	return source.GetJWSKey(h)
+}
@@ -0,0 +1,129 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFederatedJWKSProvider(t *testing.T) {
+	keyA, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	keyB, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	jwksA := testJWKS(t, map[string]interface{}{`"kid":"a1"`: &keyA.PublicKey})
+	fetches := map[string]int{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		fetches["a"]++
+		w.Write(jwksA)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		fetches["b"]++
+		w.Write(testJWKS(t, map[string]interface{}{`"kid":"b1"`: &keyB.PublicKey}))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	kp := NewFederatedJWKSProvider([]JWKSSource{
+		{Issuer: "https://a.example", JWKSURL: server.URL + "/a"},
+		{Issuer: "https://b.example", JWKSURL: server.URL + "/b"},
+	}, server.Client())
+	if kp.sources["https://a.example"].client != server.Client() {
+		t.Fatal("Provider does not use the given client")
+	}
+	now := time.Now()
+	kp.now = func() time.Time { return now }
+
+	sign := func(key *ecdsa.PrivateKey, kid, iss string) string {
+		payload, err := json.Marshal(StandardClaims{Issuer: iss})
+		if err != nil {
+			t.Fatal("Marshal: ", err)
+		}
+		jws, err := SignWithHeader(payload, Header{Alg: ALG_ES256, Kid: kid}, key)
+		if err != nil {
+			t.Fatal("Sign: ", err)
+		}
+		return jws
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := VerifyAndDecode(sign(keyA, "a1", "https://a.example"), kp); err != nil {
+			t.Fatal("Verify issuer a: ", err)
+		}
+		if _, err := VerifyAndDecode(sign(keyB, "b1", "https://b.example"), kp); err != nil {
+			t.Fatal("Verify issuer b: ", err)
+		}
+	}
+	if fetches["a"] != 1 || fetches["b"] != 1 {
+		t.Fatalf("Expected one fetch per issuer. Got %v", fetches)
+	}
+
+	// one issuer's keys can't vouch for another
+	if _, err := VerifyAndDecode(sign(keyB, "b1", "https://a.example"), kp); err == nil {
+		t.Fatal("Verified a token with another issuer's key")
+	}
+
+	_, err = VerifyAndDecode(sign(keyA, "a1", "https://unknown.example"), kp)
+	if !errors.Is(err, ErrUnknownIssuer) {
+		t.Fatalf("Expected ErrUnknownIssuer. Got %v", err)
+	}
+
+	// a rotated key is picked up once the cache is old enough
+	keyA2, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	jwksA = testJWKS(t, map[string]interface{}{`"kid":"a2"`: &keyA2.PublicKey})
+	rotated := sign(keyA2, "a2", "https://a.example")
+	fetchesBefore := fetches["a"]
+	if _, err := VerifyAndDecode(rotated, kp); err == nil {
+		t.Fatal("Verified before the cache could refresh")
+	}
+	now = now.Add(remoteJWKSMinRefresh)
+	if _, err := VerifyAndDecode(rotated, kp); err != nil {
+		t.Fatal("Verify rotated key: ", err)
+	}
+	if fetches["a"] != fetchesBefore+1 {
+		t.Fatalf("Expected a single refresh. Got %d", fetches["a"]-fetchesBefore)
+	}
+
+	if _, err := kp.GetJWSKey(Header{Alg: ALG_ES256}); err == nil {
+		t.Fatal("GetJWSKey succeeded without a payload")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package gojwstest provides stand-ins for gojws interfaces, so code
+// that consumes tokens can be tested without real keys.
+package gojwstest
+
+import (
+	"sync"
+
+	"github.com/mendsley/gojws"
+)
+
+// SignatureVerifier that returns a canned result for every token
+type MockVerifier struct {
+	Header  gojws.Header
+	Payload []byte
+	Err     error
+
+	mu     sync.Mutex
+	tokens []string
+}
+
+var _ gojws.SignatureVerifier = (*MockVerifier)(nil)
+
+// Record the token and return the canned header, payload and error
+func (m *MockVerifier) VerifyAndDecode(token string) (gojws.Header, []byte, error) {
+	m.mu.Lock()
+	m.tokens = append(m.tokens, token)
+	m.mu.Unlock()
+
+	if m.Err != nil {
+		return gojws.Header{}, nil, m.Err
+	}
+	return m.Header, m.Payload, nil
+}
+
+// Tokens passed to VerifyAndDecode so far, in order
+func (m *MockVerifier) Tokens() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.tokens...)
+}
+
+// Signer that returns a canned token for every payload
+type MockSigner struct {
+	Token string
+	Err   error
+
+	mu       sync.Mutex
+	payloads [][]byte
+	headers  []gojws.Header
+}
+
+var _ gojws.Signer = (*MockSigner)(nil)
+
+// Record the payload and return the canned token and error
+func (m *MockSigner) Sign(payload []byte) (string, error) {
+	return m.SignWithHeader(payload, gojws.Header{})
+}
+
+// Record the payload and header and return the canned token and error
+func (m *MockSigner) SignWithHeader(payload []byte, h gojws.Header) (string, error) {
+	m.mu.Lock()
+	m.payloads = append(m.payloads, append([]byte(nil), payload...))
+	m.headers = append(m.headers, h)
+	m.mu.Unlock()
+
+	if m.Err != nil {
+		return "", m.Err
+	}
+	return m.Token, nil
+}
+
+// Payloads signed so far, in order
+func (m *MockSigner) Payloads() [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([][]byte(nil), m.payloads...)
+}
+
+// Headers passed to SignWithHeader so far, in order. Calls to Sign
+// record an empty header.
+func (m *MockSigner) Headers() []gojws.Header {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]gojws.Header(nil), m.headers...)
+}
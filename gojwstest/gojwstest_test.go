@@ -0,0 +1,85 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojwstest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mendsley/gojws"
+)
+
+func TestMockVerifier(t *testing.T) {
+	m := &MockVerifier{
+		Header:  gojws.Header{Alg: gojws.ALG_ES256, Kid: "k1"},
+		Payload: []byte(`{"sub":"alice"}`),
+	}
+
+	var v gojws.SignatureVerifier = m
+	header, payload, err := v.VerifyAndDecode("token-1")
+	if err != nil {
+		t.Fatal("VerifyAndDecode: ", err)
+	}
+	if header.Kid != "k1" || string(payload) != `{"sub":"alice"}` {
+		t.Fatalf("Unexpected result %+v %q", header, payload)
+	}
+
+	errInvalid := errors.New("invalid")
+	m.Err = errInvalid
+	if _, payload, err := v.VerifyAndDecode("token-2"); err != errInvalid || payload != nil {
+		t.Fatalf("Expected canned error. Got %q %v", payload, err)
+	}
+
+	if tokens := m.Tokens(); len(tokens) != 2 || tokens[0] != "token-1" || tokens[1] != "token-2" {
+		t.Fatalf("Unexpected recorded tokens %v", tokens)
+	}
+}
+
+func TestMockSigner(t *testing.T) {
+	m := &MockSigner{Token: "a.b.c"}
+
+	var s gojws.Signer = m
+	token, err := s.Sign([]byte("one"))
+	if err != nil || token != "a.b.c" {
+		t.Fatalf("Unexpected result %q %v", token, err)
+	}
+	if _, err := s.SignWithHeader([]byte("two"), gojws.Header{Kid: "k1"}); err != nil {
+		t.Fatal("SignWithHeader: ", err)
+	}
+
+	errKMS := errors.New("kms unavailable")
+	m.Err = errKMS
+	if _, err := s.Sign([]byte("three")); err != errKMS {
+		t.Fatalf("Expected canned error. Got %v", err)
+	}
+
+	payloads := m.Payloads()
+	if len(payloads) != 3 || string(payloads[0]) != "one" || string(payloads[2]) != "three" {
+		t.Fatalf("Unexpected recorded payloads %q", payloads)
+	}
+	if headers := m.Headers(); len(headers) != 3 || headers[1].Kid != "k1" {
+		t.Fatalf("Unexpected recorded headers %+v", headers)
+	}
+}
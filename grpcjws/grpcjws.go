@@ -0,0 +1,110 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package grpcjws verifies JWS bearer tokens carried in gRPC metadata.
+package grpcjws
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/mendsley/gojws"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type contextKey int
+
+const (
+	headerContextKey contextKey = iota
+	payloadContextKey
+)
+
+// Server interceptor requiring a valid JWS in the "authorization"
+// metadata, using the Bearer scheme. Tokens are verified with
+// gojws.VerifyBearerToken, so the exp, nbf and other claims of JWTs
+// are checked along with the signature. The verified header and
+// payload are available to the handler through HeaderFromContext and
+// PayloadFromContext. Requests without a valid token fail with
+// codes.Unauthenticated, or codes.InvalidArgument if the token's
+// algorithm is refused.
+func UnaryServerInterceptor(kp gojws.KeyProvider, opts ...gojws.VerifyOption) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		token, ok := bearerToken(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "Missing bearer token")
+		}
+
+		header, payload, err := gojws.VerifyBearerToken(token, kp, opts...)
+		if err != nil {
+			return nil, status.Error(statusCode(err), err.Error())
+		}
+
+		ctx = context.WithValue(ctx, headerContextKey, header)
+		ctx = context.WithValue(ctx, payloadContextKey, payload)
+		return handler(ctx, req)
+	}
+}
+
+// Header of the JWS verified by UnaryServerInterceptor
+func HeaderFromContext(ctx context.Context) (gojws.Header, bool) {
+	header, ok := ctx.Value(headerContextKey).(gojws.Header)
+	return header, ok
+}
+
+// Payload of the JWS verified by UnaryServerInterceptor
+func PayloadFromContext(ctx context.Context) ([]byte, bool) {
+	payload, ok := ctx.Value(payloadContextKey).([]byte)
+	return payload, ok
+}
+
+// gRPC status code for a verification failure
+func statusCode(err error) codes.Code {
+	if errors.Is(err, gojws.ErrAlgorithmDenied) || errors.Is(err, gojws.ErrAlgorithmForbidden) {
+		return codes.InvalidArgument
+	}
+	return codes.Unauthenticated
+}
+
+// extract the token from "authorization: Bearer" metadata
+func bearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	const scheme = "bearer "
+	for _, auth := range md.Get("authorization") {
+		if len(auth) <= len(scheme) || !strings.EqualFold(auth[:len(scheme)], scheme) {
+			continue
+		}
+		if token := strings.TrimSpace(auth[len(scheme):]); token != "" {
+			return token, true
+		}
+	}
+	return "", false
+}
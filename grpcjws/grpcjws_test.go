@@ -0,0 +1,100 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package grpcjws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mendsley/gojws"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	key := []byte("grpc-secret")
+	token, err := gojws.SignWithHeader([]byte("payload"), gojws.Header{Alg: gojws.ALG_HS256, Kid: "k1"}, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	interceptor := UnaryServerInterceptor(gojws.ProviderFromKey(key))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		header, ok := HeaderFromContext(ctx)
+		if !ok || header.Kid != "k1" {
+			t.Errorf("Unexpected header in context: %+v", header)
+		}
+		payload, ok := PayloadFromContext(ctx)
+		if !ok || string(payload) != "payload" {
+			t.Errorf("Unexpected payload in context: %q", payload)
+		}
+		return "ok", nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	tests := []struct {
+		auth []string
+		code codes.Code
+	}{
+		{[]string{"Bearer " + token}, codes.OK},
+		{[]string{"bearer " + token}, codes.OK},
+		{nil, codes.Unauthenticated},
+		{[]string{"Basic dXNlcjpwYXNz"}, codes.Unauthenticated},
+		{[]string{"Bearer " + token + "x"}, codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		if tt.auth != nil {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.auth[0]))
+		}
+
+		resp, err := interceptor(ctx, nil, info, handler)
+		if code := status.Code(err); code != tt.code {
+			t.Fatalf("%q: expected %v. Got %v (%v)", tt.auth, tt.code, code, err)
+		}
+		if tt.code == codes.OK && resp != "ok" {
+			t.Fatalf("%q: unexpected response %v", tt.auth, resp)
+		}
+	}
+
+	// expired JWTs are refused
+	expired, err := gojws.Sign([]byte(`{"exp":1300819380}`), gojws.ALG_HS256, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+expired))
+	if _, err := interceptor(ctx, nil, info, handler); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Expected Unauthenticated for an expired token. Got %v", err)
+	}
+
+	// refused algorithms are reported as invalid arguments
+	denied := UnaryServerInterceptor(gojws.ProviderFromKey(key), gojws.WithDeniedAlgorithms(gojws.ALG_HS256))
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	if _, err := denied(ctx, nil, info, handler); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument. Got %v", err)
+	}
+}
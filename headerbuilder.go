@@ -0,0 +1,126 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// Assembles a Header for SignWithHeader
+type HeaderBuilder struct {
+	header Header
+
+	// first error from a With method, returned by Build
+	err error
+}
+
+// start building a header for alg
+func NewHeader(alg Algorithm) *HeaderBuilder {
+	return &HeaderBuilder{header: Header{Alg: alg}}
+}
+
+// set the kid parameter
+func (b *HeaderBuilder) WithKID(s string) *HeaderBuilder {
+	b.header.Kid = s
+	return b
+}
+
+// set the typ parameter
+func (b *HeaderBuilder) WithTyp(s string) *HeaderBuilder {
+	b.header.Typ = s
+	return b
+}
+
+// set the cty parameter
+func (b *HeaderBuilder) WithCty(s string) *HeaderBuilder {
+	b.header.Cty = s
+	return b
+}
+
+// Embed a certificate chain, leaf first, as the x5c parameter. A nil
+// certificate in the chain causes Build to fail.
+func (b *HeaderBuilder) WithX5C(certs []*x509.Certificate) *HeaderBuilder {
+	for i, cert := range certs {
+		if cert == nil {
+			if b.err == nil {
+				b.err = fmt.Errorf("Certificate %d of the x5c chain is nil", i)
+			}
+			return b
+		}
+	}
+	b.header.X5c = encodeX5C(certs)
+	return b
+}
+
+// Set the x5t parameter to the certificate's SHA-1 thumbprint. A nil
+// certificate causes Build to fail.
+func (b *HeaderBuilder) WithX5T(cert *x509.Certificate) *HeaderBuilder {
+	if cert == nil {
+		if b.err == nil {
+			b.err = errors.New("Certificate for x5t is nil")
+		}
+		return b
+	}
+	b.header.X5t = X5TFromCert(cert)
+	return b
+}
+
+// Carry an ECDH ephemeral public key in the jwk parameter, for
+// recipients deriving a shared secret with ECDHEphemeralKey. Don't use
+// this on tokens whose verifier takes the signing key from jwk (such
+// as DPoP proofs). Keys on curves without a JWK representation cause
+// Build to fail.
+func (b *HeaderBuilder) WithEphemeralJWK(epk *ecdsa.PublicKey) *HeaderBuilder {
+	jwk, err := PublicKeyToJWK(epk)
+	if err != nil {
+		if b.err == nil {
+			b.err = fmt.Errorf("Failed to encode ephemeral key: %v", err)
+		}
+		return b
+	}
+	b.header.Jwk = jwk
+	return b
+}
+
+// The assembled header, or the first error encountered while building
+// it
+func (b *HeaderBuilder) Build() (Header, error) {
+	if b.err != nil {
+		return Header{}, b.err
+	}
+
+	header := b.header
+	header.X5c = append([]string(nil), b.header.X5c...)
+	if len(header.X5c) == 0 {
+		header.X5c = nil
+	}
+	if b.header.Jwk != nil {
+		header.Jwk = append([]byte(nil), b.header.Jwk...)
+	}
+	return header, nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"testing"
+)
+
+func TestHeaderBuilder(t *testing.T) {
+	caCert, caKey := testCertificate(t, "root", nil, nil)
+	leafCert, leafKey := testCertificate(t, "leaf", caCert, caKey)
+
+	b := NewHeader(ALG_ES256).
+		WithKID("k1").
+		WithTyp("JWT").
+		WithCty("application/example").
+		WithX5C([]*x509.Certificate{leafCert, caCert}).
+		WithX5T(leafCert)
+	header, err := b.Build()
+	if err != nil {
+		t.Fatal("Build: ", err)
+	}
+
+	if header.Alg != ALG_ES256 || header.Kid != "k1" || header.Typ != "JWT" || header.Cty != "application/example" {
+		t.Fatalf("Unexpected header %+v", header)
+	}
+	if header.X5t != X5TFromCert(leafCert) {
+		t.Fatalf("Unexpected x5t %q", header.X5t)
+	}
+
+	// the built header must not share state with the builder
+	header.X5c[1] = ""
+	if again, _ := b.Build(); again.X5c[1] == "" {
+		t.Fatal("Build returned the builder's x5c slice")
+	}
+
+	// the embedded chain verifies
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	header, err = b.Build()
+	if err != nil {
+		t.Fatal("Build: ", err)
+	}
+	jws, err := SignWithHeader(signTestPayload, header, leafKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, nil, WithX5CVerification(roots)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	if header, err := NewHeader(ALG_HS256).Build(); err != nil || header.X5c != nil || header.Alg != ALG_HS256 {
+		t.Fatalf("Unexpected minimal header %+v", header)
+	}
+}
+
+func TestHeaderBuilder_EphemeralJWKError(t *testing.T) {
+	// P-224 has no JWK representation
+	key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	if _, err := NewHeader(ALG_HS256).WithEphemeralJWK(&key.PublicKey).WithKID("k1").Build(); err == nil {
+		t.Fatal("Built a header without the ephemeral key")
+	}
+}
+
+func TestHeaderBuilder_NilCertificate(t *testing.T) {
+	if _, err := NewHeader(ALG_ES256).WithX5T(nil).Build(); err == nil {
+		t.Fatal("Built a header with a nil x5t certificate")
+	}
+	if _, err := NewHeader(ALG_ES256).WithX5C(nil).Build(); err != nil {
+		t.Fatal("Build with an empty x5c chain: ", err)
+	}
+	if _, err := NewHeader(ALG_ES256).WithX5C([]*x509.Certificate{nil}).Build(); err == nil {
+		t.Fatal("Built a header with a nil certificate in x5c")
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"crypto/hkdf"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// largest HMAC secret read from an io.Reader
+const maxHMACKeySize = 1 << 16
+
+// Read an HMAC secret, dropping any trailing line ending left by an
+// editor or `echo` when the secret was written to a file
+func HMACKeyFromReader(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxHMACKeySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read HMAC key: %v", err)
+	}
+	if len(data) > maxHMACKeySize {
+		return nil, errors.New("HMAC key is too large")
+	}
+
+	key := bytes.TrimRight(data, "\r\n")
+	if len(key) == 0 {
+		return nil, errors.New("HMAC key is empty")
+	}
+	return key, nil
+}
+
+// Read a base64url encoded HMAC secret. Surrounding whitespace and
+// trailing '=' padding are ignored.
+func HMACKeyFromBase64URLReader(r io.Reader) ([]byte, error) {
+	encoded, err := HMACKeyFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := lenientDecode(string(bytes.TrimSpace(encoded)))
+	if err != nil {
+		return nil, fmt.Errorf("Malformed base64url HMAC key: %v", err)
+	}
+	if len(key) == 0 {
+		return nil, errors.New("HMAC key is empty")
+	}
+	return key, nil
+}
+
+// Derive an HMAC key for alg from a master secret with HKDF (RFC 5869),
+// using the algorithm's own hash. info labels the context the key is
+// used in, so distinct labels yield independent keys. The key is as
+// long as the hash output: 32 bytes for HS256, 48 for HS384 and 64 for
+// HS512.
+func HMACKeyFromHKDF(secret []byte, info []byte, salt []byte, alg Algorithm) ([]byte, error) {
+	switch alg {
+	case ALG_HS256, ALG_HS384, ALG_HS512:
+	default:
+		return nil, fmt.Errorf("Expected an HMAC algorithm. Got %s", alg)
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("HKDF secret is empty")
+	}
+
+	h := hashForAlgorithm(alg)
+	key, err := hkdf.Key(h.New, secret, salt, string(info), h.Size())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive HMAC key: %v", err)
+	}
+	return key, nil
+}
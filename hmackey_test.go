@@ -0,0 +1,119 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHMACKeyFromReader(t *testing.T) {
+	for _, in := range []string{"secret", "secret\n", "secret\r\n", "secret\n\n"} {
+		key, err := HMACKeyFromReader(strings.NewReader(in))
+		if err != nil {
+			t.Fatalf("%q: %v", in, err)
+		}
+		if string(key) != "secret" {
+			t.Fatalf("%q: unexpected key %q", in, key)
+		}
+	}
+
+	// only line endings are trimmed
+	key, err := HMACKeyFromReader(strings.NewReader(" secret \n"))
+	if err != nil {
+		t.Fatal("HMACKeyFromReader: ", err)
+	}
+	if string(key) != " secret " {
+		t.Fatalf("Unexpected key %q", key)
+	}
+
+	for _, in := range []string{"", "\n", strings.Repeat("x", maxHMACKeySize+1)} {
+		if _, err := HMACKeyFromReader(strings.NewReader(in)); err == nil {
+			t.Fatalf("Accepted key of length %d", len(in))
+		}
+	}
+}
+
+func TestHMACKeyFromBase64URLReader(t *testing.T) {
+	raw := []byte{0xfb, 0xff, 0xbf, 0x01}
+	for _, in := range []string{"-_-_AQ", "-_-_AQ==\n", "  -_-_AQ\r\n"} {
+		key, err := HMACKeyFromBase64URLReader(strings.NewReader(in))
+		if err != nil {
+			t.Fatalf("%q: %v", in, err)
+		}
+		if !bytes.Equal(key, raw) {
+			t.Fatalf("%q: unexpected key %v", in, key)
+		}
+	}
+
+	for _, in := range []string{"+/+/AQ", "====", ""} {
+		if _, err := HMACKeyFromBase64URLReader(strings.NewReader(in)); err == nil {
+			t.Fatalf("Accepted %q", in)
+		}
+	}
+}
+
+func TestHMACKeyFromHKDF(t *testing.T) {
+	secret := []byte("master-secret")
+	salt := []byte("salt")
+
+	sizes := map[Algorithm]int{ALG_HS256: 32, ALG_HS384: 48, ALG_HS512: 64}
+	for alg, size := range sizes {
+		key, err := HMACKeyFromHKDF(secret, []byte("tokens"), salt, alg)
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		if len(key) != size {
+			t.Fatalf("%s: expected %d byte key. Got %d", alg, size, len(key))
+		}
+
+		jws, err := Sign([]byte("payload"), alg, key)
+		if err != nil {
+			t.Fatalf("%s: Sign: %v", alg, err)
+		}
+		if err := VerifyWithKey(jws, key); err != nil {
+			t.Fatalf("%s: Verify: %v", alg, err)
+		}
+	}
+
+	// derivation is deterministic and separated by context label
+	a, _ := HMACKeyFromHKDF(secret, []byte("a"), salt, ALG_HS256)
+	again, _ := HMACKeyFromHKDF(secret, []byte("a"), salt, ALG_HS256)
+	b, _ := HMACKeyFromHKDF(secret, []byte("b"), salt, ALG_HS256)
+	if !bytes.Equal(a, again) {
+		t.Fatal("Derivation is not deterministic")
+	}
+	if bytes.Equal(a, b) {
+		t.Fatal("Different labels derived the same key")
+	}
+
+	if _, err := HMACKeyFromHKDF(secret, nil, nil, ALG_RS256); err == nil {
+		t.Fatal("Derived a key for RS256")
+	}
+	if _, err := HMACKeyFromHKDF(nil, nil, nil, ALG_HS256); err == nil {
+		t.Fatal("Derived a key from an empty secret")
+	}
+}
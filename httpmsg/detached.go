@@ -0,0 +1,128 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package httpmsg
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mendsley/gojws"
+)
+
+// header carrying the detached JWS from SignHTTPRequest
+const jwsSignatureHeader = "X-JWS-Signature"
+
+// Sign a request with a detached JWS (RFC 7515 Appendix F) carried in
+// the X-JWS-Signature header. The signed content is the method, the
+// target URI and the SHA-256 digest of the body, one per line. Unlike
+// Sign, no other headers are covered. The body is read and replaced so
+// the request can still be sent.
+func SignHTTPRequest(req *http.Request, key crypto.PrivateKey, alg gojws.Algorithm) error {
+	content, err := detachedContent(req, -1)
+	if err != nil {
+		return err
+	}
+
+	jws, err := gojws.Sign(content, alg, key)
+	if err != nil {
+		return err
+	}
+
+	// detach the payload; the verifier rebuilds it from the request
+	parts := strings.Split(jws, ".")
+	req.Header.Set(jwsSignatureHeader, parts[0]+".."+parts[2])
+	return nil
+}
+
+// Verify the X-JWS-Signature header of a request signed with
+// SignHTTPRequest. The body is read and replaced so a handler can
+// still consume it; bodies larger than the maximum (see
+// WithMaxBodySize) are rejected. Other options are ignored.
+func VerifyHTTPRequest(req *http.Request, kp gojws.KeyProvider, opts ...VerifyOption) error {
+	config := verifyConfig{maxBodySize: defaultMaxBodySize}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	signature := req.Header.Get(jwsSignatureHeader)
+	if signature == "" {
+		return errors.New("Missing " + jwsSignatureHeader + " header")
+	}
+
+	parts := strings.Split(signature, ".")
+	if len(parts) != 3 || parts[1] != "" {
+		return errors.New("Malformed " + jwsSignatureHeader + ": expected detached JWS")
+	}
+
+	content, err := detachedContent(req, config.maxBodySize)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = gojws.VerifyFromParts(parts[0], base64.RawURLEncoding.EncodeToString(content), parts[2], kp)
+	return err
+}
+
+// METHOD "\n" target URI "\n" base64url(SHA-256(body)). A negative
+// maxBodySize reads the body without limit.
+func detachedContent(req *http.Request, maxBodySize int64) ([]byte, error) {
+	digest := sha256.New()
+	if req.Body != nil && req.Body != http.NoBody {
+		r := io.Reader(req.Body)
+		if maxBodySize >= 0 {
+			r = io.LimitReader(r, maxBodySize+1)
+		}
+		body, err := io.ReadAll(r)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if maxBodySize >= 0 && int64(len(body)) > maxBodySize {
+			return nil, fmt.Errorf("Request body exceeds %d bytes", maxBodySize)
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		req.ContentLength = int64(len(body))
+		digest.Write(body)
+	}
+
+	target, err := componentValue(req, "@target-uri")
+	if err != nil {
+		return nil, err
+	}
+
+	content := strings.ToUpper(req.Method) + "\n" + target + "\n" + base64.RawURLEncoding.EncodeToString(digest.Sum(nil))
+	return []byte(content), nil
+}
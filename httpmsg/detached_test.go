@@ -0,0 +1,157 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package httpmsg
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mendsley/gojws"
+)
+
+func TestSignHTTPRequest(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	kp := gojws.ProviderFromKey(&key.PublicKey)
+
+	req, err := http.NewRequest("POST", "https://Example.com/foo?a=1", strings.NewReader(`{"hello": "world"}`))
+	if err != nil {
+		t.Fatal("NewRequest: ", err)
+	}
+	if err := SignHTTPRequest(req, key, gojws.ALG_ES256); err != nil {
+		t.Fatal("SignHTTPRequest: ", err)
+	}
+
+	signature := req.Header.Get("X-JWS-Signature")
+	if parts := strings.Split(signature, "."); len(parts) != 3 || parts[1] != "" {
+		t.Fatalf("Expected a detached JWS. Got %q", signature)
+	}
+
+	// the body survives signing and verification
+	if err := VerifyHTTPRequest(req, kp); err != nil {
+		t.Fatal("VerifyHTTPRequest: ", err)
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal("ReadAll: ", err)
+	}
+	if string(body) != `{"hello": "world"}` {
+		t.Fatalf("Body not preserved: %q", body)
+	}
+
+	// as seen by a server, with only a path in the URL
+	server := httptest.NewRequest("POST", "https://example.com/foo?a=1", strings.NewReader(`{"hello": "world"}`))
+	server.Header.Set("X-JWS-Signature", signature)
+	if err := VerifyHTTPRequest(server, kp); err != nil {
+		t.Fatal("VerifyHTTPRequest: ", err)
+	}
+
+	tampered := []*http.Request{
+		httptest.NewRequest("PUT", "https://example.com/foo?a=1", strings.NewReader(`{"hello": "world"}`)),
+		httptest.NewRequest("POST", "https://example.com/foo?a=2", strings.NewReader(`{"hello": "world"}`)),
+		httptest.NewRequest("POST", "https://other.example.com/foo?a=1", strings.NewReader(`{"hello": "world"}`)),
+		httptest.NewRequest("POST", "https://example.com/foo?a=1", strings.NewReader(`{"hello": "there"}`)),
+	}
+	for _, req := range tampered {
+		req.Header.Set("X-JWS-Signature", signature)
+		if err := VerifyHTTPRequest(req, kp); err == nil {
+			t.Fatalf("Verified tampered request %s %s", req.Method, req.URL)
+		}
+	}
+}
+
+func TestVerifyHTTPRequest_Malformed(t *testing.T) {
+	kp := gojws.ProviderFromKey([]byte("secret"))
+
+	for _, signature := range []string{"", "abc", "a.b.c", "a..b..c"} {
+		req := httptest.NewRequest("GET", "https://example.com/", nil)
+		if signature != "" {
+			req.Header.Set("X-JWS-Signature", signature)
+		}
+		if err := VerifyHTTPRequest(req, kp); err == nil {
+			t.Fatalf("Verified signature %q", signature)
+		}
+	}
+
+	// requests without a body can be signed
+	req := httptest.NewRequest("GET", "https://example.com/", nil)
+	if err := SignHTTPRequest(req, []byte("secret"), gojws.ALG_HS256); err != nil {
+		t.Fatal("SignHTTPRequest: ", err)
+	}
+	if err := VerifyHTTPRequest(req, kp); err != nil {
+		t.Fatal("VerifyHTTPRequest: ", err)
+	}
+}
+
+func TestVerifyHTTPRequest_MaxBodySize(t *testing.T) {
+	const payload = `{"hello": "world"}`
+	kp := gojws.ProviderFromKey([]byte("secret"))
+
+	req := httptest.NewRequest("POST", "https://example.com/", strings.NewReader(payload))
+	if err := SignHTTPRequest(req, []byte("secret"), gojws.ALG_HS256); err != nil {
+		t.Fatal("SignHTTPRequest: ", err)
+	}
+	signature := req.Header.Get("X-JWS-Signature")
+
+	large := httptest.NewRequest("POST", "https://example.com/", strings.NewReader(payload))
+	large.Header.Set("X-JWS-Signature", signature)
+	if err := VerifyHTTPRequest(large, kp, WithMaxBodySize(int64(len(payload)-1))); err == nil {
+		t.Fatal("Verified a request with an oversized body")
+	}
+
+	// a body at the limit is accepted and restored consistently
+	exact := httptest.NewRequest("POST", "https://example.com/", strings.NewReader(payload))
+	exact.Header.Set("X-JWS-Signature", signature)
+	if err := VerifyHTTPRequest(exact, kp, WithMaxBodySize(int64(len(payload)))); err != nil {
+		t.Fatal("VerifyHTTPRequest: ", err)
+	}
+	if exact.ContentLength != int64(len(payload)) {
+		t.Fatalf("Expected ContentLength %d. Got %d", len(payload), exact.ContentLength)
+	}
+	for _, get := range []func() (io.ReadCloser, error){
+		func() (io.ReadCloser, error) { return exact.Body, nil },
+		exact.GetBody,
+	} {
+		rc, err := get()
+		if err != nil {
+			t.Fatal("GetBody: ", err)
+		}
+		body, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal("ReadAll: ", err)
+		}
+		if string(body) != payload {
+			t.Fatalf("Body not preserved: %q", body)
+		}
+	}
+}
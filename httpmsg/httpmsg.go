@@ -0,0 +1,417 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package httpmsg implements HTTP Message Signatures (RFC 9421) using
+// JWS to sign the signature base.
+//
+// The signature base is built from the covered components exactly as
+// described in RFC 9421 Section 2.5. It is then signed as the payload
+// of a JWS, and the JWS (with its payload detached, per RFC 7515
+// Appendix F) is carried as the value of the Signature header. The JWS
+// protected header records the algorithm, so no "alg" parameter is
+// written to Signature-Input.
+package httpmsg
+
+import (
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mendsley/gojws"
+)
+
+// label used for signatures created by Sign
+const signatureLabel = "sig1"
+
+const (
+	// default limit on the age of a signature's created parameter
+	defaultMaxAge = 5 * time.Minute
+
+	// default tolerance for clock differences between signer and
+	// verifier
+	defaultClockSkew = time.Minute
+
+	// default limit on the request body read by VerifyHTTPRequest
+	defaultMaxBodySize = 10 << 20
+)
+
+// Optional behavior for Verify
+type VerifyOption func(*verifyConfig)
+
+type verifyConfig struct {
+	clock       gojws.Clock
+	maxAge      time.Duration
+	clockSkew   time.Duration
+	maxBodySize int64
+}
+
+// Reject signatures created more than maxAge ago (five minutes by
+// default). Zero disables the check, and with it the requirement that
+// signatures carry a created parameter.
+func WithMaxAge(maxAge time.Duration) VerifyOption {
+	return func(c *verifyConfig) {
+		c.maxAge = maxAge
+	}
+}
+
+// Tolerate clocks differing by up to d (one minute by default) when
+// checking the created and expires parameters
+func WithClockSkew(d time.Duration) VerifyOption {
+	return func(c *verifyConfig) {
+		c.clockSkew = d
+	}
+}
+
+// Read the current time from clock rather than the system clock
+func WithClock(clock gojws.Clock) VerifyOption {
+	return func(c *verifyConfig) {
+		c.clock = clock
+	}
+}
+
+// Reject requests whose body is larger than n bytes (10 MiB by default)
+// rather than reading it all into memory. Only VerifyHTTPRequest reads
+// the body.
+func WithMaxBodySize(n int64) VerifyOption {
+	return func(c *verifyConfig) {
+		c.maxBodySize = n
+	}
+}
+
+// Sign an HTTP request, covering the given components. Components are
+// either lower case header field names or derived components such as
+// "@method", "@authority", "@path", "@query", "@target-uri", "@scheme"
+// and "@request-target". The Signature-Input and Signature headers are
+// added to the request.
+func Sign(req *http.Request, components []string, key crypto.PrivateKey, alg gojws.Algorithm) error {
+	seen := make(map[string]bool)
+	quoted := make([]string, len(components))
+	for i, component := range components {
+		if component != strings.ToLower(component) {
+			return fmt.Errorf("Component names must be lower case: %s", component)
+		}
+		if seen[component] {
+			return fmt.Errorf("Duplicate component: %s", component)
+		}
+		seen[component] = true
+		quoted[i] = strconv.Quote(component)
+	}
+
+	params := "(" + strings.Join(quoted, " ") + ");created=" + strconv.FormatInt(time.Now().Unix(), 10)
+	return signWithParams(req, components, params, key, alg)
+}
+
+// sign the request with an already serialized Signature-Input member
+func signWithParams(req *http.Request, components []string, params string, key crypto.PrivateKey, alg gojws.Algorithm) error {
+	base, err := signatureBase(req, components, params)
+	if err != nil {
+		return err
+	}
+
+	jws, err := gojws.Sign(base, alg, key)
+	if err != nil {
+		return err
+	}
+
+	// detach the payload; the verifier rebuilds it from the request
+	parts := strings.Split(jws, ".")
+	detached := parts[0] + ".." + parts[2]
+
+	req.Header.Set("Signature-Input", signatureLabel+"="+params)
+	req.Header.Set("Signature", signatureLabel+"=:"+base64.StdEncoding.EncodeToString([]byte(detached))+":")
+	return nil
+}
+
+// Verify the HTTP Message Signature on a request. The first signature
+// listed in Signature-Input is checked. Signatures past their expires
+// parameter, created in the future, or older than the maximum age
+// (see WithMaxAge) are rejected.
+func Verify(req *http.Request, kp gojws.KeyProvider, opts ...VerifyOption) error {
+	config := verifyConfig{
+		clock:     gojws.SystemClock{},
+		maxAge:    defaultMaxAge,
+		clockSkew: defaultClockSkew,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	label, params, err := firstMember(req.Header.Get("Signature-Input"))
+	if err != nil {
+		return fmt.Errorf("Malformed Signature-Input: %v", err)
+	}
+
+	components, err := parseComponents(params)
+	if err != nil {
+		return fmt.Errorf("Malformed Signature-Input: %v", err)
+	}
+	if err := checkTimes(params, &config); err != nil {
+		return err
+	}
+
+	signature, err := findMember(req.Header.Get("Signature"), label)
+	if err != nil {
+		return fmt.Errorf("Malformed Signature: %v", err)
+	}
+	if len(signature) < 2 || signature[0] != ':' || signature[len(signature)-1] != ':' {
+		return errors.New("Malformed Signature: expected byte sequence")
+	}
+	detached, err := base64.StdEncoding.DecodeString(signature[1 : len(signature)-1])
+	if err != nil {
+		return fmt.Errorf("Malformed Signature: %v", err)
+	}
+
+	parts := strings.Split(string(detached), ".")
+	if len(parts) != 3 || parts[1] != "" {
+		return errors.New("Malformed Signature: expected detached JWS")
+	}
+
+	base, err := signatureBase(req, components, params)
+	if err != nil {
+		return err
+	}
+
+	jws := parts[0] + "." + base64.RawURLEncoding.EncodeToString(base) + "." + parts[2]
+	_, err = gojws.VerifyAndDecode(jws, kp)
+	return err
+}
+
+// build the signature base (RFC 9421 Section 2.5)
+func signatureBase(req *http.Request, components []string, params string) ([]byte, error) {
+	var b strings.Builder
+	for _, component := range components {
+		value, err := componentValue(req, component)
+		if err != nil {
+			return nil, err
+		}
+
+		b.WriteString(strconv.Quote(component))
+		b.WriteString(": ")
+		b.WriteString(value)
+		b.WriteString("\n")
+	}
+
+	b.WriteString(`"@signature-params": `)
+	b.WriteString(params)
+	return []byte(b.String()), nil
+}
+
+func componentValue(req *http.Request, component string) (string, error) {
+	switch component {
+	case "@method":
+		return req.Method, nil
+
+	case "@authority":
+		return authority(req), nil
+
+	case "@scheme":
+		return scheme(req), nil
+
+	case "@path":
+		return path(req), nil
+
+	case "@query":
+		return "?" + req.URL.RawQuery, nil
+
+	case "@request-target":
+		target := path(req)
+		if req.URL.RawQuery != "" {
+			target += "?" + req.URL.RawQuery
+		}
+		return target, nil
+
+	case "@target-uri":
+		target := scheme(req) + "://" + authority(req) + path(req)
+		if req.URL.RawQuery != "" {
+			target += "?" + req.URL.RawQuery
+		}
+		return target, nil
+	}
+
+	if strings.HasPrefix(component, "@") {
+		return "", fmt.Errorf("Unsupported derived component: %s", component)
+	}
+
+	values := req.Header.Values(component)
+	if len(values) == 0 {
+		return "", fmt.Errorf("Covered header is missing: %s", component)
+	}
+	// don't modify the header's own slice
+	trimmed := make([]string, len(values))
+	for i, value := range values {
+		trimmed[i] = strings.TrimSpace(value)
+	}
+	return strings.Join(trimmed, ", "), nil
+}
+
+func authority(req *http.Request) string {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	return strings.ToLower(host)
+}
+
+func scheme(req *http.Request) string {
+	if req.URL.Scheme != "" {
+		return strings.ToLower(req.URL.Scheme)
+	}
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+func path(req *http.Request) string {
+	if p := req.URL.EscapedPath(); p != "" {
+		return p
+	}
+	return "/"
+}
+
+// split a structured field dictionary into its members
+func dictionaryMembers(value string) []string {
+	return splitUnquoted(value, ',')
+}
+
+// split value at each sep that is not inside a quoted string
+func splitUnquoted(value string, sep byte) []string {
+	var members []string
+	var inString bool
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			if inString {
+				i++
+			}
+		case '"':
+			inString = !inString
+		case sep:
+			if !inString {
+				members = append(members, strings.TrimSpace(value[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(members, strings.TrimSpace(value[start:]))
+}
+
+func firstMember(value string) (label, member string, err error) {
+	label, member, ok := strings.Cut(dictionaryMembers(value)[0], "=")
+	if !ok || label == "" {
+		return "", "", errors.New("no signature found")
+	}
+	return label, member, nil
+}
+
+func findMember(value, label string) (string, error) {
+	for _, member := range dictionaryMembers(value) {
+		name, member, ok := strings.Cut(member, "=")
+		if ok && name == label {
+			return member, nil
+		}
+	}
+	return "", fmt.Errorf("no signature labeled %q", label)
+}
+
+// check the created and expires signature parameters against the
+// current time (RFC 9421 Section 2.3)
+func checkTimes(params string, config *verifyConfig) error {
+	now := config.clock.Now()
+
+	created, hasCreated, err := timeParameter(params, "created")
+	if err != nil {
+		return fmt.Errorf("Malformed Signature-Input: %v", err)
+	}
+	if hasCreated && created.After(now.Add(config.clockSkew)) {
+		return errors.New("Signature was created in the future")
+	}
+	if config.maxAge > 0 {
+		if !hasCreated {
+			return errors.New("Signature has no created parameter")
+		}
+		if now.Sub(created) > config.maxAge+config.clockSkew {
+			return errors.New("Signature is too old")
+		}
+	}
+
+	expires, hasExpires, err := timeParameter(params, "expires")
+	if err != nil {
+		return fmt.Errorf("Malformed Signature-Input: %v", err)
+	}
+	if hasExpires && now.After(expires.Add(config.clockSkew)) {
+		return errors.New("Signature has expired")
+	}
+	return nil
+}
+
+// find an integer timestamp among the parameters following the inner
+// list of a Signature-Input member
+func timeParameter(params, name string) (time.Time, bool, error) {
+	end := strings.Index(params, ")")
+	if end < 0 {
+		return time.Time{}, false, errors.New("unterminated inner list")
+	}
+
+	for _, param := range splitUnquoted(params[end+1:], ';') {
+		key, value, _ := strings.Cut(param, "=")
+		if key != name {
+			continue
+		}
+
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s parameter %q", name, value)
+		}
+		return time.Unix(seconds, 0), true, nil
+	}
+	return time.Time{}, false, nil
+}
+
+// extract the component names from a serialized inner list
+func parseComponents(params string) ([]string, error) {
+	if !strings.HasPrefix(params, "(") {
+		return nil, errors.New("expected inner list")
+	}
+	end := strings.Index(params, ")")
+	if end < 0 {
+		return nil, errors.New("unterminated inner list")
+	}
+
+	var components []string
+	for _, item := range strings.Fields(params[1:end]) {
+		component, err := strconv.Unquote(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid component %s", item)
+		}
+		components = append(components, component)
+	}
+	return components, nil
+}
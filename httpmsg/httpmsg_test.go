@@ -0,0 +1,197 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package httpmsg
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mendsley/gojws"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	kp := gojws.ProviderFromKey(&key.PublicKey)
+
+	req, err := http.NewRequest("POST", "https://example.com/foo?param=Value&Pet=dog", strings.NewReader(`{"hello": "world"}`))
+	if err != nil {
+		t.Fatal("NewRequest: ", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Digest", "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:")
+
+	components := []string{"@method", "@authority", "@path", "@query", "content-type", "content-digest"}
+	if err := Sign(req, components, key, gojws.ALG_ES256); err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	if !strings.HasPrefix(req.Header.Get("Signature-Input"), `sig1=("@method" "@authority" "@path" "@query" "content-type" "content-digest");created=`) {
+		t.Fatal("Unexpected Signature-Input: ", req.Header.Get("Signature-Input"))
+	}
+
+	if err := Verify(req, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	// any change to a covered component invalidates the signature
+	req.Header.Set("Content-Type", "text/plain")
+	if err := Verify(req, kp); err == nil {
+		t.Fatal("Verified a request with a modified header")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	req.Method = "PUT"
+	if err := Verify(req, kp); err == nil {
+		t.Fatal("Verified a request with a modified method")
+	}
+	req.Method = "POST"
+
+	// uncovered headers may change freely
+	req.Header.Set("X-Uncovered", "anything")
+	if err := Verify(req, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+}
+
+func TestSign_InvalidComponents(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://example.com/", nil)
+	if err != nil {
+		t.Fatal("NewRequest: ", err)
+	}
+
+	key := []byte("secret")
+	for _, components := range [][]string{
+		{"Content-Type"},
+		{"@method", "@method"},
+		{"x-missing"},
+		{"@unknown"},
+	} {
+		if err := Sign(req, components, key, gojws.ALG_HS256); err == nil {
+			t.Fatalf("Signed with invalid components %v", components)
+		}
+	}
+}
+
+func TestVerify_Malformed(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://example.com/", nil)
+	if err != nil {
+		t.Fatal("NewRequest: ", err)
+	}
+	kp := gojws.ProviderFromKey([]byte("secret"))
+
+	if err := Verify(req, kp); err == nil {
+		t.Fatal("Verified an unsigned request")
+	}
+
+	req.Header.Set("Signature-Input", `sig1=("@method");created=1`)
+	req.Header.Set("Signature", `sig2=:AAAA:`)
+	if err := Verify(req, kp); err == nil {
+		t.Fatal("Verified with a mismatched label")
+	}
+}
+
+func TestVerify_Times(t *testing.T) {
+	key := []byte("secret")
+	kp := gojws.ProviderFromKey(key)
+	now := time.Now()
+	unix := func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }
+
+	signed := func(params string) *http.Request {
+		req, err := http.NewRequest("GET", "https://example.com/", nil)
+		if err != nil {
+			t.Fatal("NewRequest: ", err)
+		}
+		if err := signWithParams(req, []string{"@method"}, `("@method")`+params, key, gojws.ALG_HS256); err != nil {
+			t.Fatal("sign: ", err)
+		}
+		return req
+	}
+
+	tests := []struct {
+		params string
+		opts   []VerifyOption
+		valid  bool
+	}{
+		{";created=" + unix(now), nil, true},
+		{";created=" + unix(now.Add(-defaultMaxAge)), nil, true},
+		{";created=" + unix(now.Add(-defaultMaxAge-defaultClockSkew-time.Second)), nil, false},
+		{";created=" + unix(now.Add(-time.Hour)), []VerifyOption{WithMaxAge(2 * time.Hour)}, true},
+		{";created=" + unix(now.Add(-time.Hour)), []VerifyOption{WithMaxAge(0)}, true},
+		{";created=" + unix(now.Add(30*time.Second)), nil, true},
+		{";created=" + unix(now.Add(time.Hour)), nil, false},
+		{";created=" + unix(now.Add(time.Hour)), []VerifyOption{WithMaxAge(0)}, false},
+		{";created=" + unix(now) + ";expires=" + unix(now.Add(time.Minute)), nil, true},
+		{";created=" + unix(now.Add(-2*time.Minute)) + ";expires=" + unix(now.Add(-defaultClockSkew-time.Second)), nil, false},
+		{";created=" + unix(now.Add(-2*time.Minute)) + ";expires=" + unix(now.Add(-time.Minute)), []VerifyOption{WithClockSkew(0)}, false},
+		{`;nonce="x;created=1";created=` + unix(now), nil, true},
+		{";created=soon", nil, false},
+		{"", nil, false},
+		{"", []VerifyOption{WithMaxAge(0)}, true},
+	}
+	for _, test := range tests {
+		err := Verify(signed(test.params), kp, test.opts...)
+		if test.valid && err != nil {
+			t.Fatalf("%s: Verify: %v", test.params, err)
+		}
+		if !test.valid && err == nil {
+			t.Fatalf("%s: expected verification to fail", test.params)
+		}
+	}
+
+	// the clock can be replaced
+	req := signed(";created=" + unix(now))
+	if err := Verify(req, kp, WithClock(gojws.FixedClock(now.Add(time.Hour)))); err == nil {
+		t.Fatal("Verified a signature that is too old by the given clock")
+	}
+}
+
+func TestVerify_HeaderNotModified(t *testing.T) {
+	key := []byte("secret")
+	req, err := http.NewRequest("GET", "https://example.com/", nil)
+	if err != nil {
+		t.Fatal("NewRequest: ", err)
+	}
+	req.Header.Add("X-List", " a ")
+	req.Header.Add("X-List", "b ")
+	if err := Sign(req, []string{"x-list"}, key, gojws.ALG_HS256); err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if err := Verify(req, gojws.ProviderFromKey(key)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	if values := req.Header.Values("X-List"); values[0] != " a " || values[1] != "b " {
+		t.Fatalf("Header values were modified: %q", values)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSignIntrospectionResponse(t *testing.T) {
+	key := []byte("introspection-secret")
+	exp := NewNumericDate(time.Now().Add(time.Hour))
+
+	jws, err := SignIntrospectionResponse(IntrospectionResponse{
+		StandardClaims: StandardClaims{
+			Issuer:    "https://as.example.com",
+			Subject:   "joe",
+			ExpiresAt: exp,
+		},
+		Active:    true,
+		TokenType: "Bearer",
+		Scope:     "read write",
+		ClientID:  "client-1",
+	}, Header{Alg: ALG_HS256, Typ: "token-introspection+jwt"}, key)
+	if err != nil {
+		t.Fatal("SignIntrospectionResponse: ", err)
+	}
+
+	header, resp, err := VerifyAndDecodeClaims[IntrospectionResponse](jws, ProviderFromKey(key))
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if header.Typ != "token-introspection+jwt" {
+		t.Fatalf("Unexpected typ %q", header.Typ)
+	}
+	if !resp.Active || resp.TokenType != "Bearer" || resp.Scope != "read write" || resp.ClientID != "client-1" {
+		t.Fatalf("Unexpected response %+v", resp)
+	}
+	if resp.Issuer != "https://as.example.com" || resp.Subject != "joe" || !resp.ExpiresAt.Equal(exp.Time) {
+		t.Fatalf("Unexpected claims %+v", resp.StandardClaims)
+	}
+}
+
+func TestIntrospectionResponse_Inactive(t *testing.T) {
+	// RFC 7662 requires "active" even when the token is not
+	data, err := json.Marshal(IntrospectionResponse{})
+	if err != nil {
+		t.Fatal("Marshal: ", err)
+	}
+	if string(data) != `{"active":false}` {
+		t.Fatalf("Unexpected encoding %s", data)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+// Properties of a supported signature algorithm
+type AlgorithmInfo struct {
+	Algorithm Algorithm
+
+	// Signature scheme: "none", "HMAC", "RSASSA-PKCS1-v1_5",
+	// "RSASSA-PSS", "ECDSA" or "EdDSA"
+	Family string
+
+	// Output size of the hash used by the algorithm
+	HashBits int
+
+	// JWK "kty" of keys used with the algorithm
+	KeyType string
+
+	// Approximate security strength, per NIST SP 800-57. For RSA this
+	// assumes the 2048 bit minimum required by RFC 7518.
+	SecurityStrengthBits int
+
+	// Whether new deployments should avoid the algorithm
+	Deprecated bool
+
+	// Specification registering the algorithm for JWS
+	RFC string
+}
+
+var algorithmInventory = []AlgorithmInfo{
+	{ALG_NONE, "none", 0, "", 0, true, "RFC 7518"},
+	{ALG_HS256, "HMAC", 256, "oct", 128, false, "RFC 7518"},
+	{ALG_HS384, "HMAC", 384, "oct", 192, false, "RFC 7518"},
+	{ALG_HS512, "HMAC", 512, "oct", 256, false, "RFC 7518"},
+	{ALG_RS256, "RSASSA-PKCS1-v1_5", 256, "RSA", 112, false, "RFC 7518"},
+	{ALG_RS384, "RSASSA-PKCS1-v1_5", 384, "RSA", 112, false, "RFC 7518"},
+	{ALG_RS512, "RSASSA-PKCS1-v1_5", 512, "RSA", 112, false, "RFC 7518"},
+	{ALG_ES256, "ECDSA", 256, "EC", 128, false, "RFC 7518"},
+	{ALG_ES384, "ECDSA", 384, "EC", 192, false, "RFC 7518"},
+	{ALG_ES512, "ECDSA", 512, "EC", 256, false, "RFC 7518"},
+	{ALG_ES256K, "ECDSA", 256, "EC", 128, false, "RFC 8812"},
+	{ALG_EdDSA, "EdDSA", 512, "OKP", 128, false, "RFC 8037"},
+	{ALG_PS256, "RSASSA-PSS", 256, "RSA", 112, false, "RFC 7518"},
+	{ALG_PS384, "RSASSA-PSS", 384, "RSA", 112, false, "RFC 7518"},
+	{ALG_PS512, "RSASSA-PSS", 512, "RSA", 112, false, "RFC 7518"},
+}
+
+// List every algorithm implemented by the package along with its
+// properties, for use in operational inventories and runbooks
+func AlgorithmInventory() []AlgorithmInfo {
+	inventory := make([]AlgorithmInfo, len(algorithmInventory))
+	copy(inventory, algorithmInventory)
+	return inventory
+}
+
+// Every algorithm implemented by the package, in a stable order
+func SupportedAlgorithms() []Algorithm {
+	algs := make([]Algorithm, len(algorithmInventory))
+	for i, info := range algorithmInventory {
+		algs[i] = info.Algorithm
+	}
+	return algs
+}
+
+// Whether the algorithm signs with a shared secret (HMAC)
+func (a Algorithm) IsSymmetric() bool {
+	info, ok := lookupAlgorithm(a)
+	return ok && info.Family == "HMAC"
+}
+
+// Whether the algorithm signs with a private key and verifies with a
+// public key. False for "none" and unknown algorithms.
+func (a Algorithm) IsAsymmetric() bool {
+	info, ok := lookupAlgorithm(a)
+	return ok && info.Family != "HMAC" && info.Family != "none"
+}
+
+// Output size in bytes of the hash used by the algorithm, or 0 for
+// "none" and unknown algorithms
+func (a Algorithm) HashSize() int {
+	info, _ := lookupAlgorithm(a)
+	return info.HashBits / 8
+}
+
+func lookupAlgorithm(alg Algorithm) (AlgorithmInfo, bool) {
+	for _, info := range algorithmInventory {
+		if info.Algorithm == alg {
+			return info, true
+		}
+	}
+	return AlgorithmInfo{}, false
+}
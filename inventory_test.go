@@ -0,0 +1,95 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"testing"
+)
+
+func TestAlgorithmInventory(t *testing.T) {
+	inventory := AlgorithmInventory()
+	seen := make(map[Algorithm]bool)
+	for _, info := range inventory {
+		t.Logf("%-7s %-18s hash=%-3d kty=%-3s strength=%-3d deprecated=%-5v %s",
+			info.Algorithm, info.Family, info.HashBits, info.KeyType,
+			info.SecurityStrengthBits, info.Deprecated, info.RFC)
+
+		if seen[info.Algorithm] {
+			t.Fatalf("Duplicate inventory entry for %s", info.Algorithm)
+		}
+		seen[info.Algorithm] = true
+	}
+
+	// every listed algorithm must actually be implemented
+	for alg := range seen {
+		_, err := Sign(nil, alg, nil)
+		if err != nil && err.Error() == "Unknown signature algorithm: "+string(alg) {
+			t.Fatalf("Inventory lists unimplemented algorithm %s", alg)
+		}
+	}
+
+	// callers must not be able to modify the package's copy
+	inventory[0].Deprecated = false
+	if !AlgorithmInventory()[0].Deprecated {
+		t.Fatal("Inventory was modified through returned slice")
+	}
+}
+
+func TestSupportedAlgorithms(t *testing.T) {
+	algs := SupportedAlgorithms()
+	if len(algs) != len(algorithmInventory) || algs[0] != ALG_NONE || algs[1] != ALG_HS256 {
+		t.Fatalf("Unexpected algorithms %v", algs)
+	}
+
+	// callers can't modify the table
+	algs[0] = "XX999"
+	if SupportedAlgorithms()[0] != ALG_NONE {
+		t.Fatal("SupportedAlgorithms returned the shared table")
+	}
+}
+
+func TestAlgorithmProperties(t *testing.T) {
+	tests := []struct {
+		alg        Algorithm
+		symmetric  bool
+		asymmetric bool
+		hashSize   int
+	}{
+		{ALG_NONE, false, false, 0},
+		{ALG_HS384, true, false, 48},
+		{ALG_RS256, false, true, 32},
+		{ALG_PS512, false, true, 64},
+		{ALG_ES256K, false, true, 32},
+		{ALG_EdDSA, false, true, 64},
+		{"XX999", false, false, 0},
+	}
+
+	for _, tt := range tests {
+		if tt.alg.IsSymmetric() != tt.symmetric || tt.alg.IsAsymmetric() != tt.asymmetric || tt.alg.HashSize() != tt.hashSize {
+			t.Fatalf("%s: unexpected properties symmetric=%v asymmetric=%v hash=%d",
+				tt.alg, tt.alg.IsSymmetric(), tt.alg.IsAsymmetric(), tt.alg.HashSize())
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// most jku URLs whose key sets are cached at once
+const maxJKUCacheEntries = 64
+
+// Restricts the jku URLs a fetcher will follow
+type JKUFetcherOptions struct {
+	// URLs allowed exactly, ignoring any fragment
+	AllowedURLs []*url.URL
+
+	// Hosts whose URLs are allowed. An entry beginning with '.' also
+	// allows every subdomain, e.g. ".example.com".
+	AllowedDomains []string
+
+	// Client used for fetching. A client with a 10 second timeout when
+	// nil.
+	HTTPClient *http.Client
+}
+
+// Fetch the JWKS named by each token's jku header, after checking the
+// URL against the allow-list (RFC 7515 Section 4.1.2). Only https URLs
+// are fetched, and redirects must also satisfy the allow-list. Tokens
+// whose jku is not allowed fail with ErrJKUNotAllowed. Each URL's key
+// set is cached as by NewRemoteJWKSProvider.
+func NewJKUFetcher(opts JKUFetcherOptions) KeyProvider {
+	f := &jkuFetcher{
+		allowedURLs:    append([]*url.URL(nil), opts.AllowedURLs...),
+		allowedDomains: append([]string(nil), opts.AllowedDomains...),
+		now:            time.Now,
+		cache:          make(map[string]*remoteJWKS),
+	}
+
+	client := defaultFetchClient
+	if opts.HTTPClient != nil {
+		client = opts.HTTPClient
+	}
+
+	// copy the client so the caller's redirect policy is untouched
+	f.client = new(http.Client)
+	*f.client = *client
+	f.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !f.allowed(req.URL) {
+			return fmt.Errorf("%w: redirect to %s", ErrJKUNotAllowed, req.URL)
+		}
+		if len(via) >= 10 {
+			return errors.New("Too many redirects")
+		}
+		return nil
+	}
+
+	return f
+}
+
+type jkuFetcher struct {
+	allowedURLs    []*url.URL
+	allowedDomains []string
+	client         *http.Client
+	now            func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]*remoteJWKS
+}
+
+func (f *jkuFetcher) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	if h.Jku == "" {
+		return nil, errors.New("JWS header has no jku")
+	}
+
+	u, err := url.Parse(h.Jku)
+	if err != nil || !f.allowed(u) {
+		return nil, fmt.Errorf("%w: %s", ErrJKUNotAllowed, h.Jku)
+	}
+
+	return f.source(u.String()).GetJWSKey(h)
+}
+
+// the cached key set for jwksURL. An arbitrary entry is evicted when
+// the cache is full, so tokens naming many URLs can't grow it without
+// bound.
+func (f *jkuFetcher) source(jwksURL string) *remoteJWKS {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if source, ok := f.cache[jwksURL]; ok {
+		return source
+	}
+
+	if len(f.cache) >= maxJKUCacheEntries {
+		for u := range f.cache {
+			delete(f.cache, u)
+			break
+		}
+	}
+
+	source := newRemoteJWKS(jwksURL, f.client, func() time.Time { return f.now() })
+	f.cache[jwksURL] = source
+	return source
+}
+
+// whether u passes the allow-list
+func (f *jkuFetcher) allowed(u *url.URL) bool {
+	if u.Scheme != "https" || u.User != nil {
+		return false
+	}
+
+	for _, allowed := range f.allowedURLs {
+		if allowed.Scheme == u.Scheme && strings.EqualFold(allowed.Host, u.Host) && allowed.EscapedPath() == u.EscapedPath() && allowed.RawQuery == u.RawQuery {
+			return true
+		}
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range f.allowedDomains {
+		domain = strings.ToLower(domain)
+		if host == strings.TrimPrefix(domain, ".") || (strings.HasPrefix(domain, ".") && strings.HasSuffix(host, domain)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,143 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestJKUFetcher(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	jwks := testJWKS(t, map[string]interface{}{`"kid":"k1"`: &key.PublicKey})
+
+	fetches := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write(jwks)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://localhost.invalid/jwks.json", http.StatusFound)
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	sign := func(jku string) string {
+		jws, err := SignWithHeader(signTestPayload, Header{Alg: ALG_ES256, Kid: "k1", Jku: jku}, key)
+		if err != nil {
+			t.Fatal("Sign: ", err)
+		}
+		return jws
+	}
+
+	allowedURL, err := url.Parse(server.URL + "/jwks.json")
+	if err != nil {
+		t.Fatal("Parse: ", err)
+	}
+	byURL := NewJKUFetcher(JKUFetcherOptions{AllowedURLs: []*url.URL{allowedURL}, HTTPClient: server.Client()})
+	byDomain := NewJKUFetcher(JKUFetcherOptions{AllowedDomains: []string{"127.0.0.1"}, HTTPClient: server.Client()})
+
+	for _, kp := range []KeyProvider{byURL, byDomain} {
+		for i := 0; i < 2; i++ {
+			if _, err := VerifyAndDecode(sign(server.URL+"/jwks.json"), kp); err != nil {
+				t.Fatal("Verify: ", err)
+			}
+		}
+	}
+	if fetches != 2 {
+		t.Fatalf("Expected one fetch per fetcher. Got %d", fetches)
+	}
+
+	// the cached key set expires
+	now := time.Now().Add(remoteJWKSLifetime)
+	byURL.(*jkuFetcher).now = func() time.Time { return now }
+	if _, err := VerifyAndDecode(sign(server.URL+"/jwks.json"), byURL); err != nil || fetches != 3 {
+		t.Fatalf("Expected the cache to expire: fetches=%d err=%v", fetches, err)
+	}
+
+	// the cache is bounded
+	for i := 0; i < 2*maxJKUCacheEntries; i++ {
+		byDomain.GetJWSKey(Header{Kid: "k1", Jku: server.URL + "/jwks.json?n=" + strconv.Itoa(i)})
+	}
+	if n := len(byDomain.(*jkuFetcher).cache); n > maxJKUCacheEntries {
+		t.Fatalf("Cache grew to %d entries", n)
+	}
+
+	if c := NewJKUFetcher(JKUFetcherOptions{}).(*jkuFetcher).client; c.Timeout == 0 {
+		t.Fatal("Default client has no timeout")
+	}
+
+	if _, err := byURL.GetJWSKey(Header{Kid: "k1", Jku: server.URL + "/other.json"}); !errors.Is(err, ErrJKUNotAllowed) {
+		t.Fatalf("Expected ErrJKUNotAllowed. Got %v", err)
+	}
+	if _, err := byDomain.GetJWSKey(Header{Kid: "k1", Jku: "https://attacker.example/jwks.json"}); !errors.Is(err, ErrJKUNotAllowed) {
+		t.Fatalf("Expected ErrJKUNotAllowed. Got %v", err)
+	}
+	if _, err := byDomain.GetJWSKey(Header{Kid: "k1", Jku: "http://127.0.0.1/jwks.json"}); !errors.Is(err, ErrJKUNotAllowed) {
+		t.Fatalf("Expected ErrJKUNotAllowed for plain http. Got %v", err)
+	}
+	if _, err := byDomain.GetJWSKey(Header{Kid: "k1", Jku: server.URL + "/redirect"}); !errors.Is(err, ErrJKUNotAllowed) {
+		t.Fatalf("Expected ErrJKUNotAllowed for redirect. Got %v", err)
+	}
+	if _, err := byDomain.GetJWSKey(Header{Kid: "k1"}); err == nil {
+		t.Fatal("Expected an error without a jku")
+	}
+}
+
+func TestJKUFetcher_AllowedDomains(t *testing.T) {
+	f := NewJKUFetcher(JKUFetcherOptions{AllowedDomains: []string{"example.com", ".example.org"}}).(*jkuFetcher)
+	tests := map[string]bool{
+		"https://example.com/jwks":         true,
+		"https://EXAMPLE.com:8443/jwks":    true,
+		"https://www.example.com/jwks":     false,
+		"https://example.org/jwks":         true,
+		"https://keys.example.org/jwks":    true,
+		"https://badexample.org/jwks":      false,
+		"https://example.com.evil.io/jwks": false,
+		"https://user@example.com/jwks":    false,
+		"ftp://example.com/jwks":           false,
+	}
+	for raw, expected := range tests {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal("Parse: ", err)
+		}
+		if f.allowed(u) != expected {
+			t.Fatalf("%s: expected allowed=%v", raw, expected)
+		}
+	}
+}
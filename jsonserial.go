@@ -0,0 +1,93 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// JWS JSON Serialization (RFC 7515 Section 7.2.1)
+type generalJWS struct {
+	Payload    *string         `json:"payload"`
+	Signatures []jsonSignature `json:"signatures"`
+}
+
+type jsonSignature struct {
+	Protected string  `json:"protected"`
+	Header    *Header `json:"header,omitempty"`
+	Signature string  `json:"signature"`
+}
+
+// Verify every signature of a JWS in the general JSON serialization,
+// returning the payload only if all of them are valid. A kid in a
+// signature's unprotected header is used to select its key when the
+// protected header has none. The error names the index of the first
+// signature that failed.
+func VerifyAllSignatures(jsonToken []byte, kp KeyProvider) ([]byte, error) {
+	var jws generalJWS
+	err := json.Unmarshal(jsonToken, &jws)
+	if err != nil {
+		return nil, fmt.Errorf("Malformed JWS JSON serialization: %v", err)
+	}
+	if jws.Payload == nil {
+		return nil, errors.New("Malformed JWS JSON serialization: missing payload")
+	}
+	if len(jws.Signatures) == 0 {
+		return nil, errors.New("Malformed JWS JSON serialization: no signatures")
+	}
+
+	var payload []byte
+	for i, sig := range jws.Signatures {
+		sigKP := kp
+		if sig.Header != nil && sig.Header.Kid != "" && kp != nil {
+			sigKP = unprotectedKID{kp: kp, kid: sig.Header.Kid}
+		}
+
+		_, payload, err = VerifyFromParts(sig.Protected, *jws.Payload, sig.Signature, sigKP)
+		if err != nil {
+			return nil, fmt.Errorf("Signature %d: %w", i, err)
+		}
+	}
+	return payload, nil
+}
+
+// supplies a kid from the unprotected header to the wrapped provider
+type unprotectedKID struct {
+	kp  KeyProvider
+	kid string
+}
+
+func (u unprotectedKID) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	if h.Kid == "" {
+		h.Kid = u.kid
+	}
+	if kkp, ok := u.kp.(KIDKeyProvider); ok {
+		return kkp.GetKeyByKID(h.Kid)
+	}
+	return u.kp.GetJWSKey(h)
+}
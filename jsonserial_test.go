@@ -0,0 +1,107 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// assemble a general JSON serialization from compact tokens sharing a
+// payload, moving each kid into headers[i] when one is given
+func testGeneralJWS(t *testing.T, tokens []string, kids []string) []byte {
+	var jws generalJWS
+	for i, token := range tokens {
+		parts := strings.Split(token, ".")
+		jws.Payload = &parts[1]
+
+		sig := jsonSignature{Protected: parts[0], Signature: parts[2]}
+		if kids[i] != "" {
+			sig.Header = &Header{Kid: kids[i]}
+		}
+		jws.Signatures = append(jws.Signatures, sig)
+	}
+
+	data, err := json.Marshal(jws)
+	if err != nil {
+		t.Fatal("Marshal: ", err)
+	}
+	return data
+}
+
+func TestVerifyAllSignatures(t *testing.T) {
+	kp := &testKIDProvider{
+		keys: map[string]crypto.PublicKey{
+			"k1": []byte("secret-1"),
+			"k2": []byte("secret-2"),
+		},
+	}
+
+	// kid in the protected header
+	first, err := SignWithHeader(signTestPayload, Header{Alg: ALG_HS256, Kid: "k1"}, []byte("secret-1"))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	// kid only in the unprotected header
+	second, err := Sign(signTestPayload, ALG_HS384, []byte("secret-2"))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	payload, err := VerifyAllSignatures(testGeneralJWS(t, []string{first, second}, []string{"", "k2"}), kp)
+	if err != nil {
+		t.Fatal("VerifyAllSignatures: ", err)
+	}
+	if string(payload) != string(signTestPayload) {
+		t.Fatalf("Unexpected payload %q", payload)
+	}
+
+	// a single bad signature fails the whole token, naming its index
+	bad, err := Sign(signTestPayload, ALG_HS256, []byte("wrong"))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	payload, err = VerifyAllSignatures(testGeneralJWS(t, []string{first, bad}, []string{"", "k2"}), kp)
+	if err == nil || payload != nil {
+		t.Fatal("Verified a token with a bad signature")
+	}
+	if !strings.Contains(err.Error(), "Signature 1") {
+		t.Fatalf("Error does not identify the failed signature: %v", err)
+	}
+
+	for _, data := range []string{
+		``,
+		`{}`,
+		`{"payload":"abc"}`,
+		`{"payload":"abc","signatures":[]}`,
+		`{"signatures":[{"protected":"e30","signature":""}]}`,
+	} {
+		if _, err := VerifyAllSignatures([]byte(data), kp); err == nil {
+			t.Fatalf("Verified %q", data)
+		}
+	}
+}
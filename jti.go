@@ -0,0 +1,96 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Create a random 128-bit JWT ID, base64url encoded
+func GenerateJTI() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", fmt.Errorf("Failed to generate jti: %v", err)
+	}
+	return safeEncode(id[:]), nil
+}
+
+// Records the JWT IDs that have been accepted, for replay detection
+type JTIStore interface {
+	// Report whether jti has been seen before, and record it if not.
+	// The entry may be forgotten once expiry has passed; a zero expiry
+	// means the entry never expires.
+	HasAndStore(jti string, expiry time.Time) (bool, error)
+}
+
+// how often expired entries are swept from an InMemoryJTIStore
+const jtiPruneInterval = time.Minute
+
+// JTIStore held in process memory. Suitable for a single instance; a
+// fleet of verifiers needs a shared store.
+type InMemoryJTIStore struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// create an empty in-memory JTI store
+func NewInMemoryJTIStore() *InMemoryJTIStore {
+	return &InMemoryJTIStore{
+		seen: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+func (s *InMemoryJTIStore) HasAndStore(jti string, expiry time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastPrune) >= jtiPruneInterval {
+		for id, exp := range s.seen {
+			if jtiExpired(exp, now) {
+				delete(s.seen, id)
+			}
+		}
+		s.lastPrune = now
+	}
+
+	// entries may linger between sweeps; treat them as gone
+	if exp, ok := s.seen[jti]; ok && !jtiExpired(exp, now) {
+		return true, nil
+	}
+
+	s.seen[jti] = expiry
+	return false, nil
+}
+
+func jtiExpired(expiry, now time.Time) bool {
+	return !expiry.IsZero() && !now.Before(expiry)
+}
@@ -0,0 +1,88 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestGenerateJTI(t *testing.T) {
+	a, err := GenerateJTI()
+	if err != nil {
+		t.Fatal("GenerateJTI: ", err)
+	}
+	b, err := GenerateJTI()
+	if err != nil {
+		t.Fatal("GenerateJTI: ", err)
+	}
+	if a == b {
+		t.Fatal("GenerateJTI returned the same value twice")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(a)
+	if err != nil {
+		t.Fatal("Decode: ", err)
+	}
+	if len(raw) != 16 {
+		t.Fatalf("Expected 128-bit jti. Got %d bits", 8*len(raw))
+	}
+}
+
+func TestInMemoryJTIStore(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	store := NewInMemoryJTIStore()
+	store.now = func() time.Time { return now }
+
+	seen, err := store.HasAndStore("a", now.Add(time.Minute))
+	if err != nil || seen {
+		t.Fatalf("First use: seen=%v err=%v", seen, err)
+	}
+	seen, err = store.HasAndStore("a", now.Add(time.Minute))
+	if err != nil || !seen {
+		t.Fatalf("Second use: seen=%v err=%v", seen, err)
+	}
+	if _, err := store.HasAndStore("forever", time.Time{}); err != nil {
+		t.Fatal("HasAndStore: ", err)
+	}
+
+	// once expired, the jti may be reused and is pruned
+	now = now.Add(2 * jtiPruneInterval)
+	seen, err = store.HasAndStore("b", now.Add(time.Minute))
+	if err != nil || seen {
+		t.Fatalf("Unrelated jti: seen=%v err=%v", seen, err)
+	}
+	if _, ok := store.seen["a"]; ok {
+		t.Fatal("Expired jti was not pruned")
+	}
+	if _, ok := store.seen["forever"]; !ok {
+		t.Fatal("jti without expiry was pruned")
+	}
+	seen, err = store.HasAndStore("a", now.Add(time.Minute))
+	if err != nil || seen {
+		t.Fatalf("Reuse after expiry: seen=%v err=%v", seen, err)
+	}
+}
@@ -0,0 +1,405 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// JSON Web Key (RFC 7517) parameters understood by this package
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	D   string `json:"d,omitempty"`
+	P   string `json:"p,omitempty"`
+	Q   string `json:"q,omitempty"`
+	Dp  string `json:"dp,omitempty"`
+	Dq  string `json:"dq,omitempty"`
+	Qi  string `json:"qi,omitempty"`
+	K   string `json:"k,omitempty"`
+}
+
+// Serialize the public portion of an RSA, ECDSA or Ed25519 key as a
+// JWK. Private keys are accepted, but only their public parameters are
+// written. Symmetric keys are refused; use PrivateKeyToJWK for those.
+func PublicKeyToJWK(key crypto.PublicKey) ([]byte, error) {
+	key, err := NormalizeToPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var jwk jsonWebKey
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = safeEncode(k.N.Bytes())
+		jwk.E = safeEncode(big.NewInt(int64(k.E)).Bytes())
+
+	case *ecdsa.PublicKey:
+		crv, size, err := jwkCurveName(k.Curve)
+		if err != nil {
+			return nil, err
+		}
+
+		jwk.Kty = "EC"
+		jwk.Crv = crv
+		jwk.X = safeEncode(k.X.FillBytes(make([]byte, size)))
+		jwk.Y = safeEncode(k.Y.FillBytes(make([]byte, size)))
+
+	case ed25519.PublicKey:
+		if len(k) != ed25519.PublicKeySize {
+			return nil, errors.New("Invalid Ed25519 public key")
+		}
+
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = safeEncode(k)
+
+	default:
+		return nil, fmt.Errorf("Unsupported JWK public key type %T", key)
+	}
+
+	return json.Marshal(jwk)
+}
+
+// RFC 7638 thumbprint of an RSA, ECDSA or Ed25519 public key: the
+// base64url encoded SHA-256 digest of its required JWK members,
+// serialized in lexicographic order
+func JWKThumbprint(key crypto.PublicKey) (string, error) {
+	data, err := PublicKeyToJWK(key)
+	if err != nil {
+		return "", err
+	}
+
+	var jwk jsonWebKey
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return "", fmt.Errorf("Failed to decode JWK: %v", err)
+	}
+
+	// the members are all base64url or fixed names, so need no escaping
+	var members string
+	switch jwk.Kty {
+	case "RSA":
+		members = `{"e":"` + jwk.E + `","kty":"RSA","n":"` + jwk.N + `"}`
+	case "EC":
+		members = `{"crv":"` + jwk.Crv + `","kty":"EC","x":"` + jwk.X + `","y":"` + jwk.Y + `"}`
+	case "OKP":
+		members = `{"crv":"` + jwk.Crv + `","kty":"OKP","x":"` + jwk.X + `"}`
+	default:
+		return "", fmt.Errorf("Unknown JWK key type %s", jwk.Kty)
+	}
+
+	digest := sha256.Sum256([]byte(members))
+	return safeEncode(digest[:]), nil
+}
+
+// Serialize a private key as a JWK. Supports RSA, ECDSA, Ed25519 and
+// symmetric ([]byte) keys.
+func PrivateKeyToJWK(key crypto.PrivateKey) ([]byte, error) {
+	var jwk jsonWebKey
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		jwk.Kty = "RSA"
+		jwk.N = safeEncode(k.N.Bytes())
+		jwk.E = safeEncode(big.NewInt(int64(k.E)).Bytes())
+		jwk.D = safeEncode(k.D.Bytes())
+		if len(k.Primes) == 2 {
+			k.Precompute()
+			jwk.P = safeEncode(k.Primes[0].Bytes())
+			jwk.Q = safeEncode(k.Primes[1].Bytes())
+			jwk.Dp = safeEncode(k.Precomputed.Dp.Bytes())
+			jwk.Dq = safeEncode(k.Precomputed.Dq.Bytes())
+			jwk.Qi = safeEncode(k.Precomputed.Qinv.Bytes())
+		}
+
+	case *ecdsa.PrivateKey:
+		crv, size, err := jwkCurveName(k.Curve)
+		if err != nil {
+			return nil, err
+		}
+
+		jwk.Kty = "EC"
+		jwk.Crv = crv
+		jwk.X = safeEncode(k.X.FillBytes(make([]byte, size)))
+		jwk.Y = safeEncode(k.Y.FillBytes(make([]byte, size)))
+		jwk.D = safeEncode(k.D.FillBytes(make([]byte, size)))
+
+	case ed25519.PrivateKey:
+		if len(k) != ed25519.PrivateKeySize {
+			return nil, errors.New("Invalid Ed25519 private key")
+		}
+
+		// the JWK "d" parameter is the 32 byte seed, not Go's
+		// 64 byte seed || public key form
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = safeEncode(k.Public().(ed25519.PublicKey))
+		jwk.D = safeEncode(k.Seed())
+
+	case []byte:
+		jwk.Kty = "oct"
+		jwk.K = safeEncode(k)
+
+	default:
+		return nil, fmt.Errorf("Unsupported JWK private key type %T", key)
+	}
+
+	return json.Marshal(jwk)
+}
+
+// Parse a JWK into a public key suitable for signature verification.
+// Private parameters are ignored. Symmetric ("oct") keys are returned
+// as []byte since the secret is needed to verify HMAC signatures.
+func PublicKeyFromJWK(data []byte) (crypto.PublicKey, error) {
+	var jwk jsonWebKey
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, fmt.Errorf("Failed to decode JWK: %v", err)
+	}
+
+	return jwk.publicKey()
+}
+
+// Parse a JWK containing private key parameters
+func PrivateKeyFromJWK(data []byte) (crypto.PrivateKey, error) {
+	var jwk jsonWebKey
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, fmt.Errorf("Failed to decode JWK: %v", err)
+	}
+
+	return jwk.privateKey()
+}
+
+func (jwk *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		return jwk.rsaPublicKey()
+
+	case "EC":
+		return jwk.ecdsaPublicKey()
+
+	case "OKP":
+		return jwk.ed25519PublicKey()
+
+	case "oct":
+		return jwk.symmetricKey()
+
+	default:
+		return nil, fmt.Errorf("Unknown JWK key type %s", jwk.Kty)
+	}
+}
+
+func (jwk *jsonWebKey) privateKey() (crypto.PrivateKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		pubKey, err := jwk.rsaPublicKey()
+		if err != nil {
+			return nil, err
+		}
+
+		d, err := jwkDecodeInt(jwk.D)
+		if err != nil {
+			return nil, errors.New("Malformed JWK RSA private key")
+		}
+
+		privKey := &rsa.PrivateKey{
+			PublicKey: *pubKey,
+			D:         d,
+		}
+
+		// the CRT parameters are optional, but both primes are
+		// needed to use them
+		if jwk.P != "" && jwk.Q != "" {
+			p, err := jwkDecodeInt(jwk.P)
+			if err != nil {
+				return nil, errors.New("Malformed JWK RSA private key")
+			}
+			q, err := jwkDecodeInt(jwk.Q)
+			if err != nil {
+				return nil, errors.New("Malformed JWK RSA private key")
+			}
+
+			privKey.Primes = []*big.Int{p, q}
+			if err := privKey.Validate(); err != nil {
+				return nil, fmt.Errorf("Invalid JWK RSA private key: %v", err)
+			}
+			privKey.Precompute()
+		}
+
+		return privKey, nil
+
+	case "EC":
+		pubKey, err := jwk.ecdsaPublicKey()
+		if err != nil {
+			return nil, err
+		}
+
+		d, err := jwkDecodeInt(jwk.D)
+		if err != nil {
+			return nil, errors.New("Malformed JWK EC private key")
+		}
+
+		return &ecdsa.PrivateKey{PublicKey: *pubKey, D: d}, nil
+
+	case "OKP":
+		pubKey, err := jwk.ed25519PublicKey()
+		if err != nil {
+			return nil, err
+		}
+
+		seed, err := safeDecode(jwk.D)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, errors.New("Malformed JWK Ed25519 private key")
+		}
+
+		privKey := ed25519.NewKeyFromSeed(seed)
+		if !pubKey.Equal(privKey.Public()) {
+			return nil, errors.New("JWK Ed25519 public and private keys do not match")
+		}
+
+		return privKey, nil
+
+	case "oct":
+		return jwk.symmetricKey()
+
+	default:
+		return nil, fmt.Errorf("Unknown JWK key type %s", jwk.Kty)
+	}
+}
+
+func (jwk *jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := jwkDecodeInt(jwk.N)
+	if err != nil {
+		return nil, errors.New("Malformed JWK RSA key")
+	}
+
+	e, err := jwkDecodeInt(jwk.E)
+	if err != nil || !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("Malformed JWK RSA key")
+	}
+
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (jwk *jsonWebKey) ecdsaPublicKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("Unknown JWK curve type: %s", jwk.Crv)
+	}
+
+	x, err := jwkDecodeInt(jwk.X)
+	if err != nil {
+		return nil, errors.New("Malformed JWK EC key")
+	}
+	y, err := jwkDecodeInt(jwk.Y)
+	if err != nil {
+		return nil, errors.New("Malformed JWK EC key")
+	}
+
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("JWK EC key is not on the curve")
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func (jwk *jsonWebKey) ed25519PublicKey() (ed25519.PublicKey, error) {
+	if jwk.Crv != "Ed25519" {
+		return nil, fmt.Errorf("Unknown JWK curve type: %s", jwk.Crv)
+	}
+
+	x, err := safeDecode(jwk.X)
+	if err != nil || len(x) != ed25519.PublicKeySize {
+		return nil, errors.New("Malformed JWK Ed25519 key")
+	}
+
+	return ed25519.PublicKey(x), nil
+}
+
+func (jwk *jsonWebKey) symmetricKey() ([]byte, error) {
+	if jwk.K == "" {
+		return nil, errors.New("Malformed JWK octet key")
+	}
+
+	data, err := safeDecode(jwk.K)
+	if err != nil {
+		return nil, errors.New("Malformed JWK octet key")
+	}
+
+	return data, nil
+}
+
+// decode a base64url encoded, big-endian, unsigned integer
+func jwkDecodeInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("Missing JWK parameter")
+	}
+
+	data, err := safeDecode(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
+
+// JWK curve name and coordinate size for an elliptic curve
+func jwkCurveName(curve elliptic.Curve) (string, int, error) {
+	switch curve {
+	case elliptic.P256():
+		return "P-256", 32, nil
+	case elliptic.P384():
+		return "P-384", 48, nil
+	case elliptic.P521():
+		return "P-521", 66, nil
+	}
+
+	if curve == nil {
+		return "", 0, errors.New("Invalid ECDSA key: missing curve")
+	}
+	return "", 0, fmt.Errorf("Unsupported JWK curve %s", curve.Params().Name)
+}
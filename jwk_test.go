@@ -0,0 +1,165 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+)
+
+// RFC 8037 A.1 - Ed25519 private key
+const rfc8037PrivateKey = `{"kty":"OKP","crv":"Ed25519","d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`
+
+// RFC 8037 A.4 - Ed25519 signing
+func TestVerify_EdDSA_RFC8037(t *testing.T) {
+	const jws = `eyJhbGciOiJFZERTQSJ9.RXhhbXBsZSBvZiBFZDI1NTE5IHNpZ25pbmc.hgyY0il_MGCjP0JzlnLWG1PPOt7-09PGcvMg3AIbQR6dWbhijcNR4ki4iylGjg5BhVsPt9g7sVvpAr_MuM0KAg`
+
+	pubKey, err := PublicKeyFromJWK([]byte(rfc8037PrivateKey))
+	if err != nil {
+		t.Fatal("PublicKeyFromJWK: ", err)
+	}
+
+	data, err := VerifyAndDecode(jws, ProviderFromKey(pubKey))
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if string(data) != "Example of Ed25519 signing" {
+		t.Fatalf("Unexpected payload: %v", data)
+	}
+
+	// Ed25519 is deterministic, so signing must reproduce the vector
+	privKey, err := PrivateKeyFromJWK([]byte(rfc8037PrivateKey))
+	if err != nil {
+		t.Fatal("PrivateKeyFromJWK: ", err)
+	}
+
+	signed, err := Sign(data, ALG_EdDSA, privKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if signed != jws {
+		t.Fatalf("Unexpected JWS: %s", signed)
+	}
+}
+
+func TestJWK_Ed25519_RoundTrip(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	privJWK, err := PrivateKeyToJWK(privKey)
+	if err != nil {
+		t.Fatal("PrivateKeyToJWK: ", err)
+	}
+	pubJWK, err := PublicKeyToJWK(pubKey)
+	if err != nil {
+		t.Fatal("PublicKeyToJWK: ", err)
+	}
+	if bytes.Contains(pubJWK, []byte(`"d"`)) {
+		t.Fatalf("Public JWK contains private parameters: %s", pubJWK)
+	}
+
+	decodedPriv, err := PrivateKeyFromJWK(privJWK)
+	if err != nil {
+		t.Fatal("PrivateKeyFromJWK: ", err)
+	}
+	if !privKey.Equal(decodedPriv) {
+		t.Fatal("Private key did not round trip")
+	}
+
+	decodedPub, err := PublicKeyFromJWK(pubJWK)
+	if err != nil {
+		t.Fatal("PublicKeyFromJWK: ", err)
+	}
+
+	testSignAndVerify(t, ALG_EdDSA, decodedPriv, decodedPub)
+}
+
+func TestJWK_RoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	keys := map[Algorithm]crypto.PrivateKey{
+		ALG_RS256: rsaKey,
+		ALG_ES384: ecKey,
+		ALG_HS256: []byte("symmetric secret"),
+	}
+
+	for alg, key := range keys {
+		privJWK, err := PrivateKeyToJWK(key)
+		if err != nil {
+			t.Fatalf("PrivateKeyToJWK %s: %v", alg, err)
+		}
+
+		privKey, err := PrivateKeyFromJWK(privJWK)
+		if err != nil {
+			t.Fatalf("PrivateKeyFromJWK %s: %v", alg, err)
+		}
+		pubKey, err := PublicKeyFromJWK(privJWK)
+		if err != nil {
+			t.Fatalf("PublicKeyFromJWK %s: %v", alg, err)
+		}
+
+		testSignAndVerify(t, alg, privKey, pubKey)
+	}
+}
+
+func TestJWK_Malformed(t *testing.T) {
+	for _, jwk := range []string{
+		`{"kty":"OKP","crv":"Ed25519","x":"AAAA"}`,
+		`{"kty":"OKP","crv":"X25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`,
+		`{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}`,
+		`{"kty":"RSA","n":"AQAB"}`,
+		`{"kty":"unknown"}`,
+		`not json`,
+	} {
+		if _, err := PublicKeyFromJWK([]byte(jwk)); err == nil {
+			t.Fatalf("Parsed malformed JWK: %s", jwk)
+		}
+	}
+
+	if _, err := PublicKeyToJWK([]byte("secret")); err == nil {
+		t.Fatal("Exported symmetric key as a public JWK")
+	}
+}
+
+func TestPublicKeyToJWK_InvalidEd25519(t *testing.T) {
+	if _, err := PublicKeyToJWK(ed25519.PrivateKey(make([]byte, 16))); err == nil {
+		t.Fatal("Encoded a truncated Ed25519 private key")
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// largest JWKS document that will be fetched
+const maxJWKSSize = 1 << 20
+
+// Several candidate keys for a single token. Returned by providers
+// that cannot narrow the choice down to one key; verification succeeds
+// if any of the keys validates the signature.
+type keySet []crypto.PublicKey
+
+// a JWKS entry along with its parsed key
+type jwksKey struct {
+	alg Algorithm
+	key crypto.PublicKey
+}
+
+type jwksProvider struct {
+	byKid map[string][]jwksKey
+	all   []jwksKey
+}
+
+// Build a KeyProvider from a JSON Web Key Set (RFC 7517 Section 5).
+// Keys are selected by the token's "kid" header; when several keys
+// share a kid, or the token has no kid, each candidate is tried in
+// turn. Keys marked for encryption ("use":"enc"), and keys that are
+// malformed or of an unsupported type, are ignored so that one bad
+// entry doesn't make the whole set unusable. Symmetric ("oct") keys are
+// ignored too: a key set is published, so it must never supply an HMAC
+// secret. Fails only if no usable signing key remains.
+func ProviderFromJWKS(data []byte) (KeyProvider, error) {
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("Failed to decode JWKS: %v", err)
+	}
+
+	provider := &jwksProvider{
+		byKid: make(map[string][]jwksKey),
+	}
+	for _, raw := range set.Keys {
+		var jwk jsonWebKey
+		if err := json.Unmarshal(raw, &jwk); err != nil {
+			continue
+		}
+		if jwk.Use == "enc" || jwk.Kty == "oct" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+
+		entry := jwksKey{alg: Algorithm(jwk.Alg), key: key}
+		provider.byKid[jwk.Kid] = append(provider.byKid[jwk.Kid], entry)
+		provider.all = append(provider.all, entry)
+	}
+
+	if len(provider.all) == 0 {
+		return nil, errors.New("JWKS contains no signing keys")
+	}
+
+	return provider, nil
+}
+
+func (p *jwksProvider) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	candidates := p.all
+	if h.Kid != "" {
+		var ok bool
+		candidates, ok = p.byKid[h.Kid]
+		if !ok {
+			return nil, fmt.Errorf("No JWKS key with kid %q", h.Kid)
+		}
+	}
+
+	// a key that declares its algorithm may only be used with it
+	var keys keySet
+	for _, candidate := range candidates {
+		if candidate.alg == "" || candidate.alg == h.Alg {
+			keys = append(keys, candidate.key)
+		}
+	}
+
+	switch len(keys) {
+	case 0:
+		return nil, fmt.Errorf("No JWKS key for algorithm %s", h.Alg)
+	case 1:
+		return keys[0], nil
+	}
+
+	return keys, nil
+}
+
+// download a JWKS document and build a provider from it
+func fetchJWKS(client *http.Client, jwksURL string) (KeyProvider, error) {
+	resp, err := client.Get(jwksURL)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Failed to fetch JWKS: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read JWKS: %v", err)
+	}
+	if len(data) > maxJWKSSize {
+		return nil, errors.New("JWKS document is too large")
+	}
+
+	return ProviderFromJWKS(data)
+}
@@ -0,0 +1,169 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// build a JWKS document from public keys, tagging each JWK with extra
+// parameters
+func testJWKS(t *testing.T, keys map[string]interface{}) []byte {
+	var entries []string
+	for params, key := range keys {
+		jwk, err := PublicKeyToJWK(key)
+		if err != nil {
+			t.Fatal("PublicKeyToJWK: ", err)
+		}
+
+		entries = append(entries, strings.Replace(string(jwk), "{", "{"+params+",", 1))
+	}
+
+	return []byte(fmt.Sprintf(`{"keys":[%s]}`, strings.Join(entries, ",")))
+}
+
+func TestProviderFromJWKS(t *testing.T) {
+	key1, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	key2, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	key3, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	kp, err := ProviderFromJWKS(testJWKS(t, map[string]interface{}{
+		`"kid":"shared","use":"sig"`:  &key1.PublicKey,
+		`"kid":"shared"`:              &key2.PublicKey,
+		`"kid":"other","alg":"ES384"`: &key3.PublicKey,
+	}))
+	if err != nil {
+		t.Fatal("ProviderFromJWKS: ", err)
+	}
+
+	for _, key := range []*ecdsa.PrivateKey{key1, key2} {
+		jws, err := SignWithHeader(signTestPayload, Header{Alg: ALG_ES256, Kid: "shared"}, key)
+		if err != nil {
+			t.Fatal("Sign: ", err)
+		}
+		if _, err := VerifyAndDecode(jws, kp); err != nil {
+			t.Fatal("Verify: ", err)
+		}
+
+		// no kid tries every key
+		jws, err = Sign(signTestPayload, ALG_ES256, key)
+		if err != nil {
+			t.Fatal("Sign: ", err)
+		}
+		if _, err := VerifyAndDecode(jws, kp); err != nil {
+			t.Fatal("Verify without kid: ", err)
+		}
+	}
+
+	// the key is restricted to ES384, so an ES256 token must not
+	// verify against it
+	jws, err := SignWithHeader(signTestPayload, Header{Alg: ALG_ES256, Kid: "other"}, key3)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, kp); err == nil {
+		t.Fatal("Verified with a key restricted to another algorithm")
+	}
+
+	jws, err = SignWithHeader(signTestPayload, Header{Alg: ALG_ES256, Kid: "missing"}, key1)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, kp); err == nil {
+		t.Fatal("Verified with an unknown kid")
+	}
+}
+
+func TestProviderFromJWKS_Malformed(t *testing.T) {
+	for _, jwks := range []string{
+		`not json`,
+		`{"keys":[]}`,
+		`{"keys":[{"kty":"unknown"}]}`,
+		`{"keys":[{"kty":"oct","k":"c2VjcmV0","use":"enc"}]}`,
+		`{"keys":["not a key",{"kty":"EC","crv":"P-999"}]}`,
+	} {
+		if _, err := ProviderFromJWKS([]byte(jwks)); err == nil {
+			t.Fatalf("Parsed malformed JWKS: %s", jwks)
+		}
+	}
+
+	// unusable entries are skipped when a usable key remains
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	usable := testJWKS(t, map[string]interface{}{`"kid":"e"`: &key.PublicKey})
+	jwks := strings.Replace(string(usable), `{"keys":[`, `{"keys":["not a key",{"kty":"unknown","kid":"x"},`, 1)
+	kp, err := ProviderFromJWKS([]byte(jwks))
+	if err != nil {
+		t.Fatal("ProviderFromJWKS: ", err)
+	}
+	if _, err := kp.GetJWSKey(Header{Alg: ALG_ES256, Kid: "e"}); err != nil {
+		t.Fatal("GetJWSKey: ", err)
+	}
+	if _, err := kp.GetJWSKey(Header{Alg: ALG_HS256, Kid: "x"}); err == nil {
+		t.Fatal("Found a key for a skipped entry")
+	}
+}
+
+// a published key set must never supply an HMAC secret
+func TestProviderFromJWKS_SymmetricKeys(t *testing.T) {
+	if _, err := ProviderFromJWKS([]byte(`{"keys":[{"kty":"oct","k":"c2VjcmV0","kid":"h"}]}`)); err == nil {
+		t.Fatal("Accepted a JWKS of symmetric keys")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	usable := testJWKS(t, map[string]interface{}{`"kid":"e"`: &key.PublicKey})
+	jwks := strings.Replace(string(usable), `{"keys":[`, `{"keys":[{"kty":"oct","k":"c2VjcmV0","kid":"h"},`, 1)
+	kp, err := ProviderFromJWKS([]byte(jwks))
+	if err != nil {
+		t.Fatal("ProviderFromJWKS: ", err)
+	}
+
+	forged, err := SignWithHeader(signTestPayload, Header{Alg: ALG_HS256, Kid: "h"}, []byte("secret"))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(forged, kp); err == nil {
+		t.Fatal("Verified an HMAC token with a key from the JWKS")
+	}
+}
@@ -25,8 +25,10 @@
 package gojws
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/hmac"
 	"crypto/rsa"
 	"crypto/sha256"
@@ -35,7 +37,6 @@ import (
 	"errors"
 	"fmt"
 	"hash"
-	"io"
 	"math/big"
 	"strings"
 )
@@ -43,24 +44,29 @@ import (
 type Algorithm string
 
 const (
-	ALG_NONE  = Algorithm("none")
-	ALG_HS256 = Algorithm("HS256")
-	ALG_HS384 = Algorithm("HS384")
-	ALG_HS512 = Algorithm("HS512")
-	ALG_RS256 = Algorithm("RS256")
-	ALG_RS384 = Algorithm("RS384")
-	ALG_RS512 = Algorithm("RS512")
-	ALG_ES256 = Algorithm("ES256")
-	ALG_ES384 = Algorithm("ES384")
-	ALG_ES512 = Algorithm("ES512")
-	ALG_PS256 = Algorithm("PS256")
-	ALG_PS384 = Algorithm("PS384")
-	ALG_PS512 = Algorithm("PS512")
+	ALG_NONE   = Algorithm("none")
+	ALG_HS256  = Algorithm("HS256")
+	ALG_HS384  = Algorithm("HS384")
+	ALG_HS512  = Algorithm("HS512")
+	ALG_RS256  = Algorithm("RS256")
+	ALG_RS384  = Algorithm("RS384")
+	ALG_RS512  = Algorithm("RS512")
+	ALG_ES256  = Algorithm("ES256")
+	ALG_ES384  = Algorithm("ES384")
+	ALG_ES512  = Algorithm("ES512")
+	ALG_ES256K = Algorithm("ES256K")
+	ALG_EdDSA  = Algorithm("EdDSA")
+	ALG_PS256  = Algorithm("PS256")
+	ALG_PS384  = Algorithm("PS384")
+	ALG_PS512  = Algorithm("PS512")
 )
 
 // Public key to use for "none" algorithm. This type effectively
 // works as a flag allowing no signature verification if none
-// is provided in the JWS
+// is provided in the JWS. NoneKey is also the key used to sign
+// plaintext JWS. Verifying plaintext JWS requires -tags jwsnone.
+//
+// Deprecated: when verifying, use WithNoneAlgorithmAllowed instead.
 type NoneKeyType int
 
 const NoneKey = NoneKeyType(0)
@@ -71,6 +77,31 @@ type KeyProvider interface {
 	GetJWSKey(h Header) (crypto.PublicKey, error)
 }
 
+// Optional interface for providers that select a key using the token's
+// payload, such as its issuer. The payload has NOT been verified when
+// it is passed in.
+type PayloadKeyProvider interface {
+	KeyProvider
+	GetJWSKeyForPayload(h Header, payload []byte) (crypto.PublicKey, error)
+}
+
+// Optional interface for providers that look keys up by the token's
+// kid header, such as a JWKS. Tokens without a kid fall back to
+// GetJWSKey.
+type KIDKeyProvider interface {
+	KeyProvider
+	GetKeyByKID(kid string) (crypto.PublicKey, error)
+}
+
+// Optional interface for multi-tenant providers that select a key by
+// the token's iss and sub claims. The claims are read from the payload
+// before the signature is verified, so they are NOT authenticated when
+// passed in.
+type IssuerSubjectKeyProvider interface {
+	KeyProvider
+	GetKeyByIssuerSubject(iss, sub string) (crypto.PublicKey, error)
+}
+
 // convert a single key into a provider
 func ProviderFromKey(key crypto.PublicKey) KeyProvider {
 	return singleKey{key: key}
@@ -81,65 +112,259 @@ type singleKey struct {
 }
 
 func (sk singleKey) GetJWSKey(h Header) (crypto.PublicKey, error) {
-	return sk.key, nil
+	return NormalizeToPublicKey(sk.key)
 }
 
 // JWS header
 type Header struct {
-	Alg Algorithm `json:"alg"`
-	Typ string    `json:"typ,omitempty"`
-	Cty string    `json:"cty,omitempty"`
-	Jku string    `json:"jku,omitempty"`
-	Jwk string    `json:"jwk,omitempty"`
-	X5u string    `json:"x5u,omitempty"`
-	X5t string    `json:"x5t,omitempty"`
-	X5c string    `json:"x5c,omitempty"`
-	Kid string    `json:"kid,omitempty"`
+	Alg     Algorithm       `json:"alg"`
+	Typ     string          `json:"typ,omitempty"`
+	Cty     string          `json:"cty,omitempty"`
+	Jku     string          `json:"jku,omitempty"`
+	Jwk     json.RawMessage `json:"jwk,omitempty"`
+	X5u     string          `json:"x5u,omitempty"`
+	X5t     string          `json:"x5t,omitempty"`
+	X5tS256 string          `json:"x5t#S256,omitempty"`
+	// Base64 DER certificates, leaf first (RFC 7515 Section 4.1.6).
+	// Previously declared as a string, which could not decode a real
+	// x5c array. Code that held the chain's JSON text (as returned by
+	// BuildX5CChain) should unmarshal it into this slice, or use
+	// HeaderBuilder.WithX5C.
+	X5c []string `json:"x5c,omitempty"`
+	Kid string   `json:"kid,omitempty"`
 }
 
 // Verify the authenticity of a JWS signature
-func VerifyAndDecodeWithHeader(jws string, kp KeyProvider) (header Header, payload []byte, err error) {
-	parts := strings.Split(jws, ".")
+func VerifyAndDecodeWithHeader(jws string, kp KeyProvider, opts ...VerifyOption) (header Header, payload []byte, err error) {
+	return verifyAndDecode([]byte(jws), kp, newVerifyConfig(opts))
+}
+
+// Verify the authenticity of a JWS signature, applying additional
+// verification options
+func VerifyAndDecodeWithOptions(jws string, kp KeyProvider, opts ...VerifyOption) (header Header, payload []byte, err error) {
+	return verifyAndDecode([]byte(jws), kp, newVerifyConfig(opts))
+}
+
+// Verify the authenticity of a JWS signature held in a byte slice. The
+// token is decoded and hashed in place, without first being copied into
+// a string.
+func VerifyAndDecodeBytesWithHeader(jws []byte, kp KeyProvider, opts ...VerifyOption) (header Header, payload []byte, err error) {
+	return verifyAndDecode(jws, kp, newVerifyConfig(opts))
+}
+
+// Verify a JWS whose base64url encoded header, payload and signature
+// were transported separately (e.g. in different HTTP headers)
+func VerifyFromParts(headerB64 string, payloadB64 string, signatureB64 string, kp KeyProvider, opts ...VerifyOption) (header Header, payload []byte, err error) {
+	// a '.' inside a part would shift the signing input boundaries
+	if strings.Contains(headerB64, ".") || strings.Contains(payloadB64, ".") || strings.Contains(signatureB64, ".") {
+		err = errors.New("Malformed JWS")
+		return
+	}
+
+	jws := make([]byte, 0, len(headerB64)+len(payloadB64)+len(signatureB64)+2)
+	jws = append(jws, headerB64...)
+	jws = append(jws, '.')
+	jws = append(jws, payloadB64...)
+	jws = append(jws, '.')
+	jws = append(jws, signatureB64...)
+	return verifyAndDecode(jws, kp, newVerifyConfig(opts))
+}
+
+func verifyAndDecode(jws []byte, kp KeyProvider, config *verifyConfig) (header Header, payload []byte, err error) {
+	defer func() {
+		config.reportVerification(header.Alg, err)
+	}()
+
+	parts := bytes.Split(jws, []byte("."))
 	if len(parts) != 3 {
 		err = errors.New("Malformed JWS")
 		return
 	}
 
+	// the signing input is everything before the second '.'
+	signingInput := jws[:len(parts[0])+1+len(parts[1])]
+
+	decode := safeDecodeBytes
+	if config.acceptPaddedBase64 {
+		decode = lenientDecodeBytes
+	}
+
 	// decode the JWS header
-	data, err := safeDecode(parts[0])
+	data, err := decode(parts[0])
 	if err != nil {
 		err = fmt.Errorf("Malformed JWS header: %v", err)
 		return
 	}
-	err = json.Unmarshal(data, &header)
+	if config.strictHeader {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&header)
+	} else {
+		err = json.Unmarshal(data, &header)
+	}
 	if err != nil {
 		err = fmt.Errorf("Failed to decode header: %v", err)
 		return
 	}
 
-	// acquire the public key
-	key, err := kp.GetJWSKey(header)
-	if err != nil {
-		err = fmt.Errorf("Failed to acquire public key: %v", err)
+	if !config.algorithmAllowed(header.Alg) {
+		err = fmt.Errorf("%w: %s", ErrAlgorithmDenied, header.Alg)
+		return
+	}
+
+	if header.Alg == ALG_NONE && !noneAlgorithmEnabled {
+		err = ErrAlgorithmForbidden
+		return
+	}
+
+	if config.requiredTyp != "" && normalizeTyp(header.Typ) != normalizeTyp(config.requiredTyp) {
+		err = fmt.Errorf("%w: expected %q. Got %q", ErrTypMismatch, config.requiredTyp, header.Typ)
+		return
+	}
+
+	if config.typValidator != nil {
+		err = config.typValidator(header.Typ)
+		if err != nil {
+			return
+		}
+	}
+
+	// acquire the public key, either from the embedded certificate
+	// chain, the certificate thumbprint or from the provider
+	var key crypto.PublicKey
+	if header.Alg == ALG_NONE && config.allowNone {
+		// nothing to verify against; don't ask the provider
+	} else if config.verifyX5C && len(header.X5c) > 0 {
+		key, err = verifyX5C(header.X5c, config.x5cRoots, config.x5cLeafValidator)
+		if err != nil {
+			return
+		}
+	} else if thumbprintKey, ok := lookupX5T(header, config.x5tKeyStore); ok {
+		key = thumbprintKey
+	} else if kp == nil {
+		err = errors.New("Failed to acquire public key: no key provider")
 		return
+	} else if kkp, ok := kp.(KIDKeyProvider); ok && header.Kid != "" {
+		key, err = kkp.GetKeyByKID(header.Kid)
+		if err != nil {
+			err = fmt.Errorf("Failed to acquire public key: %w", err)
+			return
+		}
+	} else if iskp, ok := kp.(IssuerSubjectKeyProvider); ok {
+		var unverified []byte
+		unverified, err = decode(parts[1])
+		if err != nil {
+			err = fmt.Errorf("Malformed JWS payload: %v", err)
+			return
+		}
+
+		var claims struct {
+			Issuer  string `json:"iss"`
+			Subject string `json:"sub"`
+		}
+		err = json.Unmarshal(unverified, &claims)
+		if err != nil {
+			err = fmt.Errorf("Failed to decode claims: %v", err)
+			return
+		}
+
+		key, err = iskp.GetKeyByIssuerSubject(claims.Issuer, claims.Subject)
+		if err != nil {
+			err = fmt.Errorf("Failed to acquire public key: %w", err)
+			return
+		}
+	} else if pkp, ok := kp.(PayloadKeyProvider); ok {
+		var unverified []byte
+		unverified, err = decode(parts[1])
+		if err != nil {
+			err = fmt.Errorf("Malformed JWS payload: %v", err)
+			return
+		}
+
+		key, err = pkp.GetJWSKeyForPayload(header, unverified)
+		if err != nil {
+			err = fmt.Errorf("Failed to acquire public key: %w", err)
+			return
+		}
+	} else {
+		key, err = kp.GetJWSKey(header)
+		if err != nil {
+			err = fmt.Errorf("Failed to acquire public key: %w", err)
+			return
+		}
 	}
 
 	// validate the signature
-	signature, err := safeDecode(parts[2])
+	signature, err := decode(parts[2])
 	if err != nil {
 		err = fmt.Errorf("Malformed JWS signature: %v", err)
 		return
 	}
 
+	// a provider may offer several candidate keys (e.g. JWKS entries
+	// sharing a kid); accept the token if any of them verifies it
+	if keys, ok := key.(keySet); ok {
+		if len(keys) == 0 {
+			err = errors.New("Failed to acquire public key: no candidate keys")
+			return
+		}
+		for _, candidate := range keys {
+			err = verifySignature(header, candidate, signingInput, signature, config)
+			if err == nil {
+				break
+			}
+		}
+	} else {
+		err = verifySignature(header, key, signingInput, signature, config)
+	}
+	if err != nil {
+		return
+	}
+
+	// decode the payload
+	payload, err = decode(parts[1])
+	if err != nil {
+		payload = nil
+		err = fmt.Errorf("Malformed JWS payload: %v", err)
+		return
+	}
+
+	if config.revocationChecker != nil {
+		err = checkRevocation(config.revocationChecker, header, payload)
+		if err != nil {
+			payload = nil
+			return
+		}
+	}
+	return
+}
+
+// check a signature over the JWS signing input
+func verifySignature(header Header, key crypto.PublicKey, signingInput []byte, signature []byte, config *verifyConfig) (err error) {
+	// providers may hand back private keys; verify with the public half
+	if header.Alg != ALG_NONE {
+		key, err = NormalizeToPublicKey(key)
+		if err != nil {
+			return
+		}
+	}
+
 	switch header.Alg {
 	case ALG_NONE:
-		// only allow plaintext if the caller explicitly passed in the
-		// "none" public key
-		if key != NoneKey {
+		// only allow plaintext if the caller explicitly opted in,
+		// either with WithNoneAlgorithmAllowed or (deprecated) by
+		// passing in the "none" public key
+		if !config.allowNone && key != NoneKey {
 			err = errors.New("Refusing to validate plaintext JWS")
 			return
 		}
 
+		// RFC 7518 Section 3.6: the signature must be empty
+		if len(signature) != 0 {
+			err = errors.New("Plaintext JWS has a non-empty signature")
+			return
+		}
+
 	case ALG_HS256, ALG_HS384, ALG_HS512:
 		symmetricKey, ok := key.([]byte)
 		if !ok {
@@ -159,11 +384,17 @@ func VerifyAndDecodeWithHeader(jws string, kp KeyProvider) (header Header, paylo
 		}
 
 		hm := hmac.New(hfunc, symmetricKey)
-		io.WriteString(hm, parts[0])
-		io.WriteString(hm, ".")
-		io.WriteString(hm, parts[1])
+		hm.Write(signingInput)
 
+		// The expected length is the hash size, which the algorithm
+		// already makes public, so rejecting on length first leaks
+		// nothing. Only the contents must be compared in constant
+		// time, which hmac.Equal does.
 		expectedSignature := hm.Sum(nil)
+		if len(signature) != len(expectedSignature) {
+			err = errors.New("Signature verification failed")
+			return
+		}
 		if !hmac.Equal(expectedSignature, signature) {
 			err = errors.New("Signature verification failed")
 			return
@@ -172,12 +403,13 @@ func VerifyAndDecodeWithHeader(jws string, kp KeyProvider) (header Header, paylo
 	case ALG_RS256, ALG_RS384, ALG_RS512:
 		pubKey, ok := key.(*rsa.PublicKey)
 		if !ok {
-			privKey, ok := key.(*rsa.PrivateKey)
-			if !ok {
-				err = fmt.Errorf("Expected RSA key. Got %T", key)
-				return
-			}
-			pubKey = &privKey.PublicKey
+			err = fmt.Errorf("Expected RSA key. Got %T", key)
+			return
+		}
+
+		err = config.checkRSAKeySize(pubKey)
+		if err != nil {
+			return
 		}
 
 		var htype crypto.Hash
@@ -196,26 +428,41 @@ func VerifyAndDecodeWithHeader(jws string, kp KeyProvider) (header Header, paylo
 		}
 
 		// generate hashed input
-		io.WriteString(hs, parts[0])
-		io.WriteString(hs, ".")
-		io.WriteString(hs, parts[1])
+		hs.Write(signingInput)
 
+		// only public values are involved, so the variable time
+		// verification in crypto/rsa does not leak secrets
 		err = rsa.VerifyPKCS1v15(pubKey, htype, hs.Sum(nil), signature)
 		if err != nil {
 			err = errors.New("Signature verification failed")
 			return
 		}
 
-	case ALG_ES256, ALG_ES384, ALG_ES512:
+	case ALG_ES256, ALG_ES384, ALG_ES512, ALG_ES256K:
 		pubKey, ok := key.(*ecdsa.PublicKey)
 		if !ok {
-			privKey, ok := key.(*ecdsa.PrivateKey)
-			if !ok {
-				err = fmt.Errorf("Expected ECDSA key. Got %T", key)
-				return
-			}
+			err = fmt.Errorf("Expected ECDSA key. Got %T", key)
+			return
+		}
 
-			pubKey = &privKey.PublicKey
+		if pubKey.Curve == nil {
+			err = errors.New("Invalid ECDSA key: missing curve")
+			return
+		}
+
+		// ES256 and ES256K share a hash and signature size, so the
+		// curve is what distinguishes them. Don't let one stand in
+		// for the other.
+		if (header.Alg == ALG_ES256K) != isSecp256k1(pubKey.Curve) {
+			err = fmt.Errorf("Key curve %s is not valid for %s", pubKey.Curve.Params().Name, header.Alg)
+			return
+		}
+
+		// ecdsa.Verify simply fails for an off-curve point, which hides
+		// a corrupt or misconfigured key behind a bad signature error
+		if pubKey.X == nil || pubKey.Y == nil || !pubKey.Curve.IsOnCurve(pubKey.X, pubKey.Y) {
+			err = fmt.Errorf("%w: ECDSA public key is not on curve %s", ErrInvalidKey, pubKey.Curve.Params().Name)
+			return
 		}
 
 		var hs hash.Hash
@@ -229,6 +476,12 @@ func VerifyAndDecodeWithHeader(jws string, kp KeyProvider) (header Header, paylo
 		} else if header.Alg == ALG_ES512 {
 			rSize, sSize = 66, 66
 			hs = sha512.New()
+		} else if header.Alg == ALG_ES256K {
+			// RFC 8812 does not require low-S signatures, so both
+			// forms are accepted here. Bitcoin and Ethereum only
+			// accept low-S; see signECDSA for the signing side.
+			rSize, sSize = 32, 32
+			hs = sha256.New()
 		} else {
 			panic("Alorithm logic error with " + header.Alg)
 		}
@@ -244,25 +497,32 @@ func VerifyAndDecodeWithHeader(jws string, kp KeyProvider) (header Header, paylo
 		s.SetBytes(signature[rSize:])
 
 		// generate hashed input
-		io.WriteString(hs, parts[0])
-		io.WriteString(hs, ".")
-		io.WriteString(hs, parts[1])
+		hs.Write(signingInput)
 
+		// verification only involves public values; timing reveals
+		// nothing about the signer's key
 		if !ecdsa.Verify(pubKey, hs.Sum(nil), r, s) {
 			err = errors.New("Signature verification failed")
 			return
 		}
 
+		// (R, N-S) is also a valid signature, so a verifier that
+		// identifies tokens by their signature must insist on one form
+		if config.requireLowS && !isLowS(pubKey.Curve, s) {
+			err = ErrSignatureMalleability
+			return
+		}
+
 	case ALG_PS256, ALG_PS384, ALG_PS512:
 		pubKey, ok := key.(*rsa.PublicKey)
 		if !ok {
-			privKey, ok := key.(*rsa.PrivateKey)
-			if !ok {
-				err = fmt.Errorf("Expected RSA key. Got %T", key)
-				return
-			}
+			err = fmt.Errorf("Expected RSA key. Got %T", key)
+			return
+		}
 
-			pubKey = &privKey.PublicKey
+		err = config.checkRSAKeySize(pubKey)
+		if err != nil {
+			return
 		}
 
 		var hs hash.Hash
@@ -281,9 +541,7 @@ func VerifyAndDecodeWithHeader(jws string, kp KeyProvider) (header Header, paylo
 		}
 
 		// generate hashed input
-		io.WriteString(hs, parts[0])
-		io.WriteString(hs, ".")
-		io.WriteString(hs, parts[1])
+		hs.Write(signingInput)
 
 		err = rsa.VerifyPSS(pubKey, htype, hs.Sum(nil), signature, nil)
 		if err != nil {
@@ -291,21 +549,39 @@ func VerifyAndDecodeWithHeader(jws string, kp KeyProvider) (header Header, paylo
 			return
 		}
 
+	case ALG_EdDSA:
+		pubKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			err = fmt.Errorf("Expected Ed25519 key. Got %T", key)
+			return
+		}
+
+		if len(pubKey) != ed25519.PublicKeySize {
+			err = errors.New("Invalid Ed25519 public key")
+			return
+		}
+
+		// Ed25519 hashes the signing input itself
+		if !ed25519.Verify(pubKey, signingInput, signature) {
+			err = errors.New("Signature verification failed")
+			return
+		}
+
 	default:
 		err = fmt.Errorf("Unknown signature algorithm: %s", header.Alg)
 		return
 	}
+	return nil
+}
 
-	// decode the payload
-	payload, err = safeDecode(parts[1])
-	if err != nil {
-		err = fmt.Errorf("Malformed JWS payload: %v", err)
-		return
-	}
+func VerifyAndDecode(jws string, kp KeyProvider, opts ...VerifyOption) (payload []byte, err error) {
+	_, payload, err = VerifyAndDecodeWithHeader(jws, kp, opts...)
 	return
 }
 
-func VerifyAndDecode(jws string, kp KeyProvider) (payload []byte, err error) {
-	_, payload, err = VerifyAndDecodeWithHeader(jws, kp)
-	return
+// Verify the authenticity of a JWS signature against a single key,
+// discarding the payload
+func VerifyWithKey(jws string, key crypto.PublicKey, opts ...VerifyOption) error {
+	_, err := VerifyAndDecode(jws, ProviderFromKey(key), opts...)
+	return err
 }
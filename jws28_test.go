@@ -252,6 +252,7 @@ func TestVerify28_ECDSA_P521_SHA512(t *testing.T) {
 
 // A.5 - Example Plaintext JWS
 func TestVerify28_NONE(t *testing.T) {
+	requireNoneAlgorithm(t)
 	const jws = `eyJhbGciOiJub25lIn0.eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ.`
 
 	data, err := VerifyAndDecode(jws, ProviderFromKey(NoneKey))
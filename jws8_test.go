@@ -166,6 +166,7 @@ func TestVerify8_ECDSA_P521_SHA512(t *testing.T) {
 
 // A.5 - Example Plaintext JWS
 func TestVerify8_Plaintext(t *testing.T) {
+	requireNoneAlgorithm(t)
 	const jws = `eyJhbGciOiJub25lIn0.eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ.`
 
 	data, err := VerifyAndDecode(jws, ProviderFromKey(NoneKey))
@@ -25,6 +25,7 @@
 package gojws
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -46,3 +47,33 @@ func TestVerify8_HMAC_SHA26_Header(t *testing.T) {
 		t.Fatal("Header decoded incorrectly")
 	}
 }
+
+// x5c is a JSON array (RFC 7515 Section 4.1.6) and jwk a JSON object
+// (Section 4.1.3); both must decode from a real header
+func TestHeader_StructuredParameters(t *testing.T) {
+	const data = `{"alg":"ES256","jwk":{"kty":"EC","crv":"P-256","x":"a","y":"b"},"x5c":["MIIB","MIIC"]}`
+
+	var header Header
+	if err := json.Unmarshal([]byte(data), &header); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+	if len(header.X5c) != 2 || header.X5c[0] != "MIIB" || header.X5c[1] != "MIIC" {
+		t.Fatalf("Unexpected x5c %v", header.X5c)
+	}
+	if string(header.Jwk) != `{"kty":"EC","crv":"P-256","x":"a","y":"b"}` {
+		t.Fatalf("Unexpected jwk %s", header.Jwk)
+	}
+
+	encoded, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal("Marshal: ", err)
+	}
+	if string(encoded) != data {
+		t.Fatalf("Header did not round trip: %s", encoded)
+	}
+
+	// the string form is not valid
+	if err := json.Unmarshal([]byte(`{"alg":"ES256","x5c":"MIIB"}`), &header); err == nil {
+		t.Fatal("Decoded x5c from a string")
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Report whether a JWS carries a JSON payload (a JWT) rather than
+// arbitrary bytes. The signature is NOT verified; use this only to pick
+// a decoding path, never to make trust decisions.
+func IsJWT(jws string) (bool, error) {
+	_, encoded, _, err := splitCompact(jws)
+	if err != nil {
+		return false, err
+	}
+
+	payload, err := decodeSegment(encoded, "payload")
+	if err != nil {
+		return false, err
+	}
+
+	return json.Valid(payload), nil
+}
+
+// Verify a JWS carrying a JWT, decode its registered claims and check
+// the exp and nbf claims against the current time
+func VerifyJWT(jws string, kp KeyProvider, opts ...VerifyOption) (header Header, payload []byte, claims StandardClaims, err error) {
+	config := newVerifyConfig(opts)
+	defer func() {
+		config.reportVerification(header.Alg, err)
+	}()
+
+	// report once, here, so the claim checks are counted too
+	signatureConfig := *config
+	signatureConfig.metrics = nil
+	header, payload, err = verifyAndDecode([]byte(jws), kp, &signatureConfig)
+	if err != nil {
+		return
+	}
+
+	claims, err = config.checkClaims(payload)
+	return
+}
+
+// Verify a bearer token, as presented to Middleware,
+// WebSocketMiddleware and grpcjws. Tokens whose payload is a JSON
+// object are JWTs, and their claims are checked exactly as by
+// VerifyJWT. Other payloads carry no claims to check, so they are only
+// accepted when none of the claim options (WithAudience, WithMaxAge,
+// WithJTIStore, WithClock, WithClockSkew) were given.
+func VerifyBearerToken(token string, kp KeyProvider, opts ...VerifyOption) (header Header, payload []byte, err error) {
+	config := newVerifyConfig(opts)
+	defer func() {
+		config.reportVerification(header.Alg, err)
+	}()
+
+	signatureConfig := *config
+	signatureConfig.metrics = nil
+	header, payload, err = verifyAndDecode([]byte(token), kp, &signatureConfig)
+	if err != nil {
+		return
+	}
+
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		if config.checksClaims() {
+			err = errors.New("Token payload is not a JWT claims set")
+		}
+		return
+	}
+
+	_, err = config.checkClaims(payload)
+	return
+}
+
+// whether any option that only applies to JWT claims was given
+func (c *verifyConfig) checksClaims() bool {
+	return len(c.audiences) > 0 || c.maxAge > 0 || c.jtiStore != nil || c.clock != nil || c.clockSkew != 0
+}
+
+// decode the registered claims of a verified payload and check them:
+// exp and nbf, the audience, the maximum age and the jti store
+func (c *verifyConfig) checkClaims(payload []byte) (StandardClaims, error) {
+	var claims StandardClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, fmt.Errorf("Failed to decode claims: %v", err)
+	}
+
+	if err := validateClaims(claims, c.clock, c.clockSkew); err != nil {
+		return claims, err
+	}
+
+	if err := c.checkAudience(claims.Audience); err != nil {
+		return claims, err
+	}
+
+	if c.maxAge > 0 && claims.IssuedAt != nil {
+		clock := c.clock
+		if clock == nil {
+			clock = SystemClock{}
+		}
+		if clock.Now().Sub(claims.IssuedAt.Time) > c.maxAge+c.clockSkew {
+			return claims, ErrTokenTooOld
+		}
+	}
+
+	if c.jtiStore != nil {
+		if claims.ID == "" {
+			return claims, errors.New("Token has no jti claim")
+		}
+
+		// the token is still accepted for clockSkew past exp, so the
+		// jti must be remembered for as long
+		var expiry time.Time
+		if claims.ExpiresAt != nil {
+			expiry = claims.ExpiresAt.Time.Add(c.clockSkew)
+		}
+
+		seen, err := c.jtiStore.HasAndStore(claims.ID, expiry)
+		if err != nil {
+			return claims, fmt.Errorf("Failed to check jti: %v", err)
+		}
+		if seen {
+			return claims, ErrTokenReplayed
+		}
+	}
+	return claims, nil
+}
+
+// Check the time based registered claims (exp and nbf) against the
+// clock's current time. A nil clock uses the system time.
+func ValidateClaims(claims StandardClaims, clock Clock) error {
+	return validateClaims(claims, clock, 0)
+}
+
+// as ValidateClaims, tolerating clocks that differ by up to skew
+func validateClaims(claims StandardClaims, clock Clock, skew time.Duration) error {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+
+	now := clock.Now()
+	if claims.ExpiresAt != nil && !now.Add(-skew).Before(claims.ExpiresAt.Time) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != nil && now.Add(skew).Before(claims.NotBefore.Time) {
+		return ErrTokenNotYetValid
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIsJWT(t *testing.T) {
+	// A.4 Example JWS using ECDSA P-521 SHA-512 has a plain text payload
+	const rawJWS = `eyJhbGciOiJFUzUxMiJ9.UGF5bG9hZA.AdwMgeerwtHoh-l192l60hp9wAHZFVJbLfD_UxMi70cwnZOYaRI1bKPWROc-mZZqwqT2SI-KGDKB34XO0aw_7XdtAG8GaSwFKdCAPZgoXD2YBJZCPEX3xKpRwcdOO8KpEHwJjyqOgzDO7iKvU8vcnwNrmxYbSW9ERBXukOXolLzeO_Jn`
+	const jwt = `eyJhbGciOiJub25lIn0.eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ.`
+
+	isJWT, err := IsJWT(jwt)
+	if err != nil {
+		t.Fatal("IsJWT: ", err)
+	}
+	if !isJWT {
+		t.Fatal("JSON payload not detected as JWT")
+	}
+
+	isJWT, err = IsJWT(rawJWS)
+	if err != nil {
+		t.Fatal("IsJWT: ", err)
+	}
+	if isJWT {
+		t.Fatal("Raw payload detected as JWT")
+	}
+
+	if _, err := IsJWT("only.two"); err == nil {
+		t.Fatal("Accepted malformed JWS")
+	}
+	if _, err := IsJWT("eyJhbGciOiJub25lIn0.!!!."); err == nil {
+		t.Fatal("Accepted malformed payload")
+	}
+}
+
+// sign a set of claims with HS256
+func signTestClaims(t *testing.T, key []byte, claims interface{}) string {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal("Marshal: ", err)
+	}
+	jws, err := Sign(payload, ALG_HS256, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	return jws
+}
+
+func TestVerifyJWT(t *testing.T) {
+	key := []byte("jwt secret")
+	kp := ProviderFromKey(key)
+	now := time.Now()
+
+	jws := signTestClaims(t, key, StandardClaims{
+		Issuer:    "joe",
+		ExpiresAt: NewNumericDate(now.Add(time.Hour)),
+		NotBefore: NewNumericDate(now.Add(-time.Hour)),
+	})
+	_, _, claims, err := VerifyJWT(jws, kp)
+	if err != nil {
+		t.Fatal("VerifyJWT: ", err)
+	}
+	if claims.Issuer != "joe" {
+		t.Fatalf("Unexpected issuer %q", claims.Issuer)
+	}
+
+	jws = signTestClaims(t, key, StandardClaims{ExpiresAt: NewNumericDate(now.Add(-time.Minute))})
+	if _, _, _, err := VerifyJWT(jws, kp); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("Expected ErrTokenExpired. Got %v", err)
+	}
+
+	jws = signTestClaims(t, key, StandardClaims{NotBefore: NewNumericDate(now.Add(time.Hour))})
+	if _, _, _, err := VerifyJWT(jws, kp); !errors.Is(err, ErrTokenNotYetValid) {
+		t.Fatalf("Expected ErrTokenNotYetValid. Got %v", err)
+	}
+
+	jws, err = Sign([]byte("not json"), ALG_HS256, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, _, _, err := VerifyJWT(jws, kp); err == nil {
+		t.Fatal("Verified a JWT without a JSON payload")
+	}
+}
+
+func TestVerifyJWT_MaxAge(t *testing.T) {
+	key := []byte("max-age secret")
+	kp := ProviderFromKey(key)
+	now := time.Unix(1300819380, 0)
+	clock := FixedClock(now)
+
+	old := signTestClaims(t, key, StandardClaims{
+		IssuedAt:  NewNumericDate(now.Add(-2 * time.Hour)),
+		ExpiresAt: NewNumericDate(now.Add(30 * 24 * time.Hour)),
+	})
+	if _, _, _, err := VerifyJWT(old, kp, WithClock(clock)); err != nil {
+		t.Fatal("VerifyJWT: ", err)
+	}
+	if _, _, _, err := VerifyJWT(old, kp, WithClock(clock), WithMaxAge(time.Hour)); !errors.Is(err, ErrTokenTooOld) {
+		t.Fatalf("Expected ErrTokenTooOld. Got %v", err)
+	}
+	if _, _, _, err := VerifyJWT(old, kp, WithClock(clock), WithMaxAge(2*time.Hour)); err != nil {
+		t.Fatal("VerifyJWT: ", err)
+	}
+	if _, _, _, err := VerifyJWT(old, kp, WithClock(clock), WithMaxAge(time.Hour), WithClockSkew(time.Hour)); err != nil {
+		t.Fatal("VerifyJWT: ", err)
+	}
+
+	// tokens without iat are not checked
+	noIAT := signTestClaims(t, key, StandardClaims{Issuer: "joe"})
+	if _, _, _, err := VerifyJWT(noIAT, kp, WithClock(clock), WithMaxAge(time.Second)); err != nil {
+		t.Fatal("VerifyJWT: ", err)
+	}
+}
+
+func TestVerifyJWT_Audience(t *testing.T) {
+	key := []byte("audience secret")
+	kp := ProviderFromKey(key)
+	token := signTestClaims(t, key, StandardClaims{
+		Audience: Audience{"https://api.example.com/v1", "web"},
+	})
+
+	tests := []struct {
+		opts []VerifyOption
+		ok   bool
+	}{
+		{nil, true},
+		{[]VerifyOption{WithAudience("web")}, true},
+		{[]VerifyOption{WithAudience("admin")}, false},
+		{[]VerifyOption{WithAudience("admin", "web")}, true},
+		{[]VerifyOption{WithAllAudiences("admin", "web")}, false},
+		{[]VerifyOption{WithAllAudiences("https://api.example.com/v1", "web")}, true},
+		{[]VerifyOption{WithAudience("https://api.example.com")}, false},
+		{[]VerifyOption{WithAudience("https://api.example.com"), WithAudienceMatchMode(AudienceMatchPrefix)}, true},
+		{[]VerifyOption{WithAllAudiences("https://api.example.com", "web"), WithAudienceMatchMode(AudienceMatchPrefix)}, true},
+	}
+	for i, tt := range tests {
+		_, _, _, err := VerifyJWT(token, kp, tt.opts...)
+		if tt.ok && err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrAudienceMismatch) {
+			t.Fatalf("Test %d: expected ErrAudienceMismatch. Got %v", i, err)
+		}
+	}
+
+	// tokens without an aud claim fail once an audience is expected
+	noAud := signTestClaims(t, key, StandardClaims{Issuer: "joe"})
+	if _, _, _, err := VerifyJWT(noAud, kp, WithAudience("web")); !errors.Is(err, ErrAudienceMismatch) {
+		t.Fatalf("Expected ErrAudienceMismatch. Got %v", err)
+	}
+}
+
+func TestVerifyJWT_JTIStore(t *testing.T) {
+	key := []byte("jwt secret")
+	kp := ProviderFromKey(key)
+	store := NewInMemoryJTIStore()
+
+	jti, err := GenerateJTI()
+	if err != nil {
+		t.Fatal("GenerateJTI: ", err)
+	}
+	jws := signTestClaims(t, key, StandardClaims{
+		ID:        jti,
+		ExpiresAt: NewNumericDate(time.Now().Add(time.Hour)),
+	})
+
+	if _, _, _, err := VerifyJWT(jws, kp, WithJTIStore(store)); err != nil {
+		t.Fatal("VerifyJWT: ", err)
+	}
+	if _, _, _, err := VerifyJWT(jws, kp, WithJTIStore(store)); !errors.Is(err, ErrTokenReplayed) {
+		t.Fatalf("Expected ErrTokenReplayed. Got %v", err)
+	}
+
+	// the store is only consulted when requested
+	if _, _, _, err := VerifyJWT(jws, kp); err != nil {
+		t.Fatal("VerifyJWT: ", err)
+	}
+
+	jws = signTestClaims(t, key, StandardClaims{Issuer: "joe"})
+	if _, _, _, err := VerifyJWT(jws, kp, WithJTIStore(store)); err == nil {
+		t.Fatal("Accepted a JWT without a jti")
+	}
+
+	// the jti is remembered for as long as the token is accepted
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	jws = signTestClaims(t, key, StandardClaims{ID: jti, ExpiresAt: NewNumericDate(exp)})
+	recorder := &expiryRecorder{}
+	if _, _, _, err := VerifyJWT(jws, kp, WithJTIStore(recorder), WithClockSkew(time.Minute)); err != nil {
+		t.Fatal("VerifyJWT: ", err)
+	}
+	if !recorder.expiry.Equal(exp.Add(time.Minute)) {
+		t.Fatalf("Expected jti expiry %v. Got %v", exp.Add(time.Minute), recorder.expiry)
+	}
+}
+
+type expiryRecorder struct {
+	expiry time.Time
+}
+
+func (r *expiryRecorder) HasAndStore(jti string, expiry time.Time) (bool, error) {
+	r.expiry = expiry
+	return false, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+)
+
+// Generate a fresh key pair suitable for alg: RSA 2048 for RS* and PS*,
+// the matching NIST curve for ES256/ES384/ES512 and Ed25519 for EdDSA
+func NewEphemeralKeyPair(alg Algorithm) (crypto.PrivateKey, crypto.PublicKey, error) {
+	var curve elliptic.Curve
+	switch alg {
+	case ALG_RS256, ALG_RS384, ALG_RS512, ALG_PS256, ALG_PS384, ALG_PS512:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to generate RSA key: %v", err)
+		}
+		return key, &key.PublicKey, nil
+
+	case ALG_ES256:
+		curve = elliptic.P256()
+	case ALG_ES384:
+		curve = elliptic.P384()
+	case ALG_ES512:
+		curve = elliptic.P521()
+
+	case ALG_EdDSA:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to generate Ed25519 key: %v", err)
+		}
+		return priv, pub, nil
+
+	case ALG_ES256K:
+		// the standard library has no secp256k1 implementation
+		return nil, nil, fmt.Errorf("Cannot generate keys for %s", alg)
+
+	case ALG_NONE, ALG_HS256, ALG_HS384, ALG_HS512:
+		return nil, nil, fmt.Errorf("Algorithm %s does not use a key pair", alg)
+
+	default:
+		return nil, nil, fmt.Errorf("Unknown signature algorithm: %s", alg)
+	}
+
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to generate ECDSA key: %v", err)
+	}
+	return key, &key.PublicKey, nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"testing"
+)
+
+func TestNewEphemeralKeyPair(t *testing.T) {
+	for _, alg := range []Algorithm{ALG_RS256, ALG_PS384, ALG_ES256, ALG_ES384, ALG_ES512, ALG_EdDSA} {
+		priv, pub, err := NewEphemeralKeyPair(alg)
+		if err != nil {
+			t.Fatalf("NewEphemeralKeyPair %s: %v", alg, err)
+		}
+		if !ValidKeyForAlgorithm(pub, alg) {
+			t.Fatalf("%s: unsuitable public key %T", alg, pub)
+		}
+		testSignAndVerify(t, alg, priv, pub)
+	}
+
+	for _, alg := range []Algorithm{ALG_NONE, ALG_HS256, ALG_ES256K, "XX999"} {
+		if _, _, err := NewEphemeralKeyPair(alg); err == nil {
+			t.Fatalf("Generated a key pair for %s", alg)
+		}
+	}
+}
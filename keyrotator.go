@@ -0,0 +1,162 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Holds a set of signing keys, one of which is primary, so a service
+// can roll over to a new key without downtime: add the new key so
+// verifiers accept it, make it primary, and remove the old key once
+// the tokens it signed have expired. Safe for concurrent use.
+type KeyRotator struct {
+	mu      sync.RWMutex
+	keys    map[string]crypto.PrivateKey
+	primary string
+}
+
+// Create a rotator whose primary key is initial. The initial key is
+// registered under the empty kid, so the tokens it signs carry no kid
+// header.
+func NewKeyRotator(initial crypto.PrivateKey) *KeyRotator {
+	return &KeyRotator{
+		keys: map[string]crypto.PrivateKey{"": initial},
+	}
+}
+
+// Register key under kid, replacing any key already registered there.
+// The key is accepted by KeyProvider straight away, but only signs
+// once it is made primary.
+func (r *KeyRotator) AddKey(kid string, key crypto.PrivateKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[kid] = key
+}
+
+// Sign with the key registered under kid from now on
+func (r *KeyRotator) SetPrimary(kid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.keys[kid]; !ok {
+		return fmt.Errorf("No key registered with kid %q", kid)
+	}
+	r.primary = kid
+	return nil
+}
+
+// Forget the key registered under kid. The primary key can't be
+// removed; make another key primary first.
+func (r *KeyRotator) RemoveKey(kid string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if kid != r.primary {
+		delete(r.keys, kid)
+	}
+}
+
+// A Signer that always uses the current primary key, with its default
+// algorithm (see defaultAlgorithm) and its kid in the header
+func (r *KeyRotator) Signer() Signer {
+	return rotatingSigner{r}
+}
+
+// A provider for every registered key. Tokens whose kid names a
+// registered key are checked against it alone; any other token is
+// tried against each key.
+func (r *KeyRotator) KeyProvider() KeyProvider {
+	return rotatingProvider{r}
+}
+
+func (r *KeyRotator) primaryKey() (string, crypto.PrivateKey) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.primary, r.keys[r.primary]
+}
+
+type rotatingSigner struct {
+	r *KeyRotator
+}
+
+func (s rotatingSigner) Sign(payload []byte) (string, error) {
+	return s.SignWithHeader(payload, Header{})
+}
+
+// Sign using h, with its kid replaced by the primary key's. The
+// algorithm defaults to the key's when h.Alg is empty.
+func (s rotatingSigner) SignWithHeader(payload []byte, h Header) (string, error) {
+	kid, key := s.r.primaryKey()
+
+	if h.Alg == "" {
+		pub, err := rotatorPublicKey(key)
+		if err != nil {
+			return "", err
+		}
+
+		h.Alg, err = defaultAlgorithm(pub)
+		if err != nil {
+			return "", err
+		}
+	}
+	h.Kid = kid
+
+	return SignWithHeader(payload, h, key)
+}
+
+type rotatingProvider struct {
+	r *KeyRotator
+}
+
+func (p rotatingProvider) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	p.r.mu.RLock()
+	defer p.r.mu.RUnlock()
+
+	if key, ok := p.r.keys[h.Kid]; ok {
+		return rotatorPublicKey(key)
+	}
+
+	var keys keySet
+	for _, key := range p.r.keys {
+		if pub, err := rotatorPublicKey(key); err == nil {
+			keys = append(keys, pub)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("No usable keys registered")
+	}
+	return keys, nil
+}
+
+func rotatorPublicKey(key crypto.PrivateKey) (crypto.PublicKey, error) {
+	if signer, ok := hardwareSigner(key); ok {
+		return signer.Public(), nil
+	}
+	return NormalizeToPublicKey(key)
+}
@@ -0,0 +1,135 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"sync"
+	"testing"
+)
+
+func TestKeyRotator(t *testing.T) {
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	r := NewKeyRotator(oldKey)
+	signer := r.Signer()
+	kp := r.KeyProvider()
+
+	oldToken, err := signer.Sign(signTestPayload)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	header, _, err := VerifyAndDecodeWithHeader(oldToken, kp)
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if header.Alg != ALG_ES256 || header.Kid != "" {
+		t.Fatalf("Unexpected header %+v", header)
+	}
+
+	if err := r.SetPrimary("k2"); err == nil {
+		t.Fatal("Made an unregistered key primary")
+	}
+
+	r.AddKey("k2", newKey)
+	if err := r.SetPrimary("k2"); err != nil {
+		t.Fatal("SetPrimary: ", err)
+	}
+
+	newToken, err := signer.SignWithHeader(signTestPayload, Header{Typ: "JWT", Kid: "ignored"})
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	header, _, err = VerifyAndDecodeWithHeader(newToken, kp)
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if header.Alg != ALG_EdDSA || header.Kid != "k2" || header.Typ != "JWT" {
+		t.Fatalf("Unexpected header %+v", header)
+	}
+
+	// both keys verify during the rollover
+	if _, err := VerifyAndDecode(oldToken, kp); err != nil {
+		t.Fatal("Verify old token: ", err)
+	}
+
+	// the primary can't be removed
+	r.RemoveKey("k2")
+	if _, err := VerifyAndDecode(newToken, kp); err != nil {
+		t.Fatal("Verify after removing primary: ", err)
+	}
+
+	r.RemoveKey("")
+	if _, err := VerifyAndDecode(oldToken, kp); err == nil {
+		t.Fatal("Verified a token signed with a removed key")
+	}
+}
+
+func TestKeyRotator_Concurrent(t *testing.T) {
+	r := NewKeyRotator([]byte("secret-0"))
+	r.AddKey("k1", []byte("secret-1"))
+	signer := r.Signer()
+	kp := r.KeyProvider()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				token, err := signer.Sign(signTestPayload)
+				if err != nil {
+					t.Error("Sign: ", err)
+					return
+				}
+				if _, err := VerifyAndDecode(token, kp); err != nil {
+					t.Error("Verify: ", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for j := 0; j < 50; j++ {
+		kid := "k1"
+		if j%2 == 0 {
+			kid = ""
+		}
+		if err := r.SetPrimary(kid); err != nil {
+			t.Fatal("SetPrimary: ", err)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,145 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	symmetricKeyTypes = []reflect.Type{reflect.TypeOf([]byte(nil))}
+	rsaKeyTypes       = []reflect.Type{reflect.TypeOf((*rsa.PublicKey)(nil)), reflect.TypeOf((*rsa.PrivateKey)(nil))}
+	ecdsaKeyTypes     = []reflect.Type{reflect.TypeOf((*ecdsa.PublicKey)(nil)), reflect.TypeOf((*ecdsa.PrivateKey)(nil))}
+	ed25519KeyTypes   = []reflect.Type{reflect.TypeOf(ed25519.PublicKey(nil)), reflect.TypeOf(ed25519.PrivateKey(nil))}
+	noneKeyTypes      = []reflect.Type{reflect.TypeOf(NoneKey)}
+)
+
+// Key types accepted when verifying a signature made with alg, or nil
+// for an unknown algorithm
+func AlgorithmKeyTypes(alg Algorithm) []reflect.Type {
+	var types []reflect.Type
+	switch alg {
+	case ALG_NONE:
+		types = noneKeyTypes
+	case ALG_HS256, ALG_HS384, ALG_HS512:
+		types = symmetricKeyTypes
+	case ALG_RS256, ALG_RS384, ALG_RS512, ALG_PS256, ALG_PS384, ALG_PS512:
+		types = rsaKeyTypes
+	case ALG_ES256, ALG_ES384, ALG_ES512, ALG_ES256K:
+		types = ecdsaKeyTypes
+	case ALG_EdDSA:
+		types = ed25519KeyTypes
+	default:
+		return nil
+	}
+
+	return append([]reflect.Type(nil), types...)
+}
+
+// Report whether key could verify a signature made with alg. ECDSA
+// keys must also be on the algorithm's curve.
+func ValidKeyForAlgorithm(key crypto.PublicKey, alg Algorithm) bool {
+	if key == nil {
+		return false
+	}
+
+	keyType := reflect.TypeOf(key)
+	found := false
+	for _, t := range AlgorithmKeyTypes(alg) {
+		if t == keyType {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		_, err := ecdsaSignatureSize(alg, k.Curve)
+		return err == nil
+	case *ecdsa.PublicKey:
+		_, err := ecdsaSignatureSize(alg, k.Curve)
+		return err == nil
+	case ed25519.PublicKey:
+		return len(k) == ed25519.PublicKeySize
+	case ed25519.PrivateKey:
+		return len(k) == ed25519.PrivateKeySize
+	}
+	return true
+}
+
+// Extract the public key from an RSA, ECDSA or Ed25519 private key.
+// Public keys, symmetric ([]byte) keys and NoneKey are returned
+// unchanged; any other type is an error.
+func NormalizeToPublicKey(key crypto.PublicKey) (crypto.PublicKey, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey, []byte, NoneKeyType:
+		return key, nil
+	case *rsa.PrivateKey:
+		return &k.PublicKey, nil
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey, nil
+	case ed25519.PrivateKey:
+		if len(k) != ed25519.PrivateKeySize {
+			return nil, errors.New("Invalid Ed25519 private key")
+		}
+		return k.Public(), nil
+	}
+
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedKeyType, key)
+}
+
+// The algorithm to sign with when the caller hasn't chosen one: ES256,
+// ES384 or ES512 by curve for ECDSA keys, RS256 for RSA, EdDSA for
+// Ed25519 and HS256 for symmetric keys
+func defaultAlgorithm(pub crypto.PublicKey) (Algorithm, error) {
+	switch k := pub.(type) {
+	case []byte:
+		return ALG_HS256, nil
+	case *rsa.PublicKey:
+		return ALG_RS256, nil
+	case ed25519.PublicKey:
+		return ALG_EdDSA, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return ALG_ES256, nil
+		case elliptic.P384():
+			return ALG_ES384, nil
+		case elliptic.P521():
+			return ALG_ES512, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no default algorithm for %T", ErrUnsupportedKeyType, pub)
+}
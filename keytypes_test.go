@@ -0,0 +1,155 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"reflect"
+	"testing"
+)
+
+func TestAlgorithmKeyTypes(t *testing.T) {
+	types := AlgorithmKeyTypes(ALG_RS256)
+	expected := []reflect.Type{reflect.TypeOf(&rsa.PublicKey{}), reflect.TypeOf(&rsa.PrivateKey{})}
+	if !reflect.DeepEqual(types, expected) {
+		t.Fatalf("Expected %v. Got %v", expected, types)
+	}
+
+	// callers can't modify the table
+	types[0] = nil
+	if AlgorithmKeyTypes(ALG_RS256)[0] == nil {
+		t.Fatal("AlgorithmKeyTypes returned the shared table")
+	}
+
+	for _, info := range AlgorithmInventory() {
+		if len(AlgorithmKeyTypes(info.Algorithm)) == 0 {
+			t.Fatalf("No key types for %s", info.Algorithm)
+		}
+	}
+	if AlgorithmKeyTypes("XX999") != nil {
+		t.Fatal("Key types for an unknown algorithm")
+	}
+}
+
+func TestValidKeyForAlgorithm(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	tests := []struct {
+		key      crypto.PublicKey
+		alg      Algorithm
+		expected bool
+	}{
+		{&rsaKey.PublicKey, ALG_RS256, true},
+		{rsaKey, ALG_PS512, true},
+		{&rsaKey.PublicKey, ALG_ES256, false},
+		{&p256.PublicKey, ALG_ES256, true},
+		{p256, ALG_ES256, true},
+		{&p256.PublicKey, ALG_ES384, false},
+		{&p256.PublicKey, ALG_ES256K, false},
+		{edPub, ALG_EdDSA, true},
+		{edPriv, ALG_EdDSA, true},
+		{ed25519.PublicKey("short"), ALG_EdDSA, false},
+		{[]byte("secret"), ALG_HS256, true},
+		{[]byte("secret"), ALG_RS256, false},
+		{NoneKey, ALG_NONE, true},
+		{nil, ALG_HS256, false},
+		{[]byte("secret"), "XX999", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidKeyForAlgorithm(tt.key, tt.alg); got != tt.expected {
+			t.Fatalf("%T with %s: expected %v. Got %v", tt.key, tt.alg, tt.expected, got)
+		}
+	}
+}
+
+func TestNormalizeToPublicKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	secret := []byte("secret")
+
+	tests := []struct {
+		key, expected crypto.PublicKey
+	}{
+		{rsaKey, &rsaKey.PublicKey},
+		{&rsaKey.PublicKey, &rsaKey.PublicKey},
+		{ecKey, &ecKey.PublicKey},
+		{&ecKey.PublicKey, &ecKey.PublicKey},
+		{edPriv, edPub},
+		{edPub, edPub},
+		{secret, secret},
+		{NoneKey, NoneKey},
+	}
+	for _, tt := range tests {
+		pub, err := NormalizeToPublicKey(tt.key)
+		if err != nil {
+			t.Fatalf("%T: %v", tt.key, err)
+		}
+		if !reflect.DeepEqual(pub, tt.expected) {
+			t.Fatalf("%T: unexpected public key %T", tt.key, pub)
+		}
+	}
+
+	for _, key := range []crypto.PublicKey{nil, "key", ed25519.PrivateKey("short")} {
+		if _, err := NormalizeToPublicKey(key); err == nil {
+			t.Fatalf("Normalized unsupported key %T", key)
+		}
+	}
+
+	// verification accepts private keys from any provider
+	jws, err := Sign(signTestPayload, ALG_ES256, ecKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, ProviderByAlgorithm(map[Algorithm]crypto.PublicKey{ALG_ES256: ecKey})); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// produce the canonical (low-S) and malleated (high-S) forms of the same
+// ES256 signature
+func lowAndHighS(t *testing.T, key *ecdsa.PrivateKey) (low, high string) {
+	jws, err := Sign(signTestPayload, ALG_ES256, key, WithLowS())
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	header, payload, signature, err := splitTestJWS(jws)
+	if err != nil {
+		t.Fatal("split: ", err)
+	}
+
+	n := key.Curve.Params().N
+	s := new(big.Int).SetBytes(signature[32:])
+	if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		t.Fatal("WithLowS produced a high-S signature")
+	}
+
+	malleated := make([]byte, len(signature))
+	copy(malleated, signature[:32])
+	new(big.Int).Sub(n, s).FillBytes(malleated[32:])
+
+	return jws, header + "." + payload + "." + safeEncode(malleated)
+}
+
+func TestVerify_RequireLowS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	kp := ProviderFromKey(&key.PublicKey)
+
+	for i := 0; i < 8; i++ {
+		low, high := lowAndHighS(t, key)
+
+		// both forms are valid ECDSA signatures
+		if _, err := VerifyAndDecode(low, kp); err != nil {
+			t.Fatal("Verify low-S: ", err)
+		}
+		if _, err := VerifyAndDecode(high, kp); err != nil {
+			t.Fatal("Verify high-S: ", err)
+		}
+
+		if _, _, err := VerifyAndDecodeWithOptions(low, kp, WithLowSRequired()); err != nil {
+			t.Fatal("Verify low-S: ", err)
+		}
+		if _, _, err := VerifyAndDecodeWithOptions(high, kp, WithLowSRequired()); !errors.Is(err, ErrSignatureMalleability) {
+			t.Fatalf("Expected ErrSignatureMalleability. Got %v", err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"errors"
+)
+
+// Receives counts of signed, verified and rejected tokens, e.g. to
+// export them as Prometheus or OpenTelemetry counters. Methods may be
+// called concurrently.
+type Metrics interface {
+	// A token using alg passed verification
+	TokenVerified(alg Algorithm)
+
+	// A token using alg (which may be empty if the header could not be
+	// decoded) failed verification. reason is a short, fixed label
+	// such as "expired" or "algorithm_denied", suitable for use as a
+	// metric dimension.
+	TokenFailed(alg Algorithm, reason string)
+
+	// A token was signed using alg
+	TokenSigned(alg Algorithm)
+}
+
+// Metrics implementation that discards everything
+type NopMetrics struct{}
+
+func (NopMetrics) TokenVerified(alg Algorithm)              {}
+func (NopMetrics) TokenFailed(alg Algorithm, reason string) {}
+func (NopMetrics) TokenSigned(alg Algorithm)                {}
+
+// Report the outcome of each verification to m. VerifyJWT reports once,
+// after its claim checks.
+func WithMetrics(m Metrics) VerifyOption {
+	return func(c *verifyConfig) {
+		c.metrics = m
+	}
+}
+
+// Report each successfully signed token to m
+func WithSigningMetrics(m Metrics) SignOption {
+	return func(c *signConfig) {
+		c.metrics = m
+	}
+}
+
+// labels for the failure reasons Metrics.TokenFailed is given
+var failureReasons = []struct {
+	err    error
+	reason string
+}{
+	{ErrAlgorithmDenied, "algorithm_denied"},
+	{ErrAlgorithmForbidden, "algorithm_forbidden"},
+	{ErrTypMismatch, "typ_mismatch"},
+	{ErrCertificateChainInvalid, "certificate_chain_invalid"},
+	{ErrSignatureMalleability, "signature_malleability"},
+	{ErrInvalidKey, "invalid_key"},
+	{ErrTokenExpired, "expired"},
+	{ErrTokenNotYetValid, "not_yet_valid"},
+	{ErrTokenTooOld, "too_old"},
+	{ErrAudienceMismatch, "audience_mismatch"},
+	{ErrTokenReplayed, "replayed"},
+	{ErrTokenRevoked, "revoked"},
+	{ErrUnknownIssuer, "unknown_issuer"},
+}
+
+// report a verification outcome, if metrics are enabled
+func (c *verifyConfig) reportVerification(alg Algorithm, err error) {
+	if c.metrics == nil {
+		return
+	}
+	if err == nil {
+		c.metrics.TokenVerified(alg)
+		return
+	}
+
+	reason := "invalid"
+	for _, fr := range failureReasons {
+		if errors.Is(err, fr.err) {
+			reason = fr.reason
+			break
+		}
+	}
+	c.metrics.TokenFailed(alg, reason)
+}
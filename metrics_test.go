@@ -0,0 +1,112 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"sync"
+	"testing"
+)
+
+// Metrics recording every call as a string
+type testMetrics struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (m *testMetrics) record(event string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, event)
+}
+
+func (m *testMetrics) TokenVerified(alg Algorithm) {
+	m.record("verified " + string(alg))
+}
+
+func (m *testMetrics) TokenFailed(alg Algorithm, reason string) {
+	m.record("failed " + string(alg) + " " + reason)
+}
+
+func (m *testMetrics) TokenSigned(alg Algorithm) {
+	m.record("signed " + string(alg))
+}
+
+func (m *testMetrics) expect(t *testing.T, events ...string) {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.events) != len(events) {
+		t.Fatalf("Expected events %q. Got %q", events, m.events)
+	}
+	for i := range events {
+		if m.events[i] != events[i] {
+			t.Fatalf("Expected events %q. Got %q", events, m.events)
+		}
+	}
+	m.events = nil
+}
+
+func TestMetrics(t *testing.T) {
+	m := new(testMetrics)
+	key := []byte("metrics-secret")
+	kp := ProviderFromKey(key)
+
+	jws, err := Sign([]byte(`{"exp":1}`), ALG_HS256, key, WithSigningMetrics(m))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	m.expect(t, "signed HS256")
+
+	if _, err := Sign(nil, ALG_HS256, "not a key", WithSigningMetrics(m)); err == nil {
+		t.Fatal("Signed with a bad key")
+	}
+	m.expect(t)
+
+	if _, err := VerifyAndDecode(jws, kp, WithMetrics(m)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	m.expect(t, "verified HS256")
+
+	VerifyAndDecode(jws, ProviderFromKey([]byte("wrong")), WithMetrics(m))
+	m.expect(t, "failed HS256 invalid")
+
+	VerifyAndDecode(jws, kp, WithMetrics(m), WithDeniedAlgorithms(ALG_HS256))
+	m.expect(t, "failed HS256 algorithm_denied")
+
+	VerifyAndDecode("garbage", kp, WithMetrics(m))
+	m.expect(t, "failed  invalid")
+
+	// VerifyJWT counts the token once, after checking its claims
+	if _, _, _, err := VerifyJWT(jws, kp, WithMetrics(m)); err == nil {
+		t.Fatal("Verified an expired JWT")
+	}
+	m.expect(t, "failed HS256 expired")
+
+	// no-op implementation satisfies the interface
+	var _ Metrics = NopMetrics{}
+	if _, err := VerifyAndDecode(jws, kp, WithMetrics(NopMetrics{})); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type contextKey int
+
+const (
+	headerContextKey contextKey = iota
+	payloadContextKey
+)
+
+// HTTP middleware requiring a valid JWS in the Authorization header,
+// using the Bearer scheme (RFC 6750). Tokens are verified with
+// VerifyBearerToken, so the exp, nbf and other claims of JWTs are
+// checked along with the signature. The verified header and payload
+// are available to the next handler through HeaderFromContext and
+// PayloadFromContext.
+func Middleware(kp KeyProvider, opts ...VerifyOption) func(http.Handler) http.Handler {
+	return tokenMiddleware(bearerToken, kp, opts)
+}
+
+// middleware verifying the token found by extract with
+// VerifyBearerToken, shared by Middleware and WebSocketMiddleware
+func tokenMiddleware(extract func(r *http.Request) (string, bool), kp KeyProvider, opts []VerifyOption) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := extract(r)
+			if !ok {
+				WriteUnauthorized(w, "", nil)
+				return
+			}
+
+			header, payload, err := VerifyBearerToken(token, kp, opts...)
+			if err != nil {
+				WriteUnauthorized(w, "", err)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), headerContextKey, header)
+			ctx = context.WithValue(ctx, payloadContextKey, payload)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Write a 401 response with an RFC 6750 Bearer challenge describing
+// why the token in err was rejected. A nil err means the request
+// carried no token, which (Section 3.1) gets a challenge without an
+// error code. Tokens refused for their algorithm are reported as
+// invalid_request; every other failure as invalid_token.
+func WriteUnauthorized(w http.ResponseWriter, realm string, err error) {
+	params := bearerParams(realm)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrAlgorithmDenied), errors.Is(err, ErrAlgorithmForbidden):
+		params = append(params, `error="invalid_request"`)
+	case errors.Is(err, ErrTokenExpired):
+		params = append(params, `error="invalid_token"`, `error_description="The token has expired"`)
+	default:
+		params = append(params, `error="invalid_token"`)
+	}
+
+	writeBearerChallenge(w, params, http.StatusUnauthorized)
+}
+
+// Write a 403 response for a valid token that does not grant access,
+// with an RFC 6750 insufficient_scope challenge. The error's text, if
+// any, is sent as the error_description.
+func WriteForbidden(w http.ResponseWriter, err error) {
+	params := []string{`error="insufficient_scope"`}
+	if err != nil {
+		params = append(params, "error_description="+quoteAuthParam(err.Error()))
+	}
+
+	writeBearerChallenge(w, params, http.StatusForbidden)
+}
+
+// realm parameter for a Bearer challenge, if any
+func bearerParams(realm string) []string {
+	if realm == "" {
+		return nil
+	}
+	return []string{"realm=" + quoteAuthParam(realm)}
+}
+
+func writeBearerChallenge(w http.ResponseWriter, params []string, status int) {
+	challenge := "Bearer"
+	if len(params) > 0 {
+		challenge += " " + strings.Join(params, ", ")
+	}
+
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(status), status)
+}
+
+// RFC 7230 quoted-string
+func quoteAuthParam(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// Header of the JWS verified by Middleware
+func HeaderFromContext(ctx context.Context) (Header, bool) {
+	header, ok := ctx.Value(headerContextKey).(Header)
+	return header, ok
+}
+
+// Payload of the JWS verified by Middleware
+func PayloadFromContext(ctx context.Context) ([]byte, bool) {
+	payload, ok := ctx.Value(payloadContextKey).([]byte)
+	return payload, ok
+}
+
+// extract the token from an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	const scheme = "bearer "
+	auth := r.Header.Get("Authorization")
+	if len(auth) <= len(scheme) || !strings.EqualFold(auth[:len(scheme)], scheme) {
+		return "", false
+	}
+
+	token := strings.TrimSpace(auth[len(scheme):])
+	return token, token != ""
+}
@@ -0,0 +1,187 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	key := []byte("middleware-secret")
+	payload := []byte(`{"iss":"joe"}`)
+	token, err := SignWithHeader(payload, Header{Alg: ALG_HS256, Kid: "k1"}, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	var called bool
+	handler := Middleware(ProviderFromKey(key))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		header, ok := HeaderFromContext(r.Context())
+		if !ok || header.Kid != "k1" {
+			t.Errorf("Unexpected header in context: %+v", header)
+		}
+		got, ok := PayloadFromContext(r.Context())
+		if !ok || !bytes.Equal(got, payload) {
+			t.Errorf("Unexpected payload in context: %q", got)
+		}
+	}))
+
+	tests := []struct {
+		auth      string
+		status    int
+		challenge string
+	}{
+		{"Bearer " + token, http.StatusOK, ""},
+		{"bearer " + token, http.StatusOK, ""},
+		{"", http.StatusUnauthorized, "Bearer"},
+		{"Basic dXNlcjpwYXNz", http.StatusUnauthorized, "Bearer"},
+		{"Bearer ", http.StatusUnauthorized, "Bearer"},
+		{"Bearer " + token + "x", http.StatusUnauthorized, `Bearer error="invalid_token"`},
+	}
+
+	for _, tt := range tests {
+		called = false
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Fatalf("%q: expected status %d. Got %d", tt.auth, tt.status, rec.Code)
+		}
+		if called != (tt.status == http.StatusOK) {
+			t.Fatalf("%q: next handler called=%v", tt.auth, called)
+		}
+		if got := rec.Header().Get("WWW-Authenticate"); got != tt.challenge {
+			t.Fatalf("%q: expected challenge %q. Got %q", tt.auth, tt.challenge, got)
+		}
+	}
+}
+
+func TestMiddleware_Claims(t *testing.T) {
+	key := []byte("middleware-secret")
+	kp := ProviderFromKey(key)
+	now := time.Unix(1300819380, 0)
+	clock := WithClock(FixedClock(now))
+
+	expired := signTestClaims(t, key, StandardClaims{Issuer: "joe", ExpiresAt: NewNumericDate(now.Add(-time.Minute))})
+	valid := signTestClaims(t, key, StandardClaims{Issuer: "joe", Audience: Audience{"api"}, ExpiresAt: NewNumericDate(now.Add(time.Minute))})
+	opaque, err := Sign([]byte("not a claims set"), ALG_HS256, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	tests := []struct {
+		token     string
+		opts      []VerifyOption
+		status    int
+		challenge string
+	}{
+		{valid, []VerifyOption{clock}, http.StatusOK, ""},
+		{valid, []VerifyOption{clock, WithAudience("api")}, http.StatusOK, ""},
+		{expired, []VerifyOption{clock}, http.StatusUnauthorized, `Bearer error="invalid_token", error_description="The token has expired"`},
+		{expired, nil, http.StatusUnauthorized, `Bearer error="invalid_token", error_description="The token has expired"`},
+		{valid, []VerifyOption{clock, WithAudience("other")}, http.StatusUnauthorized, `Bearer error="invalid_token"`},
+		{opaque, nil, http.StatusOK, ""},
+		{opaque, []VerifyOption{WithAudience("api")}, http.StatusUnauthorized, `Bearer error="invalid_token"`},
+	}
+	for i, tt := range tests {
+		handler := Middleware(kp, tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Fatalf("%d: expected status %d. Got %d", i, tt.status, rec.Code)
+		}
+		if got := rec.Header().Get("WWW-Authenticate"); got != tt.challenge {
+			t.Fatalf("%d: expected challenge %q. Got %q", i, tt.challenge, got)
+		}
+	}
+}
+
+func TestContextAccessors_Missing(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	if _, ok := HeaderFromContext(req.Context()); ok {
+		t.Fatal("Found header in empty context")
+	}
+	if _, ok := PayloadFromContext(req.Context()); ok {
+		t.Fatal("Found payload in empty context")
+	}
+}
+
+func TestWriteUnauthorized(t *testing.T) {
+	tests := []struct {
+		realm     string
+		err       error
+		challenge string
+	}{
+		{"", nil, "Bearer"},
+		{"api", nil, `Bearer realm="api"`},
+		{"", errors.New("Signature verification failed"), `Bearer error="invalid_token"`},
+		{"api", fmt.Errorf("%w: HS256", ErrAlgorithmDenied), `Bearer realm="api", error="invalid_request"`},
+		{"", ErrAlgorithmForbidden, `Bearer error="invalid_request"`},
+		{"", ErrTokenExpired, `Bearer error="invalid_token", error_description="The token has expired"`},
+		{`say "hi"`, nil, `Bearer realm="say \"hi\""`},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		WriteUnauthorized(rec, tt.realm, tt.err)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("%v: expected status 401. Got %d", tt.err, rec.Code)
+		}
+		if got := rec.Header().Get("WWW-Authenticate"); got != tt.challenge {
+			t.Fatalf("%v: expected challenge %q. Got %q", tt.err, tt.challenge, got)
+		}
+	}
+}
+
+func TestWriteForbidden(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteForbidden(rec, errors.New("requires admin"))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403. Got %d", rec.Code)
+	}
+	if got := rec.Header().Get("WWW-Authenticate"); got != `Bearer error="insufficient_scope", error_description="requires admin"` {
+		t.Fatalf("Unexpected challenge %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	WriteForbidden(rec, nil)
+	if got := rec.Header().Get("WWW-Authenticate"); got != `Bearer error="insufficient_scope"` {
+		t.Fatalf("Unexpected challenge %q", got)
+	}
+}
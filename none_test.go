@@ -0,0 +1,58 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"errors"
+	"testing"
+)
+
+// skip tests of plaintext JWS verification unless built with -tags
+// jwsnone
+func requireNoneAlgorithm(t *testing.T) {
+	t.Helper()
+	if !noneAlgorithmEnabled {
+		t.Skip("plaintext JWS verification requires -tags jwsnone")
+	}
+}
+
+func TestVerify_NoneForbidden(t *testing.T) {
+	if noneAlgorithmEnabled {
+		t.Skip("built with -tags jwsnone")
+	}
+
+	none, err := Sign([]byte("payload"), ALG_NONE, NoneKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	// neither opt-in overrides the build
+	if _, err := VerifyAndDecode(none, ProviderFromKey(NoneKey)); !errors.Is(err, ErrAlgorithmForbidden) {
+		t.Fatalf("Expected ErrAlgorithmForbidden. Got %v", err)
+	}
+	if _, err := VerifyAndDecode(none, nil, WithNoneAllowed()); !errors.Is(err, ErrAlgorithmForbidden) {
+		t.Fatalf("Expected ErrAlgorithmForbidden. Got %v", err)
+	}
+}
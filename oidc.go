@@ -0,0 +1,136 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// largest discovery document that will be fetched
+const maxDiscoverySize = 1 << 20
+
+// Optional behavior for NewOIDCProvider
+type OIDCOption func(*oidcConfig)
+
+type oidcConfig struct {
+	client         *http.Client
+	timeout        time.Duration
+	validateIssuer bool
+}
+
+// Fetch the discovery document and JWKS with client instead of the
+// default client, which has a 10 second timeout
+func WithHTTPClient(client *http.Client) OIDCOption {
+	return func(c *oidcConfig) {
+		c.client = client
+	}
+}
+
+// Limit how long fetching the discovery document may take. The default
+// is 10 seconds.
+func WithDiscoveryTimeout(d time.Duration) OIDCOption {
+	return func(c *oidcConfig) {
+		c.timeout = d
+	}
+}
+
+// Whether to require the discovery document's issuer to match
+// issuerURL exactly, as OpenID Connect Discovery Section 4.3 requires.
+// Enabled by default.
+func WithIssuerValidation(validate bool) OIDCOption {
+	return func(c *oidcConfig) {
+		c.validateIssuer = validate
+	}
+}
+
+// Create a provider for an OpenID Connect issuer by fetching its
+// discovery document (/.well-known/openid-configuration) and using the
+// JWKS named by jwks_uri, as with NewRemoteJWKSProvider. The jwks_uri
+// must be an https URL.
+func NewOIDCProvider(issuerURL string, opts ...OIDCOption) (KeyProvider, error) {
+	config := &oidcConfig{
+		client:         defaultFetchClient,
+		timeout:        10 * time.Second,
+		validateIssuer: true,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.timeout)
+	defer cancel()
+
+	discoveryURL := strings.TrimSuffix(issuerURL, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, "GET", discoveryURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid issuer URL: %v", err)
+	}
+
+	resp, err := config.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch OIDC discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Failed to fetch OIDC discovery document: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDiscoverySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read OIDC discovery document: %v", err)
+	}
+	if len(data) > maxDiscoverySize {
+		return nil, errors.New("OIDC discovery document is too large")
+	}
+
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.Unmarshal(data, &discovery); err != nil {
+		return nil, fmt.Errorf("Failed to decode OIDC discovery document: %v", err)
+	}
+
+	if config.validateIssuer && discovery.Issuer != issuerURL {
+		return nil, fmt.Errorf("OIDC discovery issuer %q does not match %q", discovery.Issuer, issuerURL)
+	}
+	if discovery.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery document has no jwks_uri")
+	}
+	if u, err := url.Parse(discovery.JWKSURI); err != nil || u.Scheme != "https" {
+		return nil, fmt.Errorf("OIDC jwks_uri %q is not an https URL", discovery.JWKSURI)
+	}
+
+	return NewRemoteJWKSProvider(discovery.JWKSURI, config.client), nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewOIDCProvider(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	jwks := testJWKS(t, map[string]interface{}{`"kid":"k1"`: &key.PublicKey})
+
+	var issuer string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"issuer":%q,"jwks_uri":%q}`, issuer, issuer+"/keys")
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(jwks)
+	})
+	mux.HandleFunc("/plain/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"issuer":%q,"jwks_uri":%q}`, issuer+"/plain", "http"+strings.TrimPrefix(issuer, "https")+"/keys")
+	})
+	mux.HandleFunc("/slow/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	issuer = server.URL
+
+	kp, err := NewOIDCProvider(issuer, WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal("NewOIDCProvider: ", err)
+	}
+	jws, err := SignWithHeader(signTestPayload, Header{Alg: ALG_ES256, Kid: "k1"}, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	// the trailing slash is tolerated when fetching, but the issuer
+	// must otherwise match exactly
+	if _, err := NewOIDCProvider(issuer+"/", WithHTTPClient(server.Client())); err == nil {
+		t.Fatal("Accepted a mismatched issuer")
+	}
+	if _, err := NewOIDCProvider(issuer+"/", WithHTTPClient(server.Client()), WithIssuerValidation(false)); err != nil {
+		t.Fatal("NewOIDCProvider without issuer validation: ", err)
+	}
+
+	_, err = NewOIDCProvider(issuer+"/slow", WithHTTPClient(server.Client()), WithIssuerValidation(false), WithDiscoveryTimeout(50*time.Millisecond))
+	if err == nil {
+		t.Fatal("Expected the discovery timeout to expire")
+	}
+
+	if _, err := NewOIDCProvider(issuer+"/plain", WithHTTPClient(server.Client())); err == nil {
+		t.Fatal("Accepted a jwks_uri that is not https")
+	}
+
+	if _, err := NewOIDCProvider(issuer+"/missing", WithHTTPClient(server.Client())); err == nil {
+		t.Fatal("Expected an error for a missing discovery document")
+	}
+}
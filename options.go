@@ -0,0 +1,431 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Additional checks applied during verification.
+//
+// Deprecated: new checks are only added as VerifyOption constructors.
+// Convert existing values with VerifyOptionsToFunctional.
+type VerifyOptions struct {
+	// Accept base64url segments that include trailing '=' padding.
+	// RFC 7515 forbids padding, but some producers emit it anyway.
+	// Padded segments were accepted by default before this option
+	// existed.
+	AcceptPaddedBase64 bool
+
+	// Called with the header's "typ" value (which may be empty) before
+	// the signature is checked. A non-nil result aborts verification
+	// and is returned to the caller unchanged.
+	TypValidator func(typ string) error
+
+	// When the header carries an x5c certificate chain, verify the
+	// chain and use the leaf certificate's key in place of the
+	// KeyProvider. Fails with ErrCertificateChainInvalid if the chain
+	// does not verify.
+	VerifyX5C bool
+
+	// Trusted roots for x5c verification. Required when VerifyX5C is
+	// set; every chain is rejected when nil.
+	X5CRoots *x509.CertPool
+
+	// Consulted before the KeyProvider for tokens that carry an
+	// x5t#S256 or x5t thumbprint but no kid. The KeyProvider is used if the store
+	// fails.
+	X5TKeyStore X5TKeyStore
+
+	// Reject tokens using any of these algorithms with
+	// ErrAlgorithmDenied, before a key is requested
+	DeniedAlgorithms []Algorithm
+
+	// Reject ECDSA signatures whose S value is in the upper half of
+	// the curve order with ErrSignatureMalleability
+	RequireLowS bool
+
+	// Accept plaintext (alg "none") JWS without consulting the
+	// KeyProvider. DeniedAlgorithms still takes precedence. Requires
+	// -tags jwsnone.
+	AllowNone bool
+}
+
+// Optional behavior for the VerifyAndDecode family and VerifyJWT
+type VerifyOption func(*verifyConfig)
+
+type verifyConfig struct {
+	acceptPaddedBase64 bool
+	typValidator       func(typ string) error
+	requiredTyp        string
+	strictHeader       bool
+	verifyX5C          bool
+	x5cRoots           *x509.CertPool
+	x5cLeafValidator   func(leaf *x509.Certificate) error
+	x5tKeyStore        X5TKeyStore
+	allowedAlgorithms  []Algorithm
+	deniedAlgorithms   []Algorithm
+	minRSAKeyBits      int
+	requireLowS        bool
+	allowNone          bool
+	clock              Clock
+	clockSkew          time.Duration
+	maxAge             time.Duration
+	audiences          []string
+	requireAllAud      bool
+	audienceMatch      AudienceMatchMode
+	jtiStore           JTIStore
+	revocationChecker  RevocationChecker
+	dpopNonce          string
+	renewalGrace       time.Duration
+	metrics            Metrics
+}
+
+func newVerifyConfig(opts []VerifyOption) *verifyConfig {
+	config := new(verifyConfig)
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// whether alg passes the allowed and denied algorithm lists
+func (c *verifyConfig) algorithmAllowed(alg Algorithm) bool {
+	for _, denied := range c.deniedAlgorithms {
+		if alg == denied {
+			return false
+		}
+	}
+
+	if len(c.allowedAlgorithms) == 0 {
+		return true
+	}
+	for _, allowed := range c.allowedAlgorithms {
+		if alg == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// enforce the minimum RSA key size, if any
+func (c *verifyConfig) checkRSAKeySize(key *rsa.PublicKey) error {
+	if c.minRSAKeyBits > 0 && key.N.BitLen() < c.minRSAKeyBits {
+		return fmt.Errorf("RSA key size %d is below the minimum of %d bits", key.N.BitLen(), c.minRSAKeyBits)
+	}
+	return nil
+}
+
+// Convert a VerifyOptions struct into the equivalent functional options
+func VerifyOptionsToFunctional(o VerifyOptions) []VerifyOption {
+	var opts []VerifyOption
+	if o.AcceptPaddedBase64 {
+		opts = append(opts, WithPaddedBase64())
+	}
+	if o.TypValidator != nil {
+		opts = append(opts, WithTypValidator(o.TypValidator))
+	}
+	if o.VerifyX5C {
+		opts = append(opts, WithX5CVerification(o.X5CRoots))
+	}
+	if o.X5TKeyStore != nil {
+		opts = append(opts, WithX5TKeyStore(o.X5TKeyStore))
+	}
+	if len(o.DeniedAlgorithms) > 0 {
+		opts = append(opts, WithDeniedAlgorithms(o.DeniedAlgorithms...))
+	}
+	if o.RequireLowS {
+		opts = append(opts, WithLowSRequired())
+	}
+	if o.AllowNone {
+		opts = append(opts, WithNoneAllowed())
+	}
+	return opts
+}
+
+// Accept base64url segments that include trailing '=' padding. RFC
+// 7515 forbids padding, but some producers emit it anyway. Padded
+// segments were accepted by default before this option existed; pass
+// it to keep that behavior.
+func WithPaddedBase64() VerifyOption {
+	return func(c *verifyConfig) {
+		c.acceptPaddedBase64 = true
+	}
+}
+
+// Check the header's "typ" value (which may be empty) before the
+// signature is checked. A non-nil result from validate aborts
+// verification and is returned to the caller unchanged.
+func WithTypValidator(validate func(typ string) error) VerifyOption {
+	return func(c *verifyConfig) {
+		c.typValidator = validate
+	}
+}
+
+// Require the header's "typ" to be typ, e.g. "at+jwt", so a token
+// issued for one purpose can't be passed off as another (RFC 8725
+// Section 3.11). The comparison ignores case and an "application/"
+// prefix. Fails with ErrTypMismatch.
+func WithRequiredTyp(typ string) VerifyOption {
+	return func(c *verifyConfig) {
+		c.requiredTyp = typ
+	}
+}
+
+// Reject headers carrying any parameter this package does not
+// recognise, rather than ignoring it. Catches misspelt parameter names
+// and enforces a minimal header.
+func WithStrictHeader() VerifyOption {
+	return func(c *verifyConfig) {
+		c.strictHeader = true
+	}
+}
+
+// When the header carries an x5c certificate chain, verify the chain
+// against roots and use the leaf certificate's key in place of the
+// KeyProvider. Fails with ErrCertificateChainInvalid if the chain does
+// not verify, or if roots is nil; the system roots are never trusted
+// implicitly.
+func WithX5CVerification(roots *x509.CertPool) VerifyOption {
+	return func(c *verifyConfig) {
+		c.verifyX5C = true
+		c.x5cRoots = roots
+	}
+}
+
+// Check the identity of the leaf certificate of a verified x5c chain,
+// e.g. with MatchX5CLeafDNSName. A non-nil result fails verification
+// with an error wrapping both it and ErrCertificateChainInvalid. Without
+// a validator any certificate issued under the trusted roots may sign
+// tokens.
+func WithX5CLeafValidator(validate func(leaf *x509.Certificate) error) VerifyOption {
+	return func(c *verifyConfig) {
+		c.x5cLeafValidator = validate
+	}
+}
+
+// For tokens that carry an x5t#S256 or x5t certificate thumbprint but
+// no kid, look up the key in store before consulting the KeyProvider.
+// x5t#S256 is preferred when both are present. The KeyProvider is used
+// if the store fails.
+func WithX5TKeyStore(store X5TKeyStore) VerifyOption {
+	return func(c *verifyConfig) {
+		c.x5tKeyStore = store
+	}
+}
+
+// Only accept tokens using one of algs. Anything else fails with
+// ErrAlgorithmDenied before a key is requested.
+func WithAllowedAlgorithms(algs ...Algorithm) VerifyOption {
+	algs = append([]Algorithm(nil), algs...)
+	return func(c *verifyConfig) {
+		c.allowedAlgorithms = algs
+	}
+}
+
+// Reject tokens using any of algs with ErrAlgorithmDenied, before a
+// key is requested. Takes precedence over the allowed algorithms.
+func WithDeniedAlgorithms(algs ...Algorithm) VerifyOption {
+	algs = append([]Algorithm(nil), algs...)
+	return func(c *verifyConfig) {
+		c.deniedAlgorithms = append(c.deniedAlgorithms, algs...)
+	}
+}
+
+// Reject RS* and PS* signatures made with an RSA key smaller than
+// bits
+func WithMinRSAKeyBits(bits int) VerifyOption {
+	return func(c *verifyConfig) {
+		c.minRSAKeyBits = bits
+	}
+}
+
+// Reject ECDSA signatures whose S value is in the upper half of the
+// curve order with ErrSignatureMalleability
+func WithLowSRequired() VerifyOption {
+	return func(c *verifyConfig) {
+		c.requireLowS = true
+	}
+}
+
+// Allow exp and nbf to be off by up to d, to absorb clock drift
+// between issuer and verifier
+func WithClockSkew(d time.Duration) VerifyOption {
+	return func(c *verifyConfig) {
+		c.clockSkew = d
+	}
+}
+
+// Reject JWTs issued more than maxAge ago, regardless of exp. Only
+// tokens carrying an iat claim are checked. Applies to VerifyJWT.
+func WithMaxAge(maxAge time.Duration) VerifyOption {
+	return func(c *verifyConfig) {
+		c.maxAge = maxAge
+	}
+}
+
+// Require the aud claim to include at least one of audiences, failing
+// with ErrAudienceMismatch. Applies to VerifyJWT.
+func WithAudience(audiences ...string) VerifyOption {
+	audiences = append([]string(nil), audiences...)
+	return func(c *verifyConfig) {
+		c.audiences = audiences
+		c.requireAllAud = false
+	}
+}
+
+// Require the aud claim to include every one of audiences, failing
+// with ErrAudienceMismatch. Applies to VerifyJWT.
+func WithAllAudiences(audiences ...string) VerifyOption {
+	audiences = append([]string(nil), audiences...)
+	return func(c *verifyConfig) {
+		c.audiences = audiences
+		c.requireAllAud = true
+	}
+}
+
+// Compare expected audiences using mode instead of exact matching
+func WithAudienceMatchMode(mode AudienceMatchMode) VerifyOption {
+	return func(c *verifyConfig) {
+		c.audienceMatch = mode
+	}
+}
+
+// Read the current time for exp and nbf checks from clock instead of
+// the system time
+func WithClock(clock Clock) VerifyOption {
+	return func(c *verifyConfig) {
+		c.clock = clock
+	}
+}
+
+// Explicitly accept plaintext JWS using the "none" algorithm, without
+// consulting the KeyProvider. These tokens carry no signature, so only
+// use this where the payload's integrity is established some other
+// way. Denied algorithms still take precedence. Has no effect unless
+// built with -tags jwsnone.
+func WithNoneAllowed() VerifyOption {
+	return func(c *verifyConfig) {
+		c.allowNone = true
+	}
+}
+
+// Equivalent to WithNoneAllowed
+func WithNoneAlgorithmAllowed() VerifyOption {
+	return WithNoneAllowed()
+}
+
+// Reject replayed JWTs by recording each jti claim in store. Tokens
+// without a jti fail verification.
+func WithJTIStore(store JTIStore) VerifyOption {
+	return func(c *verifyConfig) {
+		c.jtiStore = store
+	}
+}
+
+// After the signature is verified, ask checker whether a token with a
+// jti claim has been revoked. Revoked tokens fail with ErrTokenRevoked.
+func WithRevocationChecker(checker RevocationChecker) VerifyOption {
+	return func(c *verifyConfig) {
+		c.revocationChecker = checker
+	}
+}
+
+// Require DPoP proofs to carry nonce, a value the server previously
+// issued in a DPoP-Nonce header. Applies to VerifyDPoP.
+func WithDPoPNonce(nonce string) VerifyOption {
+	return func(c *verifyConfig) {
+		c.dpopNonce = nonce
+	}
+}
+
+// Allow Renew to re-issue tokens that expired up to grace ago. Without
+// this option only unexpired tokens can be renewed.
+func WithRenewalGracePeriod(grace time.Duration) VerifyOption {
+	return func(c *verifyConfig) {
+		c.renewalGrace = grace
+	}
+}
+
+// algorithms refused by StrictModern
+var strictModernDenied = []Algorithm{
+	ALG_NONE,
+	ALG_HS256,
+	ALG_RS256,
+	ALG_RS384,
+	ALG_RS512,
+}
+
+// Refuse algorithms unsuitable for service to service tokens:
+// plaintext, HS256 (shared secrets spread across services) and
+// RSASSA-PKCS1-v1_5 (prefer PS256 or ES256), with ErrAlgorithmDenied
+func StrictModern() VerifyOption {
+	return WithDeniedAlgorithms(strictModernDenied...)
+}
+
+// The deny-list applied by StrictModern, as a VerifyOptions struct.
+//
+// Deprecated: use StrictModern.
+func StrictModernOptions() VerifyOptions {
+	return VerifyOptions{
+		DeniedAlgorithms: append([]Algorithm(nil), strictModernDenied...),
+	}
+}
+
+// check the aud claim against the expected audiences, if any
+func (c *verifyConfig) checkAudience(aud Audience) error {
+	if len(c.audiences) == 0 {
+		return nil
+	}
+
+	for _, expected := range c.audiences {
+		matched := aud.Matches(expected, c.audienceMatch)
+		if matched && !c.requireAllAud {
+			return nil
+		}
+		if !matched && c.requireAllAud {
+			return fmt.Errorf("%w: missing %q", ErrAudienceMismatch, expected)
+		}
+	}
+
+	if c.requireAllAud {
+		return nil
+	}
+	return ErrAudienceMismatch
+}
+
+// media type names are case insensitive, and RFC 7515 Section 4.1.9
+// lets "application/" be omitted when the name has no other '/'
+func normalizeTyp(typ string) string {
+	typ = strings.ToLower(typ)
+	if rest, ok := strings.CutPrefix(typ, "application/"); ok && !strings.Contains(rest, "/") {
+		return rest
+	}
+	return typ
+}
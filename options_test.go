@@ -0,0 +1,321 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// HS256 token whose payload and signature segments carry padding
+func paddedTestJWS(key []byte) string {
+	signingInput := base64.URLEncoding.EncodeToString([]byte(`{"alg":"HS256"}`)) + "." +
+		base64.URLEncoding.EncodeToString([]byte("Example"))
+
+	hm := hmac.New(sha256.New, key)
+	io.WriteString(hm, signingInput)
+	return signingInput + "." + base64.URLEncoding.EncodeToString(hm.Sum(nil))
+}
+
+func TestVerify_PaddedBase64(t *testing.T) {
+	key := []byte("padded-secret")
+	jws := paddedTestJWS(key)
+	if !strings.Contains(jws, "=") {
+		t.Fatal("Test token is not padded: ", jws)
+	}
+
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(key)); err == nil {
+		t.Fatal("Strict verification accepted padded base64")
+	}
+
+	_, data, err := VerifyAndDecodeWithOptions(jws, ProviderFromKey(key), WithPaddedBase64())
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if string(data) != "Example" {
+		t.Fatalf("Unexpected payload: %v", data)
+	}
+}
+
+func TestVerify_TypValidator(t *testing.T) {
+	key := []byte("typ-secret")
+	jws, err := SignWithHeader([]byte("payload"), Header{Alg: ALG_HS256, Typ: "application/custom+json"}, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	errBadTyp := errors.New("bad typ")
+	var seen string
+	opts := WithTypValidator(func(typ string) error {
+		seen = typ
+		if !strings.HasSuffix(typ, "+json") {
+			return errBadTyp
+		}
+		return nil
+	})
+
+	if _, _, err := VerifyAndDecodeWithOptions(jws, ProviderFromKey(key), opts); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if seen != "application/custom+json" {
+		t.Fatalf("Validator saw typ %q", seen)
+	}
+
+	jws, err = SignWithHeader([]byte("payload"), Header{Alg: ALG_HS256, Typ: "JWT"}, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, _, err := VerifyAndDecodeWithOptions(jws, ProviderFromKey(key), opts); err != errBadTyp {
+		t.Fatalf("Expected validator error. Got %v", err)
+	}
+}
+
+func TestVerify_RequiredTyp(t *testing.T) {
+	key := []byte("typ-secret")
+	kp := ProviderFromKey(key)
+
+	tests := []struct {
+		typ      string
+		required string
+		ok       bool
+	}{
+		{"at+jwt", "at+jwt", true},
+		{"AT+JWT", "at+jwt", true},
+		{"application/at+jwt", "at+jwt", true},
+		{"at+jwt", "application/AT+JWT", true},
+		{"JWT", "at+jwt", false},
+		{"", "at+jwt", false},
+		{"application/example/at+jwt", "example/at+jwt", false},
+		{"text/at+jwt", "at+jwt", false},
+	}
+
+	for _, tt := range tests {
+		jws, err := SignWithHeader([]byte("payload"), Header{Alg: ALG_HS256, Typ: tt.typ}, key)
+		if err != nil {
+			t.Fatal("Sign: ", err)
+		}
+
+		_, err = VerifyAndDecode(jws, kp, WithRequiredTyp(tt.required))
+		if tt.ok && err != nil {
+			t.Fatalf("typ %q required %q: %v", tt.typ, tt.required, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrTypMismatch) {
+			t.Fatalf("typ %q required %q: expected ErrTypMismatch. Got %v", tt.typ, tt.required, err)
+		}
+	}
+}
+
+func TestVerify_StrictHeader(t *testing.T) {
+	key := []byte("strict-secret")
+	kp := ProviderFromKey(key)
+
+	sign := func(header string) string {
+		signingInput := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." +
+			base64.RawURLEncoding.EncodeToString([]byte("payload"))
+
+		hm := hmac.New(sha256.New, key)
+		io.WriteString(hm, signingInput)
+		return signingInput + "." + base64.RawURLEncoding.EncodeToString(hm.Sum(nil))
+	}
+
+	known := sign(`{"alg":"HS256","typ":"JWT","kid":"1"}`)
+	if _, err := VerifyAndDecode(known, kp, WithStrictHeader()); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	// "knd" is a typo of "kid"
+	unknown := sign(`{"alg":"HS256","typ":"JWT","knd":"1"}`)
+	if _, err := VerifyAndDecode(unknown, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if _, err := VerifyAndDecode(unknown, kp, WithStrictHeader()); err == nil {
+		t.Fatal("Strict verification accepted an unknown header parameter")
+	}
+}
+
+func TestVerify_DeniedAlgorithms(t *testing.T) {
+	key := []byte("denied-secret")
+	hs256, err := Sign([]byte("payload"), ALG_HS256, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	hs512, err := Sign([]byte("payload"), ALG_HS512, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	_, _, err = VerifyAndDecodeWithOptions(hs256, ProviderFromKey(key), StrictModern())
+	if !errors.Is(err, ErrAlgorithmDenied) {
+		t.Fatalf("Expected ErrAlgorithmDenied. Got %v", err)
+	}
+	if _, _, err := VerifyAndDecodeWithOptions(hs512, ProviderFromKey(key), StrictModern()); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	// plaintext is refused even with NoneKey
+	none, err := Sign([]byte("payload"), ALG_NONE, NoneKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	_, _, err = VerifyAndDecodeWithOptions(none, ProviderFromKey(NoneKey), StrictModern())
+	if !errors.Is(err, ErrAlgorithmDenied) {
+		t.Fatalf("Expected ErrAlgorithmDenied. Got %v", err)
+	}
+
+	// the deprecated struct form denies the same algorithms
+	_, _, err = VerifyAndDecodeWithOptions(hs256, ProviderFromKey(key), VerifyOptionsToFunctional(StrictModernOptions())...)
+	if !errors.Is(err, ErrAlgorithmDenied) {
+		t.Fatalf("Expected ErrAlgorithmDenied. Got %v", err)
+	}
+}
+
+func TestVerify_NoneAlgorithmAllowed(t *testing.T) {
+	none, err := Sign([]byte("payload"), ALG_NONE, NoneKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	// refused unless the caller opts in
+	if _, err := VerifyAndDecode(none, ProviderFromKey([]byte("secret"))); err == nil {
+		t.Fatal("Verified plaintext JWS without opting in")
+	}
+
+	requireNoneAlgorithm(t)
+
+	// the provider is not consulted
+	payload, err := VerifyAndDecode(none, nil, WithNoneAlgorithmAllowed())
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if string(payload) != "payload" {
+		t.Fatalf("Unexpected payload %q", payload)
+	}
+
+	// a plaintext JWS must not carry a signature
+	if _, err := VerifyAndDecode(none+"c2ln", nil, WithNoneAlgorithmAllowed()); err == nil {
+		t.Fatal("Verified plaintext JWS with a signature")
+	}
+
+	// denied algorithms still win
+	_, _, err = VerifyAndDecodeWithOptions(none, nil, StrictModern(), WithNoneAllowed())
+	if !errors.Is(err, ErrAlgorithmDenied) {
+		t.Fatalf("Expected ErrAlgorithmDenied. Got %v", err)
+	}
+}
+
+func TestVerify_AllowedAlgorithms(t *testing.T) {
+	key := []byte("allowed-secret")
+	kp := ProviderFromKey(key)
+	hs256, err := Sign([]byte("payload"), ALG_HS256, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	if _, _, err := VerifyAndDecodeWithOptions(hs256, kp, WithAllowedAlgorithms(ALG_HS256, ALG_ES256)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	_, _, err = VerifyAndDecodeWithOptions(hs256, kp, WithAllowedAlgorithms(ALG_ES256))
+	if !errors.Is(err, ErrAlgorithmDenied) {
+		t.Fatalf("Expected ErrAlgorithmDenied. Got %v", err)
+	}
+
+	// the deny-list takes precedence
+	_, _, err = VerifyAndDecodeWithOptions(hs256, kp, WithAllowedAlgorithms(ALG_HS256), WithDeniedAlgorithms(ALG_HS256))
+	if !errors.Is(err, ErrAlgorithmDenied) {
+		t.Fatalf("Expected ErrAlgorithmDenied. Got %v", err)
+	}
+}
+
+func TestVerify_MinRSAKeyBits(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	kp := ProviderFromKey(&priv.PublicKey)
+
+	for _, alg := range []Algorithm{ALG_RS256, ALG_PS256} {
+		jws, err := Sign([]byte("payload"), alg, priv)
+		if err != nil {
+			t.Fatal("Sign: ", err)
+		}
+		if _, _, err := VerifyAndDecodeWithOptions(jws, kp, WithMinRSAKeyBits(1024)); err != nil {
+			t.Fatalf("Verify %s: %v", alg, err)
+		}
+		if _, _, err := VerifyAndDecodeWithOptions(jws, kp, WithMinRSAKeyBits(2048)); err == nil {
+			t.Fatalf("Verified %s with an undersized key", alg)
+		}
+	}
+}
+
+func TestVerify_ClockSkew(t *testing.T) {
+	key := []byte("skew-secret")
+	kp := ProviderFromKey(key)
+	now := time.Now()
+
+	expired := signTestClaims(t, key, StandardClaims{ExpiresAt: NewNumericDate(now.Add(-30 * time.Second))})
+	early := signTestClaims(t, key, StandardClaims{NotBefore: NewNumericDate(now.Add(30 * time.Second))})
+
+	if _, _, _, err := VerifyJWT(expired, kp); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("Expected ErrTokenExpired. Got %v", err)
+	}
+	if _, _, _, err := VerifyJWT(expired, kp, WithClockSkew(time.Minute)); err != nil {
+		t.Fatal("VerifyJWT: ", err)
+	}
+	if _, _, _, err := VerifyJWT(early, kp, WithClockSkew(time.Minute)); err != nil {
+		t.Fatal("VerifyJWT: ", err)
+	}
+}
+
+func TestVerifyOptionsToFunctional(t *testing.T) {
+	typ := func(string) error { return nil }
+	config := newVerifyConfig(VerifyOptionsToFunctional(VerifyOptions{
+		AcceptPaddedBase64: true,
+		TypValidator:       typ,
+		VerifyX5C:          true,
+		DeniedAlgorithms:   []Algorithm{ALG_HS256},
+		RequireLowS:        true,
+		AllowNone:          true,
+	}))
+
+	if !config.acceptPaddedBase64 || config.typValidator == nil || !config.verifyX5C ||
+		!config.requireLowS || !config.allowNone {
+		t.Fatalf("Options not converted: %+v", config)
+	}
+	if config.algorithmAllowed(ALG_HS256) || !config.algorithmAllowed(ALG_HS512) {
+		t.Fatal("Denied algorithms not converted")
+	}
+
+	if opts := VerifyOptionsToFunctional(VerifyOptions{}); len(opts) != 0 {
+		t.Fatalf("Expected no options. Got %d", len(opts))
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Decode the header, payload and signature of a compact JWS WITHOUT
+// verifying the signature.
+//
+// WARNING: nothing returned by this function is authenticated. Anyone
+// can construct a token with arbitrary header and payload contents, so
+// the results must never be used for access control or any other
+// trust decision. It exists for debugging, logging and migration
+// tooling only; use VerifyAndDecodeWithHeader for everything else.
+func UnsafeParseWithoutVerification(jws string) (header Header, payload []byte, rawSignature []byte, err error) {
+	encodedHeader, encodedPayload, encodedSignature, err := splitCompact(jws)
+	if err != nil {
+		return
+	}
+
+	header, err = decodeHeader(encodedHeader)
+	if err != nil {
+		return
+	}
+
+	payload, err = decodeSegment(encodedPayload, "payload")
+	if err != nil {
+		return
+	}
+
+	rawSignature, err = decodeSegment(encodedSignature, "signature")
+	if err != nil {
+		payload = nil
+		return
+	}
+	return
+}
+
+// split a compact JWS into its encoded header, payload and signature
+func splitCompact(jws string) (header, payload, sig string, err error) {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 {
+		err = errors.New("Malformed JWS")
+		return
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// decode one base64url segment of a compact JWS, naming it in errors
+func decodeSegment(encoded, name string) ([]byte, error) {
+	data, err := safeDecode(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("Malformed JWS %s: %v", name, err)
+	}
+	return data, nil
+}
+
+// decode the encoded header segment of a compact JWS
+func decodeHeader(encoded string) (Header, error) {
+	var header Header
+	data, err := decodeSegment(encoded, "header")
+	if err != nil {
+		return header, err
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return header, fmt.Errorf("Failed to decode header: %v", err)
+	}
+	return header, nil
+}
+
+// Report whether two compact JWS carry the same payload, whatever
+// their headers and signatures. JSON payloads are compared after
+// normalizing key order and whitespace; anything else byte for byte.
+// Neither signature is verified, so this is for tests and migration
+// tooling only.
+func SamePayload(t1, t2 string) (bool, error) {
+	_, p1, _, err := UnsafeParseWithoutVerification(t1)
+	if err != nil {
+		return false, err
+	}
+	_, p2, _, err := UnsafeParseWithoutVerification(t2)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(canonicalJSON(p1), canonicalJSON(p2)), nil
+}
+
+// re-encode a JSON document with sorted keys and no insignificant
+// whitespace, leaving non-JSON data as it is
+func canonicalJSON(data []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return data
+	}
+
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return data
+	}
+	return canonical
+}
+
+// Hash the protected header of a compact JWS, for audit logs. The
+// digest covers the base64url encoded header exactly as it appears in
+// the token (and in the signing input), not the decoded JSON, so it can
+// be reproduced from the token alone. The signature is not verified.
+func HeaderHash(jws string, h crypto.Hash) ([]byte, error) {
+	if !h.Available() {
+		return nil, fmt.Errorf("Hash function %v is not available", h)
+	}
+
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 || parts[0] == "" {
+		return nil, errors.New("Malformed JWS")
+	}
+
+	hs := h.New()
+	io.WriteString(hs, parts[0])
+	return hs.Sum(nil), nil
+}
+
+// The JWS signing input of a compact JWS: its encoded header and
+// payload joined by '.', exactly as they appear in the token. Neither
+// part is decoded, and the signature is not verified.
+func SigningInput(jws string) ([]byte, error) {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 || parts[0] == "" {
+		return nil, errors.New("Malformed JWS")
+	}
+
+	return []byte(jws[:len(parts[0])+1+len(parts[1])]), nil
+}
+
+// Write a human-readable dump of a compact JWS to w, for debugging and
+// test failure messages: the header and payload as indented JSON and
+// the signature in hex. The signature is NOT verified. Payloads that
+// are not JSON are written as a quoted string.
+func PrettyPrint(jws string, w io.Writer) error {
+	encodedHeader, encodedPayload, encodedSignature, err := splitCompact(jws)
+	if err != nil {
+		return err
+	}
+
+	header, err := decodeSegment(encodedHeader, "header")
+	if err != nil {
+		return err
+	}
+	payload, err := decodeSegment(encodedPayload, "payload")
+	if err != nil {
+		return err
+	}
+	signature, err := decodeSegment(encodedSignature, "signature")
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("WARNING: signature has NOT been verified\n\nHeader:\n")
+	if err := json.Indent(&buf, header, "", "  "); err != nil {
+		return fmt.Errorf("Failed to decode header: %v", err)
+	}
+
+	buf.WriteString("\n\nPayload:\n")
+	if json.Valid(payload) {
+		json.Indent(&buf, payload, "", "  ")
+	} else {
+		fmt.Fprintf(&buf, "%q", payload)
+	}
+
+	fmt.Fprintf(&buf, "\n\nSignature:\n%x\n", signature)
+
+	_, err = buf.WriteTo(w)
+	return err
+}
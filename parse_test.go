@@ -0,0 +1,218 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestUnsafeParseWithoutVerification(t *testing.T) {
+	// A.4 Example JWS using ECDSA P-521 SHA-512
+	const jws = `eyJhbGciOiJFUzUxMiJ9.UGF5bG9hZA.AdwMgeerwtHoh-l192l60hp9wAHZFVJbLfD_UxMi70cwnZOYaRI1bKPWROc-mZZqwqT2SI-KGDKB34XO0aw_7XdtAG8GaSwFKdCAPZgoXD2YBJZCPEX3xKpRwcdOO8KpEHwJjyqOgzDO7iKvU8vcnwNrmxYbSW9ERBXukOXolLzeO_Jn`
+
+	header, payload, signature, err := UnsafeParseWithoutVerification(jws)
+	if err != nil {
+		t.Fatal("Parse: ", err)
+	}
+	if header.Alg != ALG_ES512 {
+		t.Fatalf("Header decoded incorrectly: %+v", header)
+	}
+	if !bytes.Equal(payload, []byte("Payload")) {
+		t.Fatalf("Unexpected payload: %v", payload)
+	}
+	if len(signature) != 132 {
+		t.Fatalf("Unexpected signature length %d", len(signature))
+	}
+
+	// a forged signature still parses; that is the point
+	forged := jws[:len(jws)-4] + "AAAA"
+	if _, _, _, err := UnsafeParseWithoutVerification(forged); err != nil {
+		t.Fatal("Parse: ", err)
+	}
+
+	for _, malformed := range []string{
+		"",
+		"a.b",
+		"!!!.UGF5bG9hZA.",
+		"eyJhbGciOiJFUzUxMiJ9.!!!.",
+		"eyJhbGciOiJFUzUxMiJ9.UGF5bG9hZA.!!!",
+		"bm90IGpzb24.UGF5bG9hZA.",
+	} {
+		if _, _, _, err := UnsafeParseWithoutVerification(malformed); err == nil {
+			t.Fatalf("Parsed malformed token %q", malformed)
+		}
+	}
+}
+
+func TestHeaderHash(t *testing.T) {
+	const jws = `eyJhbGciOiJFUzUxMiJ9.UGF5bG9hZA.AdwMgeerwtHoh-l192l60hp9wAHZFVJbLfD_UxMi70cwnZOYaRI1bKPWROc-mZZqwqT2SI-KGDKB34XO0aw_7XdtAG8GaSwFKdCAPZgoXD2YBJZCPEX3xKpRwcdOO8KpEHwJjyqOgzDO7iKvU8vcnwNrmxYbSW9ERBXukOXolLzeO_Jn`
+
+	digest, err := HeaderHash(jws, crypto.SHA256)
+	if err != nil {
+		t.Fatal("HeaderHash: ", err)
+	}
+	want := sha256.Sum256([]byte("eyJhbGciOiJFUzUxMiJ9"))
+	if !bytes.Equal(digest, want[:]) {
+		t.Fatalf("Unexpected digest %x", digest)
+	}
+
+	digest, err = HeaderHash(jws, crypto.SHA512)
+	if err != nil {
+		t.Fatal("HeaderHash: ", err)
+	}
+	if len(digest) != sha512.Size {
+		t.Fatalf("Unexpected digest length %d", len(digest))
+	}
+
+	for _, bad := range []string{"", "a.b", ".b.c", "a.b.c.d"} {
+		if _, err := HeaderHash(bad, crypto.SHA256); err == nil {
+			t.Fatalf("HeaderHash %q: expected an error", bad)
+		}
+	}
+	if _, err := HeaderHash(jws, crypto.Hash(0)); err == nil {
+		t.Fatal("HeaderHash succeeded without a hash function")
+	}
+}
+
+func TestSamePayload(t *testing.T) {
+	sign := func(payload string, alg Algorithm, key []byte) string {
+		jws, err := Sign([]byte(payload), alg, key)
+		if err != nil {
+			t.Fatal("Sign: ", err)
+		}
+		return jws
+	}
+
+	a := sign(`{"iss":"joe","exp":1300819380,"roles":["a","b"]}`, ALG_HS256, []byte("key-1"))
+	tests := []struct {
+		other string
+		same  bool
+	}{
+		{sign(`{"iss":"joe","exp":1300819380,"roles":["a","b"]}`, ALG_HS512, []byte("key-2")), true},
+		{sign(`{ "roles": ["a", "b"], "exp": 1300819380, "iss": "joe" }`, ALG_HS256, []byte("key-2")), true},
+		{sign(`{"iss":"joe","exp":1300819381,"roles":["a","b"]}`, ALG_HS256, []byte("key-1")), false},
+		{sign(`{"iss":"joe","exp":1300819380,"roles":["b","a"]}`, ALG_HS256, []byte("key-1")), false},
+	}
+	for i, tt := range tests {
+		same, err := SamePayload(a, tt.other)
+		if err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		if same != tt.same {
+			t.Fatalf("Test %d: expected %v", i, tt.same)
+		}
+	}
+
+	// non-JSON payloads compare exactly
+	if same, err := SamePayload(sign("Payload", ALG_HS256, []byte("k")), sign("Payload", ALG_HS384, []byte("k"))); err != nil || !same {
+		t.Fatalf("Expected identical payloads. Got %v, %v", same, err)
+	}
+	if same, err := SamePayload(sign("Payload", ALG_HS256, []byte("k")), sign("Payload ", ALG_HS256, []byte("k"))); err != nil || same {
+		t.Fatalf("Expected different payloads. Got %v, %v", same, err)
+	}
+
+	if _, err := SamePayload(a, "not a token"); err == nil {
+		t.Fatal("Compared a malformed token")
+	}
+}
+
+func TestSigningInput(t *testing.T) {
+	key := []byte("signing-input secret")
+	jws, err := Sign([]byte("Payload"), ALG_HS256, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	input, err := SigningInput(jws)
+	if err != nil {
+		t.Fatal("SigningInput: ", err)
+	}
+	if string(input) != jws[:strings.LastIndex(jws, ".")] {
+		t.Fatalf("Unexpected signing input %q", input)
+	}
+
+	// the signature is the HMAC of exactly these bytes
+	hm := hmac.New(sha256.New, key)
+	hm.Write(input)
+	if jws != string(input)+"."+Base64URLEncode(hm.Sum(nil)) {
+		t.Fatal("Signing input does not reproduce the signature")
+	}
+
+	for _, bad := range []string{"", "a.b", ".b.c", "a.b.c.d"} {
+		if _, err := SigningInput(bad); err == nil {
+			t.Fatalf("SigningInput %q: expected an error", bad)
+		}
+	}
+}
+
+func TestPrettyPrint(t *testing.T) {
+	jws, err := SignWithHeader([]byte(`{"iss":"joe","admin":true}`), Header{Alg: ALG_HS256, Kid: "k1"}, []byte("secret"))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	_, _, signature, err := UnsafeParseWithoutVerification(jws)
+	if err != nil {
+		t.Fatal("Parse: ", err)
+	}
+
+	var buf bytes.Buffer
+	if err := PrettyPrint(jws, &buf); err != nil {
+		t.Fatal("PrettyPrint: ", err)
+	}
+
+	expected := "WARNING: signature has NOT been verified\n\n" +
+		"Header:\n{\n  \"alg\": \"HS256\",\n  \"kid\": \"k1\"\n}\n\n" +
+		"Payload:\n{\n  \"iss\": \"joe\",\n  \"admin\": true\n}\n\n" +
+		"Signature:\n" + hex.EncodeToString(signature) + "\n"
+	if buf.String() != expected {
+		t.Fatalf("Unexpected output:\n%s", buf.String())
+	}
+
+	// non-JSON payloads are quoted
+	jws, err = Sign([]byte("Payload\n"), ALG_HS256, []byte("secret"))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	buf.Reset()
+	if err := PrettyPrint(jws, &buf); err != nil {
+		t.Fatal("PrettyPrint: ", err)
+	}
+	if !strings.Contains(buf.String(), "Payload:\n\"Payload\\n\"\n") {
+		t.Fatalf("Unexpected output:\n%s", buf.String())
+	}
+
+	for _, bad := range []string{"", "a.b", "!!.e30.", "e30.!!.", "e30.e30.!!", "bm90IGpzb24.e30."} {
+		if err := PrettyPrint(bad, &buf); err == nil {
+			t.Fatalf("PrettyPrint %q: expected an error", bad)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Parse a PEM encoded public key. Accepts SubjectPublicKeyInfo
+// ("PUBLIC KEY" or "EC PUBLIC KEY"), PKCS#1 ("RSA PUBLIC KEY") and
+// X.509 certificate ("CERTIFICATE") blocks. The certificate itself is
+// not verified.
+func PublicKeyFromPEM(pemData []byte) (crypto.PublicKey, error) {
+	block, err := decodePEM(pemData)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return parsePKIXPublicKey(block.Bytes)
+
+	case "RSA PUBLIC KEY":
+		return parsePKCS1PublicKey(block.Bytes)
+
+	case "EC PUBLIC KEY":
+		// not a standard block type, but some tools label an EC
+		// SubjectPublicKeyInfo this way
+		key, err := parsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := key.(*ecdsa.PublicKey); !ok {
+			return nil, fmt.Errorf("Expected ECDSA key in EC PUBLIC KEY block. Got %T", key)
+		}
+		return key, nil
+
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse certificate: %v", err)
+		}
+		return supportedPublicKey(cert.PublicKey)
+	}
+
+	return nil, fmt.Errorf("Unexpected PEM block type %q; expected PUBLIC KEY, RSA PUBLIC KEY, EC PUBLIC KEY or CERTIFICATE", block.Type)
+}
+
+// Create a provider for the public key in a PEM block, as accepted by
+// PublicKeyFromPEM
+func ProviderFromPEMPublicKey(pemData []byte) (KeyProvider, error) {
+	key, err := PublicKeyFromPEM(pemData)
+	if err != nil {
+		return nil, err
+	}
+	return ProviderFromKey(key), nil
+}
+
+// Create a provider for a DER encoded public key, either a PKIX
+// SubjectPublicKeyInfo or a PKCS#1 RSA public key
+func ProviderFromDERPublicKey(derData []byte) (KeyProvider, error) {
+	var key crypto.PublicKey
+	var err error
+	if _, perr := x509.ParsePKCS1PublicKey(derData); perr == nil {
+		key, err = parsePKCS1PublicKey(derData)
+	} else {
+		key, err = parsePKIXPublicKey(derData)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ProviderFromKey(key), nil
+}
+
+// parse a SubjectPublicKeyInfo, explaining the common mistake of
+// supplying a PKCS#1 key instead
+func parsePKIXPublicKey(der []byte) (crypto.PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		if _, perr := x509.ParsePKCS1PublicKey(der); perr == nil {
+			return nil, errors.New("Expected PKIX public key, got PKCS#1")
+		}
+		return nil, fmt.Errorf("Failed to parse public key: %v", err)
+	}
+
+	return supportedPublicKey(key)
+}
+
+func supportedPublicKey(key crypto.PublicKey) (crypto.PublicKey, error) {
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: public key %T", ErrUnsupportedKeyType, key)
+}
+
+// parse a PKCS#1 RSA public key, explaining the common mistake of
+// supplying a PKIX key instead
+func parsePKCS1PublicKey(der []byte) (crypto.PublicKey, error) {
+	key, err := x509.ParsePKCS1PublicKey(der)
+	if err != nil {
+		if _, perr := x509.ParsePKIXPublicKey(der); perr == nil {
+			return nil, errors.New("Expected PKCS#1 public key, got PKIX")
+		}
+		return nil, fmt.Errorf("Failed to parse public key: %v", err)
+	}
+
+	return key, nil
+}
+
+// Parse a PEM encoded private key. Accepts PKCS#8 ("PRIVATE KEY"),
+// SEC 1 ("EC PRIVATE KEY") and PKCS#1 ("RSA PRIVATE KEY") blocks.
+func PrivateKeyFromPEM(pemData []byte) (crypto.PrivateKey, error) {
+	block, err := decodePEM(pemData)
+	if err != nil {
+		return nil, err
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("Unexpected PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse private key: %v", err)
+	}
+
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: private key %T", ErrUnsupportedKeyType, key)
+}
+
+// Parse a PEM encoded PKCS#8 ("PRIVATE KEY") block holding an RSA,
+// ECDSA or Ed25519 key. Other key types fail with
+// ErrUnsupportedKeyType.
+func PrivateKeyFromPKCS8PEM(pemData []byte) (crypto.PrivateKey, error) {
+	block, err := decodePEM(pemData)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("Expected PKCS#8 PRIVATE KEY block, got %q", block.Type)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse PKCS#8 private key: %v", err)
+	}
+
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: private key %T", ErrUnsupportedKeyType, key)
+}
+
+// Read a PEM encoded PKCS#8 private key from a file, as with
+// PrivateKeyFromPKCS8PEM
+func PrivateKeyFromPKCS8File(path string) (crypto.PrivateKey, error) {
+	pemData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read private key: %v", err)
+	}
+
+	return PrivateKeyFromPKCS8PEM(pemData)
+}
+
+// Parse a PEM encoded PKCS#8 private key and return its public key,
+// for verifiers that are handed the signing key's file
+func PublicKeyFromPKCS8PEM(pemData []byte) (crypto.PublicKey, error) {
+	key, err := PrivateKeyFromPKCS8PEM(pemData)
+	if err != nil {
+		return nil, err
+	}
+
+	return NormalizeToPublicKey(key)
+}
+
+// Encode the public portion of a key as a PEM SubjectPublicKeyInfo
+// block. Private keys are accepted and their public key is written.
+func PublicKeyToPEM(key crypto.PublicKey) ([]byte, error) {
+	key, err := NormalizeToPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode public key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// decode the first PEM block in data
+func decodePEM(pemData []byte) (*pem.Block, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("No PEM data found")
+	}
+
+	return block, nil
+}
@@ -0,0 +1,283 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPEM_RoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	keys := map[Algorithm]crypto.PrivateKey{
+		ALG_PS256: rsaKey,
+		ALG_ES256: ecKey,
+		ALG_EdDSA: edKey,
+	}
+
+	for alg, key := range keys {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("MarshalPKCS8PrivateKey %s: %v", alg, err)
+		}
+
+		privKey, err := PrivateKeyFromPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+		if err != nil {
+			t.Fatalf("PrivateKeyFromPEM %s: %v", alg, err)
+		}
+
+		pubPEM, err := PublicKeyToPEM(privKey)
+		if err != nil {
+			t.Fatalf("PublicKeyToPEM %s: %v", alg, err)
+		}
+
+		pubKey, err := PublicKeyFromPEM(pubPEM)
+		if err != nil {
+			t.Fatalf("PublicKeyFromPEM %s: %v", alg, err)
+		}
+
+		testSignAndVerify(t, alg, privKey, pubKey)
+	}
+}
+
+func TestPEM_LegacyPrivateKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal("MarshalECPrivateKey: ", err)
+	}
+
+	if _, err := PrivateKeyFromPEM(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})); err != nil {
+		t.Fatal("PrivateKeyFromPEM PKCS#1: ", err)
+	}
+	if _, err := PrivateKeyFromPEM(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER})); err != nil {
+		t.Fatal("PrivateKeyFromPEM SEC 1: ", err)
+	}
+}
+
+func TestPEM_Malformed(t *testing.T) {
+	if _, err := PublicKeyFromPEM([]byte("not pem")); err == nil {
+		t.Fatal("Parsed non-PEM data")
+	}
+	if _, err := PublicKeyFromPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{0}})); err == nil {
+		t.Fatal("Parsed private key block as a public key")
+	}
+	if _, err := PrivateKeyFromPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{0}})); err == nil {
+		t.Fatal("Parsed garbage private key")
+	}
+}
+
+func TestPublicKeyFromPEM_BlockTypes(t *testing.T) {
+	cert, certKey := testCertificate(t, "signer", nil, nil)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	ecDER, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	if err != nil {
+		t.Fatal("MarshalPKIXPublicKey: ", err)
+	}
+	rsaDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal("MarshalPKIXPublicKey: ", err)
+	}
+
+	tests := []struct {
+		block *pem.Block
+		want  crypto.PublicKey
+	}{
+		{&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}, &certKey.PublicKey},
+		{&pem.Block{Type: "EC PUBLIC KEY", Bytes: ecDER}, &ecKey.PublicKey},
+		{&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)}, &rsaKey.PublicKey},
+	}
+	for _, tt := range tests {
+		key, err := PublicKeyFromPEM(pem.EncodeToMemory(tt.block))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.block.Type, err)
+		}
+		if !tt.want.(interface{ Equal(crypto.PublicKey) bool }).Equal(key) {
+			t.Fatalf("%s: unexpected key", tt.block.Type)
+		}
+	}
+
+	// EC PUBLIC KEY must hold an EC key
+	if _, err := PublicKeyFromPEM(pem.EncodeToMemory(&pem.Block{Type: "EC PUBLIC KEY", Bytes: rsaDER})); err == nil {
+		t.Fatal("Parsed an RSA key from an EC PUBLIC KEY block")
+	}
+	if _, err := PublicKeyFromPEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{0}})); err == nil {
+		t.Fatal("Parsed a garbage certificate")
+	}
+
+	_, err = PublicKeyFromPEM(pem.EncodeToMemory(&pem.Block{Type: "DSA PUBLIC KEY", Bytes: []byte{0}}))
+	if err == nil || !strings.Contains(err.Error(), `"DSA PUBLIC KEY"`) {
+		t.Fatalf("Expected the block type in the error. Got %v", err)
+	}
+}
+
+func TestProviderFromPublicKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	jws, err := Sign(signTestPayload, ALG_RS256, rsaKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	pkix, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal("MarshalPKIXPublicKey: ", err)
+	}
+	pkcs1 := x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)
+
+	var providers []KeyProvider
+	for _, der := range [][]byte{pkix, pkcs1} {
+		kp, err := ProviderFromDERPublicKey(der)
+		if err != nil {
+			t.Fatal("ProviderFromDERPublicKey: ", err)
+		}
+		providers = append(providers, kp)
+	}
+	for _, block := range []*pem.Block{{Type: "PUBLIC KEY", Bytes: pkix}, {Type: "RSA PUBLIC KEY", Bytes: pkcs1}} {
+		kp, err := ProviderFromPEMPublicKey(pem.EncodeToMemory(block))
+		if err != nil {
+			t.Fatalf("ProviderFromPEMPublicKey %s: %v", block.Type, err)
+		}
+		providers = append(providers, kp)
+	}
+
+	for i, kp := range providers {
+		if _, err := VerifyAndDecode(jws, kp); err != nil {
+			t.Fatalf("Provider %d: %v", i, err)
+		}
+	}
+
+	// mislabelled blocks get a helpful error
+	_, err = ProviderFromPEMPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkcs1}))
+	if err == nil || !strings.Contains(err.Error(), "Expected PKIX public key, got PKCS#1") {
+		t.Fatal("Unexpected error for PKCS#1 key in PUBLIC KEY block: ", err)
+	}
+	_, err = ProviderFromPEMPublicKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: pkix}))
+	if err == nil || !strings.Contains(err.Error(), "Expected PKCS#1 public key, got PKIX") {
+		t.Fatal("Unexpected error for PKIX key in RSA PUBLIC KEY block: ", err)
+	}
+
+	if _, err := ProviderFromDERPublicKey([]byte{0x30, 0x00}); err == nil {
+		t.Fatal("Parsed garbage DER")
+	}
+}
+
+// keys generated with `openssl genpkey`
+func TestPKCS8_OpenSSL(t *testing.T) {
+	keys := map[string]Algorithm{
+		"rsa":     ALG_PS256,
+		"ec":      ALG_ES384,
+		"ed25519": ALG_EdDSA,
+	}
+
+	for name, alg := range keys {
+		path := filepath.Join("testdata", "pkcs8", name+".pem")
+		privKey, err := PrivateKeyFromPKCS8File(path)
+		if err != nil {
+			t.Fatalf("PrivateKeyFromPKCS8File %s: %v", name, err)
+		}
+
+		pemData, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal("ReadFile: ", err)
+		}
+		pubKey, err := PublicKeyFromPKCS8PEM(pemData)
+		if err != nil {
+			t.Fatalf("PublicKeyFromPKCS8PEM %s: %v", name, err)
+		}
+
+		// matches the key extracted by `openssl pkey -pubout`
+		pubData, err := os.ReadFile(filepath.Join("testdata", "pkcs8", name+".pub.pem"))
+		if err != nil {
+			t.Fatal("ReadFile: ", err)
+		}
+		opensslPub, err := PublicKeyFromPEM(pubData)
+		if err != nil {
+			t.Fatalf("PublicKeyFromPEM %s: %v", name, err)
+		}
+		if !opensslPub.(interface{ Equal(crypto.PublicKey) bool }).Equal(pubKey) {
+			t.Fatalf("%s: public key does not match openssl", name)
+		}
+
+		testSignAndVerify(t, alg, privKey, opensslPub)
+	}
+
+	_, err := PrivateKeyFromPKCS8File(filepath.Join("testdata", "pkcs8", "x25519.pem"))
+	if !errors.Is(err, ErrUnsupportedKeyType) {
+		t.Fatalf("Expected ErrUnsupportedKeyType for X25519. Got %v", err)
+	}
+	if _, err := PrivateKeyFromPKCS8File(filepath.Join("testdata", "pkcs8", "missing.pem")); err == nil {
+		t.Fatal("Read a missing file")
+	}
+	if _, err := PrivateKeyFromPKCS8File(filepath.Join("testdata", "pkcs8", "rsa.pub.pem")); err == nil {
+		t.Fatal("Parsed a public key as PKCS#8")
+	}
+}
+
+func TestPublicKeyToPEM_InvalidEd25519(t *testing.T) {
+	if _, err := PublicKeyToPEM(ed25519.PrivateKey(make([]byte, 16))); err == nil {
+		t.Fatal("Encoded a truncated Ed25519 private key")
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// typ header of a signed PKCE code challenge
+const CodeChallengeType = "pkce+jwt"
+
+type codeChallengeClaims struct {
+	Challenge       string `json:"challenge"`
+	ChallengeMethod string `json:"challenge_method"`
+}
+
+// Sign the S256 code challenge (RFC 7636 Section 4.2) derived from
+// codeVerifier. The verifier itself is not included in the token.
+func SignCodeChallenge(codeVerifier string, alg Algorithm, key crypto.PrivateKey) (string, error) {
+	if codeVerifier == "" {
+		return "", errors.New("Empty PKCE code verifier")
+	}
+
+	payload, err := json.Marshal(codeChallengeClaims{
+		Challenge:       codeChallengeS256(codeVerifier),
+		ChallengeMethod: "S256",
+	})
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode code challenge: %v", err)
+	}
+
+	return SignWithHeader(payload, Header{Alg: alg, Typ: CodeChallengeType}, key)
+}
+
+// Verify a token from SignCodeChallenge and check that codeVerifier
+// produces the signed challenge
+func VerifyCodeChallenge(token string, codeVerifier string, kp KeyProvider) error {
+	header, payload, err := VerifyAndDecodeWithHeader(token, kp)
+	if err != nil {
+		return err
+	}
+	if header.Typ != CodeChallengeType {
+		return fmt.Errorf("Expected typ %s. Got %q", CodeChallengeType, header.Typ)
+	}
+
+	var claims codeChallengeClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return fmt.Errorf("Failed to decode code challenge: %v", err)
+	}
+	if claims.ChallengeMethod != "S256" {
+		return fmt.Errorf("Unsupported code challenge method %q", claims.ChallengeMethod)
+	}
+
+	expected := codeChallengeS256(codeVerifier)
+	if subtle.ConstantTimeCompare([]byte(claims.Challenge), []byte(expected)) != 1 {
+		return errors.New("PKCE code verifier does not match challenge")
+	}
+	return nil
+}
+
+// BASE64URL(SHA256(ASCII(code_verifier)))
+func codeChallengeS256(codeVerifier string) string {
+	digest := sha256.Sum256([]byte(codeVerifier))
+	return safeEncode(digest[:])
+}
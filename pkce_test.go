@@ -0,0 +1,85 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+)
+
+func TestCodeChallengeS256(t *testing.T) {
+	// RFC 7636 Appendix B
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	if got := codeChallengeS256(verifier); got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Fatalf("Unexpected challenge %s", got)
+	}
+}
+
+func TestSignCodeChallenge(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	kp := ProviderFromKey(&key.PublicKey)
+
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	token, err := SignCodeChallenge(verifier, ALG_ES256, key)
+	if err != nil {
+		t.Fatal("SignCodeChallenge: ", err)
+	}
+
+	header, payload, err := VerifyAndDecodeWithHeader(token, kp)
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if header.Typ != CodeChallengeType {
+		t.Fatalf("Unexpected typ %q", header.Typ)
+	}
+	if string(payload) != `{"challenge":"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM","challenge_method":"S256"}` {
+		t.Fatalf("Unexpected payload %s", payload)
+	}
+
+	if err := VerifyCodeChallenge(token, verifier, kp); err != nil {
+		t.Fatal("VerifyCodeChallenge: ", err)
+	}
+	if err := VerifyCodeChallenge(token, verifier+"x", kp); err == nil {
+		t.Fatal("Verified with the wrong code verifier")
+	}
+
+	// a token signed over the same claims without the typ is refused
+	plain, err := Sign(payload, ALG_ES256, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if err := VerifyCodeChallenge(plain, verifier, kp); err == nil {
+		t.Fatal("Verified a token without the pkce+jwt typ")
+	}
+
+	if _, err := SignCodeChallenge("", ALG_ES256, key); err == nil {
+		t.Fatal("Signed an empty code verifier")
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// select a key by the algorithm named in the JWS header
+func ProviderByAlgorithm(m map[Algorithm]crypto.PublicKey) KeyProvider {
+	keys := make(algorithmKeys, len(m))
+	for alg, key := range m {
+		keys[alg] = key
+	}
+	return keys
+}
+
+type algorithmKeys map[Algorithm]crypto.PublicKey
+
+func (ak algorithmKeys) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	key, ok := ak[h.Alg]
+	if !ok {
+		return nil, fmt.Errorf("No key registered for algorithm %s", h.Alg)
+	}
+	return key, nil
+}
+
+// try each provider in order, using the first key found. If every
+// provider fails, the returned error includes each of their failures
+func ChainProvider(providers ...KeyProvider) KeyProvider {
+	return chainProvider(append([]KeyProvider(nil), providers...))
+}
+
+type chainProvider []KeyProvider
+
+func (cp chainProvider) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	if len(cp) == 0 {
+		return nil, errors.New("No key providers in chain")
+	}
+
+	errs := make([]error, 0, len(cp))
+	for i, kp := range cp {
+		key, err := kp.GetJWSKey(h)
+		if err == nil {
+			return key, nil
+		}
+		errs = append(errs, fmt.Errorf("provider %d: %w", i, err))
+	}
+
+	return nil, fmt.Errorf("All key providers failed: %w", errors.Join(errs...))
+}
+
+// A key lookup made through an AuditingProvider
+type KeyLookupEvent struct {
+	Time      time.Time
+	Kid       string
+	Algorithm Algorithm
+	Found     bool
+	Error     error
+}
+
+// Wrap a provider so every key lookup is reported to logger, whether
+// or not it succeeds. Results are returned unchanged. Providers that
+// select keys by payload (PayloadKeyProvider) keep doing so.
+func AuditingProvider(inner KeyProvider, logger func(event KeyLookupEvent)) KeyProvider {
+	ap := auditingProvider{inner: inner, logger: logger, now: time.Now}
+	if pkp, ok := inner.(PayloadKeyProvider); ok {
+		return auditingPayloadProvider{auditingProvider: ap, inner: pkp}
+	}
+	return ap
+}
+
+type auditingProvider struct {
+	inner  KeyProvider
+	logger func(event KeyLookupEvent)
+	now    func() time.Time
+}
+
+func (ap auditingProvider) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	key, err := ap.inner.GetJWSKey(h)
+	ap.log(h, key, err)
+	return key, err
+}
+
+func (ap auditingProvider) log(h Header, key crypto.PublicKey, err error) {
+	ap.logger(KeyLookupEvent{
+		Time:      ap.now(),
+		Kid:       h.Kid,
+		Algorithm: h.Alg,
+		Found:     err == nil && key != nil,
+		Error:     err,
+	})
+}
+
+type auditingPayloadProvider struct {
+	auditingProvider
+	inner PayloadKeyProvider
+}
+
+func (ap auditingPayloadProvider) GetJWSKeyForPayload(h Header, payload []byte) (crypto.PublicKey, error) {
+	key, err := ap.inner.GetJWSKeyForPayload(h, payload)
+	ap.log(h, key, err)
+	return key, err
+}
@@ -0,0 +1,304 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProviderByAlgorithm(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	keys := map[Algorithm]crypto.PublicKey{
+		ALG_RS256: &rsaKey.PublicKey,
+		ALG_ES256: &ecKey.PublicKey,
+	}
+	kp := ProviderByAlgorithm(keys)
+
+	// later changes to the map must not affect the provider
+	delete(keys, ALG_ES256)
+
+	for alg, key := range map[Algorithm]crypto.PrivateKey{ALG_RS256: rsaKey, ALG_ES256: ecKey} {
+		jws, err := Sign(signTestPayload, alg, key)
+		if err != nil {
+			t.Fatal("Sign: ", err)
+		}
+		if _, err := VerifyAndDecode(jws, kp); err != nil {
+			t.Fatalf("Verify %s: %v", alg, err)
+		}
+	}
+
+	jws, err := Sign(signTestPayload, ALG_PS256, rsaKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, kp); err == nil {
+		t.Fatal("Verified with an unregistered algorithm")
+	}
+}
+
+type failingProvider struct {
+	err error
+}
+
+func (fp failingProvider) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	return nil, fp.err
+}
+
+func TestChainProvider(t *testing.T) {
+	key := []byte("chain secret")
+	jws, err := Sign(signTestPayload, ALG_HS256, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	errPrimary := errors.New("primary unavailable")
+	kp := ChainProvider(failingProvider{errPrimary}, ProviderFromKey(key))
+	if _, err := VerifyAndDecode(jws, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	errFallback := errors.New("fallback unavailable")
+	kp = ChainProvider(failingProvider{errPrimary}, failingProvider{errFallback})
+	_, err = kp.GetJWSKey(Header{Alg: ALG_HS256})
+	if err == nil {
+		t.Fatal("Expected an error from an exhausted chain")
+	}
+	if !errors.Is(err, errPrimary) || !errors.Is(err, errFallback) {
+		t.Fatal("Chain error does not wrap each failure: ", err)
+	}
+	if !strings.Contains(err.Error(), "provider 1: fallback unavailable") {
+		t.Fatal("Chain error does not describe each failure: ", err)
+	}
+
+	if _, err := ChainProvider().GetJWSKey(Header{Alg: ALG_HS256}); err == nil {
+		t.Fatal("Expected an error from an empty chain")
+	}
+}
+
+// KIDKeyProvider with a fixed key set, counting lookups by each path
+type testKIDProvider struct {
+	keys     map[string]crypto.PublicKey
+	fallback crypto.PublicKey
+	byKID    int
+	byHeader int
+}
+
+func (p *testKIDProvider) GetKeyByKID(kid string) (crypto.PublicKey, error) {
+	p.byKID++
+	key, ok := p.keys[kid]
+	if !ok {
+		return nil, errors.New("Unknown kid " + kid)
+	}
+	return key, nil
+}
+
+func (p *testKIDProvider) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	p.byHeader++
+	return p.fallback, nil
+}
+
+func TestKIDKeyProvider(t *testing.T) {
+	kp := &testKIDProvider{
+		keys:     map[string]crypto.PublicKey{"k1": []byte("secret-1")},
+		fallback: []byte("secret-2"),
+	}
+
+	jws, err := SignWithHeader(signTestPayload, Header{Alg: ALG_HS256, Kid: "k1"}, []byte("secret-1"))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if kp.byKID != 1 || kp.byHeader != 0 {
+		t.Fatalf("Expected a kid lookup. Got %d kid, %d header", kp.byKID, kp.byHeader)
+	}
+
+	// without a kid the provider's GetJWSKey is used
+	jws, err = Sign(signTestPayload, ALG_HS256, []byte("secret-2"))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if kp.byKID != 1 || kp.byHeader != 1 {
+		t.Fatalf("Expected a header lookup. Got %d kid, %d header", kp.byKID, kp.byHeader)
+	}
+
+	jws, err = SignWithHeader(signTestPayload, Header{Alg: ALG_HS256, Kid: "k2"}, []byte("secret-2"))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, kp); err == nil || !strings.Contains(err.Error(), "Unknown kid k2") {
+		t.Fatalf("Expected unknown kid error. Got %v", err)
+	}
+}
+
+func TestAuditingProvider(t *testing.T) {
+	now := time.Unix(1300819380, 0)
+	var events []KeyLookupEvent
+	kp := AuditingProvider(ProviderByAlgorithm(map[Algorithm]crypto.PublicKey{
+		ALG_HS256: []byte("audit-secret"),
+	}), func(event KeyLookupEvent) {
+		events = append(events, event)
+	}).(auditingProvider)
+	kp.now = func() time.Time { return now }
+
+	jws, err := SignWithHeader(signTestPayload, Header{Alg: ALG_HS256, Kid: "k1"}, []byte("audit-secret"))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	jws, err = Sign(signTestPayload, ALG_HS512, []byte("audit-secret"))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, kp); err == nil {
+		t.Fatal("Verified with an unregistered algorithm")
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events. Got %d", len(events))
+	}
+	if e := events[0]; !e.Time.Equal(now) || e.Kid != "k1" || e.Algorithm != ALG_HS256 || !e.Found || e.Error != nil {
+		t.Fatalf("Unexpected event %+v", e)
+	}
+	if e := events[1]; e.Kid != "" || e.Algorithm != ALG_HS512 || e.Found || e.Error == nil {
+		t.Fatalf("Unexpected event %+v", e)
+	}
+}
+
+// PayloadKeyProvider keyed by the iss claim
+type testIssuerProvider map[string]crypto.PublicKey
+
+func (p testIssuerProvider) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	return nil, errors.New("Issuer required")
+}
+
+func (p testIssuerProvider) GetJWSKeyForPayload(h Header, payload []byte) (crypto.PublicKey, error) {
+	claims, err := DecodeClaims[StandardClaims](payload)
+	if err != nil {
+		return nil, err
+	}
+	if key, ok := p[claims.Issuer]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownIssuer
+}
+
+func TestAuditingProvider_Payload(t *testing.T) {
+	var events []KeyLookupEvent
+	kp := AuditingProvider(testIssuerProvider{"joe": []byte("issuer-secret")}, func(event KeyLookupEvent) {
+		events = append(events, event)
+	})
+
+	if _, err := VerifyAndDecode(signTestClaims(t, []byte("issuer-secret"), StandardClaims{Issuer: "joe"}), kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if _, err := VerifyAndDecode(signTestClaims(t, []byte("issuer-secret"), StandardClaims{Issuer: "eve"}), kp); !errors.Is(err, ErrUnknownIssuer) {
+		t.Fatalf("Expected ErrUnknownIssuer. Got %v", err)
+	}
+
+	if len(events) != 2 || !events[0].Found || events[1].Found || !errors.Is(events[1].Error, ErrUnknownIssuer) {
+		t.Fatalf("Unexpected events %+v", events)
+	}
+}
+
+// IssuerSubjectKeyProvider with one key per tenant, recording lookups
+type testTenantProvider struct {
+	keys    map[[2]string]crypto.PublicKey
+	lookups [][2]string
+}
+
+func (p *testTenantProvider) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	return nil, errors.New("Issuer and subject required")
+}
+
+func (p *testTenantProvider) GetKeyByIssuerSubject(iss, sub string) (crypto.PublicKey, error) {
+	p.lookups = append(p.lookups, [2]string{iss, sub})
+	if key, ok := p.keys[[2]string{iss, sub}]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownIssuer
+}
+
+func TestIssuerSubjectKeyProvider(t *testing.T) {
+	kp := &testTenantProvider{
+		keys: map[[2]string]crypto.PublicKey{
+			{"https://idp.example.com", "tenant-a"}: []byte("secret-a"),
+			{"https://idp.example.com", "tenant-b"}: []byte("secret-b"),
+		},
+	}
+
+	a := signTestClaims(t, []byte("secret-a"), StandardClaims{Issuer: "https://idp.example.com", Subject: "tenant-a"})
+	if _, err := VerifyAndDecode(a, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	// tenant b's key does not verify a token claiming to be tenant b
+	// but signed with tenant a's key
+	forged := signTestClaims(t, []byte("secret-a"), StandardClaims{Issuer: "https://idp.example.com", Subject: "tenant-b"})
+	if _, err := VerifyAndDecode(forged, kp); err == nil {
+		t.Fatal("Verified a token signed with another tenant's key")
+	}
+
+	unknown := signTestClaims(t, []byte("secret-a"), StandardClaims{Issuer: "https://idp.example.com", Subject: "tenant-c"})
+	if _, err := VerifyAndDecode(unknown, kp); !errors.Is(err, ErrUnknownIssuer) {
+		t.Fatalf("Expected ErrUnknownIssuer. Got %v", err)
+	}
+
+	if len(kp.lookups) != 3 || kp.lookups[1] != [2]string{"https://idp.example.com", "tenant-b"} {
+		t.Fatalf("Unexpected lookups %v", kp.lookups)
+	}
+
+	// payloads that aren't JSON claims can't be routed
+	notJSON, err := Sign([]byte("payload"), ALG_HS256, []byte("secret-a"))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(notJSON, kp); err == nil {
+		t.Fatal("Verified a token without claims")
+	}
+}
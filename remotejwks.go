@@ -0,0 +1,175 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// how long a fetched JWKS is used before being fetched again
+	remoteJWKSLifetime = time.Hour
+
+	// minimum age of a cached JWKS before an unknown key forces a
+	// fetch, so unknown kids can't be used to hammer the server
+	remoteJWKSMinRefresh = time.Minute
+
+	// delay before retrying a failed fetch, doubled after each
+	// consecutive failure up to remoteJWKSMaxRetryDelay
+	remoteJWKSRetryDelay    = 5 * time.Second
+	remoteJWKSMaxRetryDelay = 5 * time.Minute
+)
+
+// Used to fetch key sets when the caller doesn't supply a client.
+// Unlike http.DefaultClient it gives up on an unresponsive server.
+var defaultFetchClient = &http.Client{Timeout: 10 * time.Second}
+
+// Select keys from a JWKS served over HTTP. The document is fetched on
+// first use, cached for an hour, and fetched again early when a token
+// names a key it doesn't contain (as happens after key rotation).
+// Concurrent lookups share a single fetch. When a fetch fails the
+// previously fetched keys continue to be used, and the fetch is retried
+// with an increasing delay. A nil client uses a client with a 10
+// second timeout.
+func NewRemoteJWKSProvider(jwksURL string, client *http.Client) KeyProvider {
+	return newRemoteJWKS(jwksURL, client, time.Now)
+}
+
+type remoteJWKS struct {
+	url    string
+	client *http.Client
+	now    func() time.Time
+
+	mu       sync.Mutex
+	keys     KeyProvider
+	fetched  time.Time
+	inflight *jwksFetch
+
+	// most recent consecutive fetch failures
+	failures  int
+	failedAt  time.Time
+	lastError error
+}
+
+// a fetch in progress, waited on by concurrent lookups
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+func newRemoteJWKS(jwksURL string, client *http.Client, now func() time.Time) *remoteJWKS {
+	if client == nil {
+		client = defaultFetchClient
+	}
+	return &remoteJWKS{
+		url:    jwksURL,
+		client: client,
+		now:    now,
+	}
+}
+
+func (r *remoteJWKS) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	now := r.now()
+	keys, fetched := r.cached()
+	if keys == nil || now.Sub(fetched) >= remoteJWKSLifetime {
+		// stale keys are still served if the fetch fails
+		if err := r.refresh(now); err != nil && keys == nil {
+			return nil, err
+		}
+		keys, fetched = r.cached()
+	}
+
+	key, err := keys.GetJWSKey(h)
+	if err != nil && now.Sub(fetched) >= remoteJWKSMinRefresh {
+		// the server may have rotated in a new key
+		if ferr := r.refresh(now); ferr != nil {
+			return nil, ferr
+		}
+		keys, _ = r.cached()
+		key, err = keys.GetJWSKey(h)
+	}
+	return key, err
+}
+
+func (r *remoteJWKS) cached() (KeyProvider, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.keys, r.fetched
+}
+
+// Fetch the JWKS without holding the lock, joining a fetch that is
+// already in progress. Fails without fetching while backing off from
+// an earlier failure.
+func (r *remoteJWKS) refresh(now time.Time) error {
+	r.mu.Lock()
+	if f := r.inflight; f != nil {
+		r.mu.Unlock()
+		<-f.done
+		return f.err
+	}
+	if r.failures > 0 && now.Sub(r.failedAt) < r.retryDelay() {
+		err := r.lastError
+		r.mu.Unlock()
+		return err
+	}
+	f := &jwksFetch{done: make(chan struct{})}
+	r.inflight = f
+	r.mu.Unlock()
+
+	keys, err := fetchJWKS(r.client, r.url)
+
+	r.mu.Lock()
+	if err != nil {
+		r.failures++
+		r.failedAt = now
+		r.lastError = err
+	} else {
+		r.keys = keys
+		r.fetched = now
+		r.failures = 0
+		r.lastError = nil
+	}
+	r.inflight = nil
+	r.mu.Unlock()
+
+	f.err = err
+	close(f.done)
+	return err
+}
+
+// must be called with r.mu held
+func (r *remoteJWKS) retryDelay() time.Duration {
+	delay := remoteJWKSRetryDelay
+	for i := 1; i < r.failures && delay < remoteJWKSMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > remoteJWKSMaxRetryDelay {
+		delay = remoteJWKSMaxRetryDelay
+	}
+	return delay
+}
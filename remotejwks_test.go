@@ -0,0 +1,205 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRemoteJWKSProvider(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	fetches := 0
+	jwks := testJWKS(t, map[string]interface{}{`"kid":"k1"`: &key.PublicKey})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write(jwks)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	kp := newRemoteJWKS(server.URL, nil, func() time.Time { return now })
+
+	jws, err := SignWithHeader(signTestPayload, Header{Alg: ALG_ES256, Kid: "k1"}, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := VerifyAndDecode(jws, kp); err != nil {
+			t.Fatal("Verify: ", err)
+		}
+	}
+	if fetches != 1 {
+		t.Fatalf("Expected one fetch. Got %d", fetches)
+	}
+
+	// unknown kids only trigger a fetch once the cache is old enough
+	if _, err := kp.GetJWSKey(Header{Alg: ALG_ES256, Kid: "k2"}); err == nil || fetches != 1 {
+		t.Fatalf("Unexpected lookup of unknown kid: fetches=%d err=%v", fetches, err)
+	}
+	now = now.Add(remoteJWKSMinRefresh)
+	if _, err := kp.GetJWSKey(Header{Alg: ALG_ES256, Kid: "k2"}); err == nil || fetches != 2 {
+		t.Fatalf("Unexpected lookup of unknown kid: fetches=%d err=%v", fetches, err)
+	}
+
+	// the cache expires
+	now = now.Add(remoteJWKSLifetime)
+	if _, err := VerifyAndDecode(jws, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if fetches != 3 {
+		t.Fatalf("Expected the cache to expire. Got %d fetches", fetches)
+	}
+
+	if _, err := NewRemoteJWKSProvider(server.URL+"/missing\x00", nil).GetJWSKey(Header{Alg: ALG_ES256}); err == nil {
+		t.Fatal("Expected an error for a bad URL")
+	}
+}
+
+func TestRemoteJWKSProvider_Failures(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	fetches, fail := 0, true
+	jwks := testJWKS(t, map[string]interface{}{`"kid":"k1"`: &key.PublicKey})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(jwks)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	kp := newRemoteJWKS(server.URL, nil, func() time.Time { return now })
+	if kp.client.Timeout == 0 {
+		t.Fatal("Default client has no timeout")
+	}
+
+	// failures back off before retrying
+	h := Header{Alg: ALG_ES256, Kid: "k1"}
+	for i := 0; i < 3; i++ {
+		if _, err := kp.GetJWSKey(h); err == nil {
+			t.Fatal("Expected an error from a failing server")
+		}
+	}
+	if fetches != 1 {
+		t.Fatalf("Expected one fetch while backing off. Got %d", fetches)
+	}
+	fail = false
+	now = now.Add(remoteJWKSRetryDelay)
+	if _, err := kp.GetJWSKey(h); err != nil || fetches != 2 {
+		t.Fatalf("Expected a retry after the delay: fetches=%d err=%v", fetches, err)
+	}
+
+	// expired keys are still served while the server is failing
+	fail = true
+	now = now.Add(remoteJWKSLifetime)
+	for i := 0; i < 3; i++ {
+		if _, err := kp.GetJWSKey(h); err != nil {
+			t.Fatal("Expected the stale key to be used: ", err)
+		}
+	}
+	if fetches != 3 {
+		t.Fatalf("Expected one fetch while backing off. Got %d", fetches)
+	}
+
+	// consecutive failures increase the delay
+	now = now.Add(remoteJWKSRetryDelay)
+	kp.GetJWSKey(h)
+	if fetches != 4 {
+		t.Fatalf("Expected a retry after the delay. Got %d fetches", fetches)
+	}
+	now = now.Add(remoteJWKSRetryDelay)
+	kp.GetJWSKey(h)
+	if fetches != 4 {
+		t.Fatalf("Expected the retry delay to grow. Got %d fetches", fetches)
+	}
+	now = now.Add(remoteJWKSRetryDelay)
+	kp.GetJWSKey(h)
+	if fetches != 5 {
+		t.Fatalf("Expected a retry after the longer delay. Got %d fetches", fetches)
+	}
+}
+
+func TestRemoteJWKSProvider_SharedFetch(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	var fetches int32
+	started, release := make(chan struct{}), make(chan struct{})
+	jwks := testJWKS(t, map[string]interface{}{`"kid":"k1"`: &key.PublicKey})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			close(started)
+		}
+		<-release
+		w.Write(jwks)
+	}))
+	defer server.Close()
+
+	kp := NewRemoteJWKSProvider(server.URL, nil)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := kp.GetJWSKey(Header{Alg: ALG_ES256, Kid: "k1"})
+			errs <- err
+		}()
+	}
+
+	<-started
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal("GetJWSKey: ", err)
+		}
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Fatalf("Expected concurrent lookups to share one fetch. Got %d", n)
+	}
+}
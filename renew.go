@@ -0,0 +1,122 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Verify a JWT and re-sign its claims with signingKey, setting iat to
+// now, exp to now+ttl and jti to a fresh identifier, so the renewed
+// token isn't mistaken for a replay of the original. All other claims
+// are kept. The header is kept too, except that parameters identifying
+// the signing key (kid, jku, jwk, x5u, x5c, x5t and x5t#S256) are
+// removed unless signingKey is the key that signed the original token.
+// Tokens that expired more than the WithRenewalGracePeriod ago fail
+// with ErrTokenExpiredBeyondRenewalWindow.
+func Renew(token string, kp KeyProvider, ttl time.Duration, signingKey crypto.PrivateKey, opts ...VerifyOption) (string, error) {
+	if ttl <= 0 {
+		return "", errors.New("Token TTL must be positive")
+	}
+
+	config := newVerifyConfig(opts)
+	header, payload, err := verifyAndDecode([]byte(token), kp, config)
+	if err != nil {
+		return "", err
+	}
+
+	var claims StandardClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("Failed to decode claims: %v", err)
+	}
+
+	clock := config.clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	now := clock.Now()
+
+	if claims.ExpiresAt != nil && now.Sub(claims.ExpiresAt.Time) > config.renewalGrace+config.clockSkew {
+		return "", ErrTokenExpiredBeyondRenewalWindow
+	}
+	if claims.NotBefore != nil && now.Add(config.clockSkew).Before(claims.NotBefore.Time) {
+		return "", ErrTokenNotYetValid
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil || fields == nil {
+		return "", errors.New("Token payload must be a JSON object")
+	}
+
+	fields["iat"], err = json.Marshal(NewNumericDate(now))
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode iat: %v", err)
+	}
+	fields["exp"], err = json.Marshal(NewNumericDate(now.Add(ttl)))
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode exp: %v", err)
+	}
+	jti, err := GenerateJTI()
+	if err != nil {
+		return "", err
+	}
+	fields["jti"], err = json.Marshal(jti)
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode jti: %v", err)
+	}
+
+	// the original key's identifiers would point verifiers at the
+	// wrong key
+	if !signedBy(token, signingKey) {
+		header.Kid = ""
+		header.Jku = ""
+		header.Jwk = nil
+		header.X5u = ""
+		header.X5c = nil
+		header.X5t = ""
+		header.X5tS256 = ""
+	}
+
+	renewed, err := json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode payload: %v", err)
+	}
+
+	return SignWithHeader(renewed, header, signingKey)
+}
+
+// whether token's signature verifies with the public half of key
+func signedBy(token string, key crypto.PrivateKey) bool {
+	pub, err := NormalizeToPublicKey(key)
+	if err != nil {
+		return false
+	}
+	_, _, err = verifyAndDecode([]byte(token), ProviderFromKey(pub), newVerifyConfig(nil))
+	return err == nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRenew(t *testing.T) {
+	key := []byte("renew secret")
+	kp := ProviderFromKey(key)
+	now := time.Unix(1300819380, 0)
+	clock := WithClock(FixedClock(now))
+
+	token, err := SignWithHeader([]byte(`{"sub":"joe","scope":"read","iat":1,"exp":1300819440}`), Header{Alg: ALG_HS256, Kid: "k1"}, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	renewed, err := Renew(token, kp, time.Hour, key, clock)
+	if err != nil {
+		t.Fatal("Renew: ", err)
+	}
+
+	header, payload, err := VerifyAndDecodeWithHeader(renewed, kp)
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if header.Kid != "k1" {
+		t.Fatalf("Header not preserved: %+v", header)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+	jti, _ := claims["jti"].(string)
+	if jti == "" || claims["exp"] != 1300822980.0 || claims["iat"] != 1300819380.0 || claims["scope"] != "read" || claims["sub"] != "joe" || len(claims) != 5 {
+		t.Fatalf("Unexpected payload %s", payload)
+	}
+
+	// each renewal gets a fresh jti
+	again, err := Renew(renewed, kp, time.Hour, key, clock)
+	if err != nil {
+		t.Fatal("Renew: ", err)
+	}
+	_, payload, err = VerifyAndDecodeWithHeader(again, kp)
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+	if claims["jti"] == jti {
+		t.Fatal("Renewal reused the jti")
+	}
+}
+
+func TestRenew_NewKey(t *testing.T) {
+	oldKey, newKey := []byte("old secret"), []byte("new secret")
+	clock := WithClock(FixedClock(time.Unix(1300819380, 0)))
+
+	header := Header{Alg: ALG_HS256, Kid: "old", X5t: "thumb", X5c: []string{"MIIB"}}
+	token, err := SignWithHeader([]byte(`{"sub":"joe","exp":1300819440}`), header, oldKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	// identifiers of the original key are dropped
+	renewed, err := Renew(token, ProviderFromKey(oldKey), time.Hour, newKey, clock)
+	if err != nil {
+		t.Fatal("Renew: ", err)
+	}
+	renewedHeader, _, err := VerifyAndDecodeWithHeader(renewed, ProviderFromKey(newKey))
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if renewedHeader.Kid != "" || renewedHeader.X5t != "" || renewedHeader.X5c != nil || renewedHeader.Alg != ALG_HS256 {
+		t.Fatalf("Key identifiers kept for a new key: %+v", renewedHeader)
+	}
+}
+
+func TestRenew_GracePeriod(t *testing.T) {
+	key := []byte("renew secret")
+	kp := ProviderFromKey(key)
+	now := time.Unix(1300819380, 0)
+	clock := WithClock(FixedClock(now))
+
+	expired := signTestClaims(t, key, StandardClaims{ExpiresAt: NewNumericDate(now.Add(-10 * time.Minute))})
+
+	if _, err := Renew(expired, kp, time.Hour, key, clock); !errors.Is(err, ErrTokenExpiredBeyondRenewalWindow) {
+		t.Fatalf("Expected ErrTokenExpiredBeyondRenewalWindow. Got %v", err)
+	}
+	if _, err := Renew(expired, kp, time.Hour, key, clock, WithRenewalGracePeriod(5*time.Minute)); !errors.Is(err, ErrTokenExpiredBeyondRenewalWindow) {
+		t.Fatalf("Expected ErrTokenExpiredBeyondRenewalWindow. Got %v", err)
+	}
+	if _, err := Renew(expired, kp, time.Hour, key, clock, WithRenewalGracePeriod(15*time.Minute)); err != nil {
+		t.Fatal("Renew: ", err)
+	}
+
+	early := signTestClaims(t, key, StandardClaims{NotBefore: NewNumericDate(now.Add(time.Hour))})
+	if _, err := Renew(early, kp, time.Hour, key, clock); !errors.Is(err, ErrTokenNotYetValid) {
+		t.Fatalf("Expected ErrTokenNotYetValid. Got %v", err)
+	}
+
+	// the signature is always checked
+	forged := signTestClaims(t, []byte("other secret"), StandardClaims{Subject: "joe"})
+	if _, err := Renew(forged, kp, time.Hour, key, clock); err == nil {
+		t.Fatal("Renewed a token with a bad signature")
+	}
+	if _, err := Renew(expired, kp, 0, key, clock); err == nil {
+		t.Fatal("Renewed with a zero TTL")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"errors"
+	"testing"
+)
+
+// revokes a fixed set of jti values
+type testRevocationList struct {
+	revoked map[string]bool
+	err     error
+	calls   int
+}
+
+func (l *testRevocationList) IsRevoked(jti string, header Header) (bool, error) {
+	l.calls++
+	return l.revoked[jti], l.err
+}
+
+func TestVerify_RevocationChecker(t *testing.T) {
+	key := []byte("revocation-secret")
+	kp := ProviderFromKey(key)
+	list := &testRevocationList{revoked: map[string]bool{"bad": true}}
+
+	good := signTestClaims(t, key, StandardClaims{ID: "good"})
+	if _, err := VerifyAndDecode(good, kp, WithRevocationChecker(list)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	bad := signTestClaims(t, key, StandardClaims{ID: "bad"})
+	payload, err := VerifyAndDecode(bad, kp, WithRevocationChecker(list))
+	if !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("Expected ErrTokenRevoked. Got %v", err)
+	}
+	if payload != nil {
+		t.Fatal("Returned the payload of a revoked token")
+	}
+	if _, _, _, err := VerifyJWT(bad, kp, WithRevocationChecker(list)); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("Expected ErrTokenRevoked from VerifyJWT. Got %v", err)
+	}
+
+	// no jti, nothing to check
+	calls := list.calls
+	if _, err := VerifyAndDecode(signTestClaims(t, key, StandardClaims{Issuer: "joe"}), kp, WithRevocationChecker(list)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if list.calls != calls {
+		t.Fatal("Checker consulted for a token without a jti")
+	}
+
+	// only checked once the signature is valid
+	if _, err := VerifyAndDecode(bad, ProviderFromKey([]byte("wrong")), WithRevocationChecker(list)); err == nil || list.calls != calls {
+		t.Fatalf("Checker consulted before signature verification: %v", err)
+	}
+
+	errBackend := errors.New("backend down")
+	list.err = errBackend
+	if _, err := VerifyAndDecode(good, kp, WithRevocationChecker(list)); !errors.Is(err, errBackend) {
+		t.Fatalf("Expected checker error. Got %v", err)
+	}
+}
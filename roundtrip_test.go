@@ -0,0 +1,82 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, alg := range SupportedAlgorithms() {
+		var privKey crypto.PrivateKey
+		var pubKey crypto.PublicKey
+		switch alg {
+		case ALG_NONE:
+			if noneAlgorithmEnabled {
+				testSignAndVerify(t, alg, NoneKey, NoneKey)
+			}
+			continue
+
+		case ALG_HS256, ALG_HS384, ALG_HS512:
+			secret := make([]byte, 64)
+			if _, err := rand.Read(secret); err != nil {
+				t.Fatal("Read: ", err)
+			}
+			privKey, pubKey = secret, secret
+
+		case ALG_ES256K:
+			// NewEphemeralKeyPair can't generate secp256k1 keys
+			key, err := ecdsa.GenerateKey(testSecp256k1, rand.Reader)
+			if err != nil {
+				t.Fatal("GenerateKey: ", err)
+			}
+			privKey, pubKey = key, &key.PublicKey
+
+		default:
+			var err error
+			privKey, pubKey, err = NewEphemeralKeyPair(alg)
+			if err != nil {
+				t.Fatalf("%s: NewEphemeralKeyPair: %v", alg, err)
+			}
+		}
+
+		testSignAndVerify(t, alg, privKey, pubKey)
+
+		// a corrupted signature is refused
+		jws, err := Sign(signTestPayload, alg, privKey)
+		if err != nil {
+			t.Fatalf("%s: Sign: %v", alg, err)
+		}
+		tampered := jws[:len(jws)-4] + "AAAA"
+		if tampered != jws {
+			if _, err := VerifyAndDecode(tampered, ProviderFromKey(pubKey)); err == nil {
+				t.Fatalf("%s: verified a tampered signature", alg)
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Assembles an SD-JWT (draft-ietf-oauth-selective-disclosure-jwt):
+// selected top-level claims are replaced by digests in the signed
+// payload, and the disclosures that reveal them are appended to the
+// token, separated by "~". The holder may then drop any disclosure
+// before presenting the token.
+type SDJWTBuilder struct {
+	claims      interface{}
+	disclosures []sdDisclosure
+	salt        func() (string, error)
+}
+
+type sdDisclosure struct {
+	name  string
+	value interface{}
+}
+
+// Start building an SD-JWT over claims, which must marshal to a JSON
+// object. Claims that are not added as disclosures are always visible.
+func NewSDJWT(claims interface{}) *SDJWTBuilder {
+	return &SDJWTBuilder{claims: claims, salt: sdSalt}
+}
+
+// Make a top-level claim selectively disclosable. A claim of the same
+// name in the builder's claims is replaced.
+func (b *SDJWTBuilder) AddDisclosure(claimName string, value interface{}) *SDJWTBuilder {
+	b.disclosures = append(b.disclosures, sdDisclosure{name: claimName, value: value})
+	return b
+}
+
+// Sign the payload and return the SD-JWT in the form
+// <JWS>~<Disclosure 1>~...~<Disclosure N>~
+func (b *SDJWTBuilder) Sign(header Header, key crypto.PrivateKey) (string, error) {
+	claims := make(map[string]json.RawMessage)
+	if b.claims != nil {
+		data, err := json.Marshal(b.claims)
+		if err != nil {
+			return "", fmt.Errorf("Failed to encode claims: %v", err)
+		}
+		if err := json.Unmarshal(data, &claims); err != nil || claims == nil {
+			return "", errors.New("SD-JWT claims must be a JSON object")
+		}
+	}
+	if _, ok := claims["_sd"]; ok {
+		return "", errors.New("SD-JWT claims must not contain _sd")
+	}
+	if _, ok := claims["_sd_alg"]; ok {
+		return "", errors.New("SD-JWT claims must not contain _sd_alg")
+	}
+
+	seen := make(map[string]bool)
+	encoded := make([]string, len(b.disclosures))
+	digests := make([]string, len(b.disclosures))
+	for i, d := range b.disclosures {
+		if d.name == "" || d.name == "_sd" || d.name == "..." {
+			return "", fmt.Errorf("Invalid SD-JWT claim name %q", d.name)
+		}
+		if seen[d.name] {
+			return "", fmt.Errorf("Claim %q disclosed more than once", d.name)
+		}
+		seen[d.name] = true
+		delete(claims, d.name)
+
+		salt, err := b.salt()
+		if err != nil {
+			return "", err
+		}
+
+		data, err := json.Marshal([]interface{}{salt, d.name, d.value})
+		if err != nil {
+			return "", fmt.Errorf("Failed to encode disclosure %q: %v", d.name, err)
+		}
+
+		encoded[i] = safeEncode(data)
+		digest := sha256.Sum256([]byte(encoded[i]))
+		digests[i] = safeEncode(digest[:])
+	}
+
+	// sorted so the payload doesn't reveal the claims' original order
+	sort.Strings(digests)
+
+	var err error
+	claims["_sd"], err = json.Marshal(digests)
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode payload: %v", err)
+	}
+	claims["_sd_alg"] = json.RawMessage(`"sha-256"`)
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode payload: %v", err)
+	}
+
+	jws, err := SignWithHeader(payload, header, key)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(jws)
+	sb.WriteByte('~')
+	for _, disclosure := range encoded {
+		sb.WriteString(disclosure)
+		sb.WriteByte('~')
+	}
+	return sb.String(), nil
+}
+
+// random 128-bit salt, base64url encoded
+func sdSalt() (string, error) {
+	var salt [16]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return "", fmt.Errorf("Failed to generate disclosure salt: %v", err)
+	}
+	return safeEncode(salt[:]), nil
+}
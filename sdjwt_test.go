@@ -0,0 +1,138 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSDJWTBuilder(t *testing.T) {
+	key := []byte("sd-jwt secret")
+
+	b := NewSDJWT(map[string]interface{}{
+		"iss":   "https://issuer.example.com",
+		"email": "placeholder",
+	}).
+		AddDisclosure("email", "joe@example.com").
+		AddDisclosure("address", map[string]string{"country": "DE"})
+
+	salts := []string{"2GLC42sKQveCfGfryNRN9w", "eluV5Og3gSNII8EYnsxA_A"}
+	b.salt = func() (string, error) {
+		salt := salts[0]
+		salts = salts[1:]
+		return salt, nil
+	}
+
+	sdjwt, err := b.Sign(Header{Alg: ALG_HS256, Typ: "example+sd-jwt"}, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if !strings.HasSuffix(sdjwt, "~") {
+		t.Fatalf("SD-JWT must end with ~: %s", sdjwt)
+	}
+
+	parts := strings.Split(sdjwt, "~")
+	if len(parts) != 4 {
+		t.Fatalf("Expected JWS, 2 disclosures and a trailing ~. Got %d parts", len(parts))
+	}
+
+	payload, err := VerifyAndDecode(parts[0], ProviderFromKey(key))
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	var claims map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+	if _, ok := claims["email"]; ok {
+		t.Fatal("Disclosed claim left in the payload")
+	}
+	if string(claims["iss"]) != `"https://issuer.example.com"` || string(claims["_sd_alg"]) != `"sha-256"` {
+		t.Fatalf("Unexpected payload %s", payload)
+	}
+
+	var sd []string
+	if err := json.Unmarshal(claims["_sd"], &sd); err != nil {
+		t.Fatal("Unmarshal _sd: ", err)
+	}
+	if !sort.StringsAreSorted(sd) {
+		t.Fatalf("_sd digests are not sorted: %v", sd)
+	}
+
+	expected := []string{
+		`["2GLC42sKQveCfGfryNRN9w","email","joe@example.com"]`,
+		`["eluV5Og3gSNII8EYnsxA_A","address",{"country":"DE"}]`,
+	}
+	for i, disclosure := range parts[1:3] {
+		data, err := safeDecode(disclosure)
+		if err != nil {
+			t.Fatal("Decode disclosure: ", err)
+		}
+		if string(data) != expected[i] {
+			t.Fatalf("Unexpected disclosure %s", data)
+		}
+
+		digest := sha256.Sum256([]byte(disclosure))
+		found := false
+		for _, d := range sd {
+			found = found || d == safeEncode(digest[:])
+		}
+		if !found {
+			t.Fatalf("No digest in _sd for disclosure %s", data)
+		}
+	}
+}
+
+func TestSDJWTBuilder_Errors(t *testing.T) {
+	key := []byte("sd-jwt secret")
+	header := Header{Alg: ALG_HS256}
+
+	tests := []*SDJWTBuilder{
+		NewSDJWT([]string{"not", "an", "object"}),
+		NewSDJWT(map[string]interface{}{"_sd": []string{}}),
+		NewSDJWT(nil).AddDisclosure("email", "a").AddDisclosure("email", "b"),
+		NewSDJWT(nil).AddDisclosure("_sd", "a"),
+		NewSDJWT(nil).AddDisclosure("bad", func() {}),
+	}
+	for i, b := range tests {
+		if _, err := b.Sign(header, key); err == nil {
+			t.Fatalf("Test %d: expected an error", i)
+		}
+	}
+
+	// no disclosures still produces a valid SD-JWT
+	sdjwt, err := NewSDJWT(map[string]string{"iss": "joe"}).Sign(header, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if strings.Count(sdjwt, "~") != 1 || !strings.HasSuffix(sdjwt, "~") {
+		t.Fatalf("Unexpected SD-JWT %s", sdjwt)
+	}
+}
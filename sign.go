@@ -0,0 +1,329 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+)
+
+// Optional behavior for Sign and SignWithHeader
+type SignOption func(*signConfig)
+
+type signConfig struct {
+	lowS    bool
+	pss     *PSSOptions
+	metrics Metrics
+}
+
+// Parameters for PS256, PS384 and PS512 signatures
+type PSSOptions struct {
+	// Salt length in bytes, or one of rsa.PSSSaltLengthAuto and
+	// rsa.PSSSaltLengthEqualsHash
+	SaltLength int
+}
+
+// Normalize ECDSA signatures to the low-S form, for verifiers that
+// reject malleable signatures. ES256K signatures are always low-S.
+func WithLowS() SignOption {
+	return func(c *signConfig) {
+		c.lowS = true
+	}
+}
+
+// Control the RSASSA-PSS salt length. Without this option the salt is
+// the size of the hash output, as RFC 7518 Section 3.5 requires.
+func WithPSSOptions(opts PSSOptions) SignOption {
+	return func(c *signConfig) {
+		c.pss = &opts
+	}
+}
+
+// Sign a payload using the specified algorithm, producing a JWS in
+// compact serialization
+func Sign(payload []byte, alg Algorithm, key crypto.PrivateKey, opts ...SignOption) (string, error) {
+	return SignWithHeader(payload, Header{Alg: alg}, key, opts...)
+}
+
+// Sign a payload using the supplied header. The signature algorithm
+// is taken from header.Alg
+func SignWithHeader(payload []byte, header Header, key crypto.PrivateKey, opts ...SignOption) (jws string, err error) {
+	var config signConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	if config.metrics != nil {
+		defer func() {
+			if err == nil {
+				config.metrics.TokenSigned(header.Alg)
+			}
+		}()
+	}
+
+	data, err := json.Marshal(header)
+	if err != nil {
+		err = fmt.Errorf("Failed to encode header: %v", err)
+		return
+	}
+
+	signingInput := safeEncode(data) + "." + safeEncode(payload)
+
+	// keys held in hardware (HSM, TPM, cloud KMS) are only available
+	// as a crypto.Signer
+	if signer, ok := hardwareSigner(key); ok {
+		var signature []byte
+		signature, err = signerSignature(signer, header.Alg, signingInput, config.signerOpts(header.Alg), config.lowS)
+		if err != nil {
+			return
+		}
+
+		jws = signingInput + "." + safeEncode(signature)
+		return
+	}
+
+	var signature []byte
+	switch header.Alg {
+	case ALG_NONE:
+		// mirror verification: only produce plaintext if the caller
+		// explicitly passed in the "none" key
+		if key != NoneKey {
+			err = errors.New("Refusing to create plaintext JWS")
+			return
+		}
+
+	case ALG_HS256, ALG_HS384, ALG_HS512, ALG_RS256, ALG_RS384, ALG_RS512, ALG_ES256, ALG_ES384, ALG_ES512, ALG_ES256K, ALG_PS256, ALG_PS384, ALG_PS512:
+		var hs hash.Hash
+		var signDigest func(digest []byte) ([]byte, error)
+		hs, signDigest, err = digestSigner(header.Alg, key, &config)
+		if err != nil {
+			return
+		}
+
+		io.WriteString(hs, signingInput)
+		signature, err = signDigest(hs.Sum(nil))
+		if err != nil {
+			return
+		}
+
+	case ALG_EdDSA:
+		privKey, ok := key.(ed25519.PrivateKey)
+		if !ok || len(privKey) != ed25519.PrivateKeySize {
+			err = fmt.Errorf("Expected Ed25519 private key. Got %T", key)
+			return
+		}
+
+		signature = ed25519.Sign(privKey, []byte(signingInput))
+
+	default:
+		err = fmt.Errorf("Unknown signature algorithm: %s", header.Alg)
+		return
+	}
+
+	jws = signingInput + "." + safeEncode(signature)
+	return
+}
+
+// For algorithms that sign a digest of the signing input: the hash to
+// write the input to, and the function turning its sum into a signature
+func digestSigner(alg Algorithm, key crypto.PrivateKey, config *signConfig) (hash.Hash, func(digest []byte) ([]byte, error), error) {
+	switch alg {
+	case ALG_HS256, ALG_HS384, ALG_HS512:
+		symmetricKey, ok := key.([]byte)
+		if !ok {
+			return nil, nil, fmt.Errorf("Expected symmetric ([]byte) key. Got %T", key)
+		}
+
+		// the HMAC is the signature
+		hm := hmac.New(hashForAlgorithm(alg).New, symmetricKey)
+		return hm, func(digest []byte) ([]byte, error) {
+			return digest, nil
+		}, nil
+
+	case ALG_RS256, ALG_RS384, ALG_RS512:
+		privKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, nil, fmt.Errorf("Expected RSA private key. Got %T", key)
+		}
+
+		htype := hashForAlgorithm(alg)
+		return htype.New(), func(digest []byte) ([]byte, error) {
+			signature, err := rsa.SignPKCS1v15(rand.Reader, privKey, htype, digest)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to sign JWS: %v", err)
+			}
+			return signature, nil
+		}, nil
+
+	case ALG_ES256, ALG_ES384, ALG_ES512, ALG_ES256K:
+		privKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, nil, fmt.Errorf("Expected ECDSA private key. Got %T", key)
+		}
+
+		return hashForAlgorithm(alg).New(), func(digest []byte) ([]byte, error) {
+			return signECDSA(privKey, alg, digest, config.lowS)
+		}, nil
+
+	case ALG_PS256, ALG_PS384, ALG_PS512:
+		privKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, nil, fmt.Errorf("Expected RSA private key. Got %T", key)
+		}
+
+		// RFC 7518 Section 3.5 requires the salt to be the same size
+		// as the hash output
+		htype := hashForAlgorithm(alg)
+		pssOpts := &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthEqualsHash,
+			Hash:       htype,
+		}
+		if config.pss != nil {
+			pssOpts.SaltLength = config.pss.SaltLength
+		}
+
+		return htype.New(), func(digest []byte) ([]byte, error) {
+			signature, err := rsa.SignPSS(rand.Reader, privKey, htype, digest, pssOpts)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to sign JWS: %v", err)
+			}
+			return signature, nil
+		}, nil
+	}
+
+	return nil, nil, fmt.Errorf("Unknown signature algorithm: %s", alg)
+}
+
+// a crypto.Signer that is not one of the concrete private key types
+// signed with directly
+func hardwareSigner(key crypto.PrivateKey) (crypto.Signer, bool) {
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return nil, false
+	}
+
+	signer, ok := key.(crypto.Signer)
+	return signer, ok
+}
+
+// options for a crypto.Signer, or nil to use the algorithm's defaults
+func (c *signConfig) signerOpts(alg Algorithm) crypto.SignerOpts {
+	switch alg {
+	case ALG_PS256, ALG_PS384, ALG_PS512:
+		if c.pss != nil {
+			return &rsa.PSSOptions{
+				SaltLength: c.pss.SaltLength,
+				Hash:       hashForAlgorithm(alg),
+			}
+		}
+	}
+	return nil
+}
+
+// hash function used by an algorithm's signature
+func hashForAlgorithm(alg Algorithm) crypto.Hash {
+	switch alg {
+	case ALG_HS256, ALG_RS256, ALG_ES256, ALG_PS256, ALG_ES256K:
+		return crypto.SHA256
+	case ALG_HS384, ALG_RS384, ALG_ES384, ALG_PS384:
+		return crypto.SHA384
+	case ALG_HS512, ALG_RS512, ALG_ES512, ALG_PS512:
+		return crypto.SHA512
+	}
+
+	panic("Algorithm logic error with " + alg)
+}
+
+// produce the JWS (R || S) form of an ECDSA signature
+func signECDSA(privKey *ecdsa.PrivateKey, alg Algorithm, hashed []byte, lowS bool) ([]byte, error) {
+	size, err := ecdsaSignatureSize(alg, privKey.Curve)
+	if err != nil {
+		return nil, err
+	}
+
+	r, s, err := ecdsa.Sign(rand.Reader, privKey, hashed)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to sign JWS: %v", err)
+	}
+
+	return encodeECDSASignature(alg, privKey.Curve, size, r, s, lowS), nil
+}
+
+// size of each of R and S for an algorithm, after checking the key's
+// curve is appropriate for it
+func ecdsaSignatureSize(alg Algorithm, curve elliptic.Curve) (int, error) {
+	var size int
+	switch alg {
+	case ALG_ES256, ALG_ES256K:
+		size = 32
+	case ALG_ES384:
+		size = 48
+	case ALG_ES512:
+		size = 66
+	default:
+		panic("Algorithm logic error with " + alg)
+	}
+
+	if curve == nil {
+		return 0, errors.New("Invalid ECDSA key: missing curve")
+	}
+	if (alg == ALG_ES256K) != isSecp256k1(curve) || (curve.Params().BitSize+7)/8 != size {
+		return 0, fmt.Errorf("Key curve %s is not valid for %s", curve.Params().Name, alg)
+	}
+
+	return size, nil
+}
+
+func encodeECDSASignature(alg Algorithm, curve elliptic.Curve, size int, r, s *big.Int, lowS bool) []byte {
+	// Bitcoin and Ethereum reject signatures where S is in the upper
+	// half of the curve order, since (R, N-S) is an equally valid
+	// signature for the same message. Always emit the low-S form for
+	// ES256K so tokens interoperate with those verifiers.
+	if (lowS || alg == ALG_ES256K) && !isLowS(curve, s) {
+		s = new(big.Int).Sub(curve.Params().N, s)
+	}
+
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+	return signature
+}
+
+// whether S lies in the lower half of the curve order
+func isLowS(curve elliptic.Curve, s *big.Int) bool {
+	halfN := new(big.Int).Rsh(curve.Params().N, 1)
+	return s.Cmp(halfN) <= 0
+}
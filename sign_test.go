@@ -0,0 +1,279 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+var signTestPayload = []byte(`{"iss":"joe","exp":1300819380,"http://example.com/is_root":true}`)
+
+func testSignAndVerify(t *testing.T, alg Algorithm, privKey crypto.PrivateKey, pubKey crypto.PublicKey) {
+	jws, err := Sign(signTestPayload, alg, privKey)
+	if err != nil {
+		t.Fatalf("Sign %s: %v", alg, err)
+	}
+
+	header, data, err := VerifyAndDecodeWithHeader(jws, ProviderFromKey(pubKey))
+	if err != nil {
+		t.Fatalf("Verify %s: %v", alg, err)
+	}
+
+	if header.Alg != alg {
+		t.Fatalf("Unexpected algorithm: %s", header.Alg)
+	}
+	if !bytes.Equal(data, signTestPayload) {
+		t.Fatalf("Unexpected payload: %v", data)
+	}
+}
+
+// split a compact JWS, decoding only the signature
+func splitTestJWS(jws string) (header, payload string, signature []byte, err error) {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 {
+		err = errors.New("Malformed JWS")
+		return
+	}
+
+	signature, err = safeDecode(parts[2])
+	return parts[0], parts[1], signature, err
+}
+
+func TestSign_HMAC(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+	for _, alg := range []Algorithm{ALG_HS256, ALG_HS384, ALG_HS512} {
+		testSignAndVerify(t, alg, key, key)
+	}
+}
+
+func TestSign_RSA(t *testing.T) {
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	for _, alg := range []Algorithm{ALG_RS256, ALG_RS384, ALG_RS512, ALG_PS256, ALG_PS384, ALG_PS512} {
+		testSignAndVerify(t, alg, privKey, &privKey.PublicKey)
+	}
+}
+
+func TestSign_PSSOptions(t *testing.T) {
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	// check the salt length by verifying with it fixed
+	verifySalt := func(jws string, saltLength int) error {
+		header, payload, signature, err := splitTestJWS(jws)
+		if err != nil {
+			return err
+		}
+		hashed := crypto.SHA256.New()
+		hashed.Write([]byte(header + "." + payload))
+		return rsa.VerifyPSS(&privKey.PublicKey, crypto.SHA256, hashed.Sum(nil), signature, &rsa.PSSOptions{SaltLength: saltLength})
+	}
+
+	jws, err := Sign(signTestPayload, ALG_PS256, privKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if err := verifySalt(jws, crypto.SHA256.Size()); err != nil {
+		t.Fatal("Default salt is not the hash size: ", err)
+	}
+
+	jws, err = Sign(signTestPayload, ALG_PS256, privKey, WithPSSOptions(PSSOptions{SaltLength: 20}))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if err := verifySalt(jws, 20); err != nil {
+		t.Fatal("Salt length option not applied: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(&privKey.PublicKey)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+}
+
+func TestSign_ECDSA(t *testing.T) {
+	curves := map[Algorithm]elliptic.Curve{
+		ALG_ES256: elliptic.P256(),
+		ALG_ES384: elliptic.P384(),
+		ALG_ES512: elliptic.P521(),
+	}
+
+	for alg, curve := range curves {
+		privKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal("GenerateKey: ", err)
+		}
+
+		testSignAndVerify(t, alg, privKey, &privKey.PublicKey)
+	}
+}
+
+func TestSign_ECDSA_WrongCurve(t *testing.T) {
+	privKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	if _, err := Sign(signTestPayload, ALG_ES256, privKey); err == nil {
+		t.Fatal("Signed ES256 with a P-384 key")
+	}
+}
+
+func TestVerify_ECDSA_OffCurveKey(t *testing.T) {
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	jws, err := Sign(signTestPayload, ALG_ES256, privKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	offCurve := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     privKey.X,
+		Y:     new(big.Int).Add(privKey.Y, big.NewInt(1)),
+	}
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(offCurve)); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("Expected ErrInvalidKey. Got %v", err)
+	}
+
+	missing := &ecdsa.PublicKey{Curve: elliptic.P256()}
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(missing)); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("Expected ErrInvalidKey. Got %v", err)
+	}
+}
+
+func TestSign_NONE(t *testing.T) {
+	if _, err := Sign(signTestPayload, ALG_NONE, []byte("secret")); err == nil {
+		t.Fatal("Created plaintext JWS without NoneKey")
+	}
+
+	requireNoneAlgorithm(t)
+
+	testSignAndVerify(t, ALG_NONE, NoneKey, NoneKey)
+}
+
+func TestSign_KeyTypeMismatch(t *testing.T) {
+	if _, err := Sign(signTestPayload, ALG_RS256, []byte("secret")); err == nil {
+		t.Fatal("Signed RS256 with a symmetric key")
+	}
+}
+
+// every corruption of a signature must be rejected, without panicking
+func TestVerify_TamperedSignatures(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	hmacKey := []byte("tamper-secret")
+
+	keys := map[Algorithm][2]interface{}{
+		ALG_HS256: {hmacKey, hmacKey},
+		ALG_HS512: {hmacKey, hmacKey},
+		ALG_RS256: {rsaKey, &rsaKey.PublicKey},
+		ALG_PS256: {rsaKey, &rsaKey.PublicKey},
+		ALG_ES256: {ecKey, &ecKey.PublicKey},
+	}
+
+	for alg, pair := range keys {
+		jws, err := Sign(signTestPayload, alg, pair[0])
+		if err != nil {
+			t.Fatal("Sign: ", err)
+		}
+		header, payload, signature, err := splitTestJWS(jws)
+		if err != nil {
+			t.Fatal("Split: ", err)
+		}
+		kp := ProviderFromKey(pair[1])
+		verify := func(sig []byte) error {
+			_, err := VerifyAndDecode(header+"."+payload+"."+safeEncode(sig), kp)
+			return err
+		}
+
+		for i := range signature {
+			tampered := append([]byte(nil), signature...)
+			tampered[i] ^= 0x01
+			if verify(tampered) == nil {
+				t.Fatalf("%s: verified with byte %d modified", alg, i)
+			}
+		}
+
+		variants := [][]byte{
+			nil,
+			signature[:len(signature)-1],
+			append(append([]byte(nil), signature...), 0),
+			make([]byte, len(signature)),
+		}
+		for i, sig := range variants {
+			if verify(sig) == nil {
+				t.Fatalf("%s: verified tampered signature %d", alg, i)
+			}
+		}
+	}
+}
+
+func TestVerifyWithKey(t *testing.T) {
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	jws, err := Sign(signTestPayload, ALG_ES256, privKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if err := VerifyWithKey(jws, &privKey.PublicKey); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if err := VerifyWithKey(jws, &privKey.PublicKey, WithAllowedAlgorithms(ALG_ES384)); !errors.Is(err, ErrAlgorithmDenied) {
+		t.Fatalf("Expected ErrAlgorithmDenied. Got %v", err)
+	}
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	if err := VerifyWithKey(jws, &other.PublicKey); err == nil {
+		t.Fatal("Verified with the wrong key")
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sign a URL so it is only valid until ttl from now, for CDN style
+// links. An exp parameter holding the expiry in Unix seconds is
+// appended, then a sig parameter holding a detached JWS (RFC 7515
+// Appendix F) over the whole URL up to that point. The URL must not
+// have a fragment or already carry exp or sig parameters.
+func SignURL(rawURL string, key crypto.PrivateKey, alg Algorithm, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		return "", errors.New("Signed URL TTL must be positive")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("Invalid URL: %v", err)
+	}
+	if u.Fragment != "" || strings.HasSuffix(rawURL, "#") {
+		return "", errors.New("Cannot sign a URL with a fragment")
+	}
+	query := u.Query()
+	if query.Has("exp") || query.Has("sig") {
+		return "", errors.New("URL already has exp or sig parameters")
+	}
+
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	signed := rawURL + sep + "exp=" + strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+
+	jws, err := Sign([]byte(signed), alg, key)
+	if err != nil {
+		return "", err
+	}
+
+	// detach the payload; the verifier takes it from the URL
+	parts := strings.Split(jws, ".")
+	return signed + "&sig=" + parts[0] + ".." + parts[2], nil
+}
+
+// Verify a URL created by SignURL, failing with ErrTokenExpired once
+// its exp parameter has passed. WithClock and WithClockSkew apply to
+// the expiry check.
+func VerifySignedURL(rawURL string, kp KeyProvider, opts ...VerifyOption) error {
+	// the signature covers everything before the final sig parameter
+	index := strings.LastIndex(rawURL, "&sig=")
+	if index < 0 {
+		return errors.New("URL is not signed")
+	}
+	signed, signature := rawURL[:index], rawURL[index+len("&sig="):]
+
+	parts := strings.Split(signature, ".")
+	if len(parts) != 3 || parts[1] != "" {
+		return errors.New("Malformed URL signature")
+	}
+
+	u, err := url.Parse(signed)
+	if err != nil {
+		return fmt.Errorf("Invalid URL: %v", err)
+	}
+	exp, err := strconv.ParseInt(u.Query().Get("exp"), 10, 64)
+	if err != nil {
+		return errors.New("Signed URL has no valid exp parameter")
+	}
+
+	_, _, err = VerifyFromParts(parts[0], safeEncode([]byte(signed)), parts[2], kp, opts...)
+	if err != nil {
+		return err
+	}
+
+	config := newVerifyConfig(opts)
+	clock := config.clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	if !clock.Now().Before(time.Unix(exp, 0).Add(config.clockSkew)) {
+		return ErrTokenExpired
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignURL(t *testing.T) {
+	key := []byte("url-secret")
+	kp := ProviderFromKey(key)
+
+	for _, rawURL := range []string{"https://cdn.example.com/file.mp4", "https://cdn.example.com/file.mp4?quality=hd&b=1"} {
+		signed, err := SignURL(rawURL, key, ALG_HS256, time.Hour)
+		if err != nil {
+			t.Fatalf("%s: SignURL: %v", rawURL, err)
+		}
+		if !strings.HasPrefix(signed, rawURL) {
+			t.Fatalf("%s: signed URL %s does not extend the original", rawURL, signed)
+		}
+
+		u, err := url.Parse(signed)
+		if err != nil {
+			t.Fatal("Parse: ", err)
+		}
+		if u.Query().Get("exp") == "" || u.Query().Get("sig") == "" {
+			t.Fatalf("%s: missing parameters in %s", rawURL, signed)
+		}
+
+		if err := VerifySignedURL(signed, kp); err != nil {
+			t.Fatalf("%s: VerifySignedURL: %v", rawURL, err)
+		}
+
+		// expired once the clock passes exp
+		late := WithClock(FixedClock(time.Now().Add(2 * time.Hour)))
+		if err := VerifySignedURL(signed, kp, late); !errors.Is(err, ErrTokenExpired) {
+			t.Fatalf("%s: expected ErrTokenExpired. Got %v", rawURL, err)
+		}
+
+		// the path, query and expiry are all covered
+		index := strings.Index(signed, "&sig=")
+		tampered := []string{
+			strings.Replace(signed, "file.mp4", "other.mp4", 1),
+			strings.Replace(signed, "exp=", "exp=9", 1),
+			signed[:index] + "&admin=1" + signed[index:],
+			signed + "x",
+			signed[:index],
+		}
+		for _, bad := range tampered {
+			if err := VerifySignedURL(bad, kp); err == nil {
+				t.Fatalf("Verified tampered URL %s", bad)
+			}
+		}
+	}
+
+	for _, bad := range []string{"https://example.com/#frag", "https://example.com/?sig=1", "https://example.com/?exp=1", "://bad"} {
+		if _, err := SignURL(bad, key, ALG_HS256, time.Hour); err == nil {
+			t.Fatalf("Signed %s", bad)
+		}
+	}
+	if _, err := SignURL("https://example.com/", key, ALG_HS256, 0); err == nil {
+		t.Fatal("Signed a URL without a TTL")
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// Sign a payload using an opaque crypto.Signer, such as a key held in
+// an HSM or cloud KMS. The hash and signer options are derived from
+// header.Alg; opts may be given to override them (e.g. to change the
+// PSS salt length) but must use the algorithm's hash. HMAC algorithms
+// cannot be used with a crypto.Signer.
+func SignWithSigner(payload []byte, header Header, signer crypto.Signer, opts crypto.SignerOpts) (jws string, err error) {
+	data, err := json.Marshal(header)
+	if err != nil {
+		err = fmt.Errorf("Failed to encode header: %v", err)
+		return
+	}
+
+	signingInput := safeEncode(data) + "." + safeEncode(payload)
+
+	signature, err := signerSignature(signer, header.Alg, signingInput, opts, false)
+	if err != nil {
+		return
+	}
+
+	jws = signingInput + "." + safeEncode(signature)
+	return
+}
+
+// produce a JWS signature over signingInput using a crypto.Signer
+func signerSignature(signer crypto.Signer, alg Algorithm, signingInput string, opts crypto.SignerOpts, lowS bool) ([]byte, error) {
+	pub := signer.Public()
+
+	var defaultOpts crypto.SignerOpts
+	switch alg {
+	case ALG_RS256, ALG_RS384, ALG_RS512:
+		if _, ok := pub.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("Expected RSA signer. Got %T", pub)
+		}
+		defaultOpts = hashForAlgorithm(alg)
+
+	case ALG_PS256, ALG_PS384, ALG_PS512:
+		if _, ok := pub.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("Expected RSA signer. Got %T", pub)
+		}
+		defaultOpts = &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthEqualsHash,
+			Hash:       hashForAlgorithm(alg),
+		}
+
+	case ALG_ES256, ALG_ES384, ALG_ES512, ALG_ES256K:
+		if _, ok := pub.(*ecdsa.PublicKey); !ok {
+			return nil, fmt.Errorf("Expected ECDSA signer. Got %T", pub)
+		}
+		defaultOpts = hashForAlgorithm(alg)
+
+	case ALG_EdDSA:
+		if _, ok := pub.(ed25519.PublicKey); !ok {
+			return nil, fmt.Errorf("Expected Ed25519 signer. Got %T", pub)
+		}
+
+		// Ed25519 signs the message itself, not a digest
+		if opts == nil {
+			opts = crypto.Hash(0)
+		}
+		if opts.HashFunc() != crypto.Hash(0) {
+			return nil, errors.New("Ed25519 signer options must not specify a hash")
+		}
+
+		signature, err := signer.Sign(rand.Reader, []byte(signingInput), opts)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to sign JWS: %v", err)
+		}
+		return signature, nil
+
+	case ALG_NONE, ALG_HS256, ALG_HS384, ALG_HS512:
+		return nil, fmt.Errorf("Algorithm %s cannot be used with a crypto.Signer", alg)
+
+	default:
+		return nil, fmt.Errorf("Unknown signature algorithm: %s", alg)
+	}
+
+	if opts == nil {
+		opts = defaultOpts
+	}
+	if opts.HashFunc() != defaultOpts.HashFunc() {
+		return nil, fmt.Errorf("Signer options hash does not match %s", alg)
+	}
+
+	hs := opts.HashFunc().New()
+	io.WriteString(hs, signingInput)
+
+	signature, err := signer.Sign(rand.Reader, hs.Sum(nil), opts)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to sign JWS: %v", err)
+	}
+
+	if ecPub, ok := pub.(*ecdsa.PublicKey); ok {
+		return ecdsaSignatureFromASN1(alg, ecPub, signature, lowS)
+	}
+
+	return signature, nil
+}
+
+// crypto.Signer produces ASN.1 DER encoded ECDSA signatures, while JWS
+// uses fixed-size R || S
+func ecdsaSignatureFromASN1(alg Algorithm, pub *ecdsa.PublicKey, der []byte, lowS bool) ([]byte, error) {
+	size, err := ecdsaSignatureSize(alg, pub.Curve)
+	if err != nil {
+		return nil, err
+	}
+
+	var sig struct {
+		R, S *big.Int
+	}
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil || len(rest) != 0 {
+		return nil, errors.New("Signer returned a malformed ECDSA signature")
+	}
+	if sig.R.Sign() <= 0 || sig.S.Sign() <= 0 || sig.R.BitLen() > 8*size || sig.S.BitLen() > 8*size {
+		return nil, errors.New("Signer returned a malformed ECDSA signature")
+	}
+
+	return encodeECDSASignature(alg, pub.Curve, size, sig.R, sig.S, lowS), nil
+}
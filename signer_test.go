@@ -0,0 +1,184 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"testing"
+)
+
+// hides the concrete key type, as an HSM backed signer would
+type opaqueSigner struct {
+	signer crypto.Signer
+}
+
+func (s opaqueSigner) Public() crypto.PublicKey {
+	return s.signer.Public()
+}
+
+func (s opaqueSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.signer.Sign(rand, digest, opts)
+}
+
+func TestSignWithSigner(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	signers := map[Algorithm]crypto.Signer{
+		ALG_RS384: rsaKey,
+		ALG_PS512: rsaKey,
+		ALG_ES512: ecKey,
+		ALG_EdDSA: edKey,
+	}
+
+	for alg, signer := range signers {
+		jws, err := SignWithSigner(signTestPayload, Header{Alg: alg}, opaqueSigner{signer}, nil)
+		if err != nil {
+			t.Fatalf("SignWithSigner %s: %v", alg, err)
+		}
+
+		data, err := VerifyAndDecode(jws, ProviderFromKey(signer.Public()))
+		if err != nil {
+			t.Fatalf("Verify %s: %v", alg, err)
+		}
+		if string(data) != string(signTestPayload) {
+			t.Fatalf("Unexpected payload: %v", data)
+		}
+	}
+}
+
+func TestSignWithSigner_PSSOverride(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto, Hash: crypto.SHA256}
+	jws, err := SignWithSigner(signTestPayload, Header{Alg: ALG_PS256}, rsaKey, opts)
+	if err != nil {
+		t.Fatal("SignWithSigner: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(&rsaKey.PublicKey)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	// the override must still use the algorithm's hash
+	opts.Hash = crypto.SHA512
+	if _, err := SignWithSigner(signTestPayload, Header{Alg: ALG_PS256}, rsaKey, opts); err == nil {
+		t.Fatal("Accepted signer options with the wrong hash")
+	}
+}
+
+func TestSignWithSigner_Mismatch(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	for _, alg := range []Algorithm{ALG_HS256, ALG_RS256, ALG_ES384, ALG_NONE, Algorithm("bogus")} {
+		if _, err := SignWithSigner(signTestPayload, Header{Alg: alg}, ecKey, nil); err == nil {
+			t.Fatalf("Signed %s with a P-256 signer", alg)
+		}
+	}
+}
+
+// records how many signatures were requested
+type countingSigner struct {
+	opaqueSigner
+	calls int
+}
+
+func (s *countingSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.calls++
+	return s.opaqueSigner.Sign(rand, digest, opts)
+}
+
+func TestSignWithHeader_Signer(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	signers := map[Algorithm]crypto.Signer{
+		ALG_RS256: rsaKey,
+		ALG_PS384: rsaKey,
+		ALG_ES256: ecKey,
+		ALG_EdDSA: edKey,
+	}
+
+	for alg, key := range signers {
+		signer := &countingSigner{opaqueSigner: opaqueSigner{key}}
+		testSignAndVerify(t, alg, signer, key.Public())
+		if signer.calls != 1 {
+			t.Fatalf("%s: expected one call to the signer. Got %d", alg, signer.calls)
+		}
+	}
+
+	// sign options still apply
+	for i := 0; i < 16; i++ {
+		jws, err := Sign(signTestPayload, ALG_ES256, opaqueSigner{ecKey}, WithLowS())
+		if err != nil {
+			t.Fatal("Sign: ", err)
+		}
+		if _, err := VerifyAndDecode(jws, ProviderFromKey(&ecKey.PublicKey), WithLowSRequired()); err != nil {
+			t.Fatal("Verify: ", err)
+		}
+	}
+	jws, err := Sign(signTestPayload, ALG_PS256, opaqueSigner{rsaKey}, WithPSSOptions(PSSOptions{SaltLength: 20}))
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(&rsaKey.PublicKey)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	if _, err := Sign(signTestPayload, ALG_HS256, opaqueSigner{ecKey}); err == nil {
+		t.Fatal("Signed HS256 with a crypto.Signer")
+	}
+}
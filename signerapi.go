@@ -0,0 +1,79 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"fmt"
+)
+
+// Signs payloads with a key chosen when the Signer is created, so code
+// that issues tokens need not handle keys, and can be tested with a
+// stub (see the gojwstest package)
+type Signer interface {
+	Sign(payload []byte) (string, error)
+	SignWithHeader(payload []byte, h Header) (string, error)
+}
+
+// Create a Signer for alg using key, which may be any key accepted by
+// the package-level SignWithHeader. opts apply to every signature.
+func NewSigner(alg Algorithm, key crypto.PrivateKey, opts ...SignOption) (Signer, error) {
+	check := crypto.PublicKey(key)
+	if signer, ok := hardwareSigner(key); ok {
+		check = signer.Public()
+	}
+	if !ValidKeyForAlgorithm(check, alg) {
+		return nil, fmt.Errorf("Key of type %T cannot sign %s", key, alg)
+	}
+
+	return &keySigner{
+		alg:  alg,
+		key:  key,
+		opts: append([]SignOption(nil), opts...),
+	}, nil
+}
+
+type keySigner struct {
+	alg  Algorithm
+	key  crypto.PrivateKey
+	opts []SignOption
+}
+
+func (s *keySigner) Sign(payload []byte) (string, error) {
+	return SignWithHeader(payload, Header{Alg: s.alg}, s.key, s.opts...)
+}
+
+// Sign using h, whose algorithm defaults to the Signer's. Naming a
+// different algorithm is an error.
+func (s *keySigner) SignWithHeader(payload []byte, h Header) (string, error) {
+	if h.Alg == "" {
+		h.Alg = s.alg
+	}
+	if h.Alg != s.alg {
+		return "", fmt.Errorf("Signer for %s cannot sign %s", s.alg, h.Alg)
+	}
+
+	return SignWithHeader(payload, h, s.key, s.opts...)
+}
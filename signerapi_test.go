@@ -0,0 +1,81 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+)
+
+func TestNewSigner(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	kp := ProviderFromKey(&ecKey.PublicKey)
+
+	for _, key := range []interface{}{ecKey, opaqueSigner{ecKey}} {
+		signer, err := NewSigner(ALG_ES256, key, WithLowS())
+		if err != nil {
+			t.Fatalf("NewSigner %T: %v", key, err)
+		}
+
+		jws, err := signer.Sign(signTestPayload)
+		if err != nil {
+			t.Fatal("Sign: ", err)
+		}
+		if _, err := VerifyAndDecode(jws, kp, WithLowSRequired()); err != nil {
+			t.Fatal("Verify: ", err)
+		}
+
+		jws, err = signer.SignWithHeader(signTestPayload, Header{Kid: "k1"})
+		if err != nil {
+			t.Fatal("SignWithHeader: ", err)
+		}
+		header, _, err := VerifyAndDecodeWithHeader(jws, kp)
+		if err != nil {
+			t.Fatal("Verify: ", err)
+		}
+		if header.Alg != ALG_ES256 || header.Kid != "k1" {
+			t.Fatalf("Unexpected header %+v", header)
+		}
+
+		if _, err := signer.SignWithHeader(signTestPayload, Header{Alg: ALG_ES384}); err == nil {
+			t.Fatal("Signed with a different algorithm")
+		}
+	}
+
+	if _, err := NewSigner(ALG_ES384, ecKey); err == nil {
+		t.Fatal("Created a signer with the wrong curve")
+	}
+	if _, err := NewSigner(ALG_HS256, ecKey); err == nil {
+		t.Fatal("Created an HMAC signer with an ECDSA key")
+	}
+	if _, err := NewSigner(ALG_HS256, []byte("secret")); err != nil {
+		t.Fatal("NewSigner: ", err)
+	}
+}
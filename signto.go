@@ -0,0 +1,171 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bufio"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Sign a payload using the supplied header, writing the compact JWS to
+// w. For algorithms that sign a digest (HS*, RS*, ES*, PS*) the payload
+// is base64url encoded concurrently with hashing and writing the
+// output, so large payloads are never held encoded in memory. Other
+// algorithms are signed with SignWithHeader. On error, w may have
+// received part of the token.
+func SignTo(w io.Writer, payload []byte, header Header, key crypto.PrivateKey, opts ...SignOption) error {
+	if needsSigningInput(header.Alg, key) {
+		jws, err := SignWithHeader(payload, header, key, opts...)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, jws)
+		return err
+	}
+
+	return signStreamTo(w, header, key, opts, func(out io.Writer, encodedHeader string) error {
+		return signAsync(out, encodedHeader, payload)
+	})
+}
+
+// Sign the payload read from r, writing the compact JWS to w. For
+// algorithms that sign a digest (HS*, RS*, ES*, PS*) the payload is
+// read, base64url encoded, hashed and written as it streams, so it is
+// never held in memory. Other algorithms need the whole signing input
+// and read the payload fully before signing. On error, w may have
+// received part of the token.
+func SignReaderTo(w io.Writer, r io.Reader, header Header, key crypto.PrivateKey, opts ...SignOption) error {
+	if needsSigningInput(header.Alg, key) {
+		payload, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("Failed to read payload: %v", err)
+		}
+		return SignTo(w, payload, header, key, opts...)
+	}
+
+	return signStreamTo(w, header, key, opts, func(out io.Writer, encodedHeader string) error {
+		buffered := bufio.NewWriterSize(out, signAsyncChunkSize)
+		_, err := io.WriteString(buffered, encodedHeader+".")
+		if err != nil {
+			return err
+		}
+
+		enc := NewBase64URLEncoder(buffered)
+		if _, err := io.Copy(enc, r); err != nil {
+			return err
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+		return buffered.Flush()
+	})
+}
+
+// EdDSA needs the whole signing input, and hardware signers are handed
+// it as a string
+func needsSigningInput(alg Algorithm, key crypto.PrivateKey) bool {
+	_, ok := hardwareSigner(key)
+	return ok || alg == ALG_NONE || alg == ALG_EdDSA
+}
+
+// write a token signed with a digest algorithm to w. writeInput writes
+// the signing input (the encoded header, '.' and the encoded payload)
+// to out, which both hashes it and passes it on to w.
+func signStreamTo(w io.Writer, header Header, key crypto.PrivateKey, opts []SignOption, writeInput func(out io.Writer, encodedHeader string) error) error {
+	var config signConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	hs, signDigest, err := digestSigner(header.Alg, key, &config)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("Failed to encode header: %v", err)
+	}
+
+	err = writeInput(io.MultiWriter(w, hs), safeEncode(data))
+	if err != nil {
+		return err
+	}
+
+	signature, err := signDigest(hs.Sum(nil))
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "."+safeEncode(signature))
+	if err != nil {
+		return err
+	}
+
+	if config.metrics != nil {
+		config.metrics.TokenSigned(header.Alg)
+	}
+	return nil
+}
+
+// size of each encoded block passed from the encoder to the writer
+const signAsyncChunkSize = 32 * 1024
+
+// write the signing input to w, encoding large payloads on another
+// goroutine while w consumes them
+func signAsync(w io.Writer, encodedHeader string, payload []byte) error {
+	// not worth a goroutine for a single chunk
+	if len(payload) < signAsyncChunkSize {
+		_, err := io.WriteString(w, encodedHeader+"."+safeEncode(payload))
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		// hand over large chunks; the encoder writes 1 KB at a time
+		buffered := bufio.NewWriterSize(pw, signAsyncChunkSize)
+		enc := NewBase64URLEncoder(buffered)
+		_, err := enc.Write(payload)
+		if err == nil {
+			err = enc.Close()
+		}
+		if err == nil {
+			err = buffered.Flush()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	_, err := io.WriteString(w, encodedHeader+".")
+	if err == nil {
+		_, err = io.Copy(w, pr)
+	}
+
+	// unblock the encoder if w failed
+	pr.CloseWithError(err)
+	return err
+}
@@ -0,0 +1,164 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestSignTo(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	keys := map[Algorithm]interface{}{
+		ALG_HS256: []byte("signto-secret"),
+		ALG_RS256: rsaKey,
+		ALG_PS384: rsaKey,
+		ALG_ES256: ecKey,
+		ALG_EdDSA: edKey,
+	}
+
+	// large enough to span several pipe writes
+	payload := bytes.Repeat([]byte("0123456789"), 100*1024)
+	for alg, key := range keys {
+		var buf bytes.Buffer
+		if err := SignTo(&buf, payload, Header{Alg: alg, Kid: "k1"}, key); err != nil {
+			t.Fatalf("%s: SignTo: %v", alg, err)
+		}
+
+		header, data, err := VerifyAndDecodeWithHeader(buf.String(), ProviderFromKey(key))
+		if err != nil {
+			t.Fatalf("%s: Verify: %v", alg, err)
+		}
+		if header.Kid != "k1" || !bytes.Equal(data, payload) {
+			t.Fatalf("%s: unexpected round trip", alg)
+		}
+	}
+
+	// HMAC is deterministic, so the output matches SignWithHeader exactly
+	expected, err := SignWithHeader(payload, Header{Alg: ALG_HS256}, keys[ALG_HS256])
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	var buf bytes.Buffer
+	if err := SignTo(&buf, payload, Header{Alg: ALG_HS256}, keys[ALG_HS256]); err != nil {
+		t.Fatal("SignTo: ", err)
+	}
+	if buf.String() != expected {
+		t.Fatal("SignTo output differs from SignWithHeader")
+	}
+
+	if err := SignTo(&buf, payload, Header{Alg: ALG_RS256}, ecKey); err == nil {
+		t.Fatal("Signed with a mismatched key")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestSignTo_WriteError(t *testing.T) {
+	payload := make([]byte, 256*1024)
+	if err := SignTo(failingWriter{}, payload, Header{Alg: ALG_HS256}, []byte("secret")); err == nil {
+		t.Fatal("Expected the write error")
+	}
+}
+
+func TestSignReaderTo(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	keys := map[Algorithm]interface{}{
+		ALG_HS256: []byte("signto-secret"),
+		ALG_ES256: ecKey,
+		ALG_EdDSA: edKey,
+	}
+
+	payload := bytes.Repeat([]byte("0123456789"), 100*1024)
+	for alg, key := range keys {
+		var buf bytes.Buffer
+		if err := SignReaderTo(&buf, bytes.NewReader(payload), Header{Alg: alg, Kid: "k1"}, key); err != nil {
+			t.Fatalf("%s: SignReaderTo: %v", alg, err)
+		}
+
+		header, data, err := VerifyAndDecodeWithHeader(buf.String(), ProviderFromKey(key))
+		if err != nil {
+			t.Fatalf("%s: Verify: %v", alg, err)
+		}
+		if header.Kid != "k1" || !bytes.Equal(data, payload) {
+			t.Fatalf("%s: unexpected round trip", alg)
+		}
+	}
+
+	// the streamed output matches SignWithHeader exactly
+	expected, err := SignWithHeader(payload, Header{Alg: ALG_HS256}, keys[ALG_HS256])
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	var buf bytes.Buffer
+	if err := SignReaderTo(&buf, bytes.NewReader(payload), Header{Alg: ALG_HS256}, keys[ALG_HS256]); err != nil {
+		t.Fatal("SignReaderTo: ", err)
+	}
+	if buf.String() != expected {
+		t.Fatal("SignReaderTo output differs from SignWithHeader")
+	}
+
+	// read errors are reported
+	r := io.MultiReader(bytes.NewReader(payload), failingReader{})
+	if err := SignReaderTo(io.Discard, r, Header{Alg: ALG_HS256}, keys[ALG_HS256]); err == nil {
+		t.Fatal("Expected the read error")
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read failed")
+}
@@ -0,0 +1,149 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"crypto/subtle"
+	"sync"
+	"time"
+)
+
+// Wrap a SignatureVerifier with an LRU cache of verified tokens, so a
+// token presented repeatedly (e.g. a bearer token at an API gateway)
+// is only verified once. Entries expire after maxAge or at the token's
+// exp claim, whichever is sooner. Failures are not cached, since they
+// may be transient (e.g. a key set that has not yet been refreshed).
+//
+// A capacity or maxAge of zero or less disables caching.
+func NewTokenCache(capacity int, maxAge time.Duration, inner SignatureVerifier) SignatureVerifier {
+	if capacity <= 0 || maxAge <= 0 {
+		return inner
+	}
+
+	return &tokenCache{
+		inner:    inner,
+		capacity: capacity,
+		maxAge:   maxAge,
+		entries:  make(map[[sha256.Size]byte]*list.Element),
+		lru:      list.New(),
+		now:      time.Now,
+	}
+}
+
+type tokenCache struct {
+	inner    SignatureVerifier
+	capacity int
+	maxAge   time.Duration
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	lru     *list.List // most recently used at the front
+	now     func() time.Time
+}
+
+type tokenCacheEntry struct {
+	digest  [sha256.Size]byte
+	token   []byte
+	header  Header
+	payload []byte
+	expiry  time.Time
+}
+
+func (c *tokenCache) VerifyAndDecode(token string) (Header, []byte, error) {
+	digest := sha256.Sum256([]byte(token))
+	if header, payload, ok := c.lookup(digest, token); ok {
+		return header, payload, nil
+	}
+
+	header, payload, err := c.inner.VerifyAndDecode(token)
+	if err != nil {
+		return header, payload, err
+	}
+
+	c.store(digest, token, header, payload)
+	return header, payload, nil
+}
+
+func (c *tokenCache) lookup(digest [sha256.Size]byte, token string) (Header, []byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[digest]
+	if !ok {
+		return Header{}, nil, false
+	}
+
+	// the digest only indexes the cache; the token itself must match
+	entry := elem.Value.(*tokenCacheEntry)
+	if subtle.ConstantTimeCompare(entry.token, []byte(token)) != 1 {
+		return Header{}, nil, false
+	}
+
+	if !c.now().Before(entry.expiry) {
+		c.lru.Remove(elem)
+		delete(c.entries, digest)
+		return Header{}, nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return entry.header, append([]byte(nil), entry.payload...), true
+}
+
+func (c *tokenCache) store(digest [sha256.Size]byte, token string, header Header, payload []byte) {
+	now := c.now()
+	expiry := now.Add(c.maxAge)
+	if claims, err := DecodeClaims[StandardClaims](payload); err == nil && claims.ExpiresAt != nil {
+		if claims.ExpiresAt.Before(expiry) {
+			expiry = claims.ExpiresAt.Time
+		}
+	}
+	if !now.Before(expiry) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[digest]; ok {
+		c.lru.Remove(elem)
+		delete(c.entries, digest)
+	}
+
+	for c.lru.Len() >= c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*tokenCacheEntry).digest)
+	}
+
+	c.entries[digest] = c.lru.PushFront(&tokenCacheEntry{
+		digest:  digest,
+		token:   []byte(token),
+		header:  header,
+		payload: append([]byte(nil), payload...),
+		expiry:  expiry,
+	})
+}
@@ -0,0 +1,158 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// SignatureVerifier that counts calls and decodes without verifying
+type countingVerifier struct {
+	calls int
+	err   error
+}
+
+func (v *countingVerifier) VerifyAndDecode(token string) (Header, []byte, error) {
+	v.calls++
+	if v.err != nil {
+		return Header{}, nil, v.err
+	}
+	return Header{Alg: ALG_HS256, Kid: token}, []byte(token), nil
+}
+
+func TestTokenCache(t *testing.T) {
+	inner := &countingVerifier{}
+	cache := NewTokenCache(2, time.Minute, inner).(*tokenCache)
+
+	now := time.Unix(1300819380, 0)
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		header, payload, err := cache.VerifyAndDecode("a")
+		if err != nil {
+			t.Fatal("VerifyAndDecode: ", err)
+		}
+		if header.Kid != "a" || string(payload) != "a" {
+			t.Fatalf("Unexpected result %+v %q", header, payload)
+		}
+	}
+	if inner.calls != 1 {
+		t.Fatalf("Expected 1 verification. Got %d", inner.calls)
+	}
+
+	// "b" and "c" push "a" out
+	cache.VerifyAndDecode("b")
+	cache.VerifyAndDecode("c")
+	cache.VerifyAndDecode("a")
+	if inner.calls != 4 {
+		t.Fatalf("Expected 4 verifications. Got %d", inner.calls)
+	}
+
+	// "a" was just refreshed; "b" is then the least recently used
+	cache.VerifyAndDecode("c")
+	cache.VerifyAndDecode("a")
+	if inner.calls != 4 {
+		t.Fatalf("Expected 4 verifications. Got %d", inner.calls)
+	}
+
+	now = now.Add(time.Minute)
+	cache.VerifyAndDecode("a")
+	if inner.calls != 5 {
+		t.Fatalf("Expected expired entry to be verified again. Got %d verifications", inner.calls)
+	}
+}
+
+func TestTokenCache_Exp(t *testing.T) {
+	inner := &countingVerifier{}
+	cache := NewTokenCache(10, time.Hour, inner).(*tokenCache)
+
+	now := time.Unix(1300819380, 0)
+	cache.now = func() time.Time { return now }
+
+	token := fmt.Sprintf(`{"exp":%d}`, now.Add(time.Minute).Unix())
+	cache.VerifyAndDecode(token)
+	cache.VerifyAndDecode(token)
+	if inner.calls != 1 {
+		t.Fatalf("Expected 1 verification. Got %d", inner.calls)
+	}
+
+	// exp comes before maxAge
+	now = now.Add(time.Minute)
+	cache.VerifyAndDecode(token)
+	if inner.calls != 2 {
+		t.Fatalf("Expected 2 verifications. Got %d", inner.calls)
+	}
+
+	// already expired tokens are not cached
+	cache.VerifyAndDecode(token)
+	if inner.calls != 3 {
+		t.Fatalf("Expected 3 verifications. Got %d", inner.calls)
+	}
+}
+
+func TestTokenCache_Errors(t *testing.T) {
+	errInvalid := errors.New("invalid")
+	inner := &countingVerifier{err: errInvalid}
+	cache := NewTokenCache(10, time.Hour, inner)
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := cache.VerifyAndDecode("bad"); err != errInvalid {
+			t.Fatalf("Expected inner error. Got %v", err)
+		}
+	}
+	if inner.calls != 2 {
+		t.Fatalf("Expected failures to be retried. Got %d verifications", inner.calls)
+	}
+
+	if NewTokenCache(0, time.Hour, inner) != SignatureVerifier(inner) {
+		t.Fatal("Zero capacity did not disable caching")
+	}
+}
+
+func TestTokenCache_Verifier(t *testing.T) {
+	key := []byte("cache-secret")
+	cache := NewTokenCache(10, time.Minute, NewVerifier(ProviderFromKey(key)))
+
+	jws, err := Sign([]byte(`{"iss":"joe"}`), ALG_HS256, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	for i := 0; i < 2; i++ {
+		_, payload, err := cache.VerifyAndDecode(jws)
+		if err != nil {
+			t.Fatal("VerifyAndDecode: ", err)
+		}
+		if string(payload) != `{"iss":"joe"}` {
+			t.Fatalf("Unexpected payload %q", payload)
+		}
+	}
+
+	if _, _, err := cache.VerifyAndDecode(jws[:len(jws)-2]); err == nil {
+		t.Fatal("Verified a truncated token")
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+// Verifies tokens, so code that consumes them can be tested with a
+// stub in place of real keys (see the gojwstest package)
+type SignatureVerifier interface {
+	VerifyAndDecode(token string) (Header, []byte, error)
+}
+
+// Create a SignatureVerifier using kp and opts for every token
+func NewVerifier(kp KeyProvider, opts ...VerifyOption) SignatureVerifier {
+	return &verifier{
+		kp:   kp,
+		opts: append([]VerifyOption(nil), opts...),
+	}
+}
+
+type verifier struct {
+	kp   KeyProvider
+	opts []VerifyOption
+}
+
+func (v *verifier) VerifyAndDecode(token string) (Header, []byte, error) {
+	return VerifyAndDecodeWithHeader(token, v.kp, v.opts...)
+}
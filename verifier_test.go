@@ -0,0 +1,54 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestNewVerifier(t *testing.T) {
+	key := []byte("verifier-secret")
+	jws, err := SignWithHeader(signTestPayload, Header{Alg: ALG_HS256, Kid: "k1"}, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	var v SignatureVerifier = NewVerifier(ProviderFromKey(key))
+	header, payload, err := v.VerifyAndDecode(jws)
+	if err != nil {
+		t.Fatal("VerifyAndDecode: ", err)
+	}
+	if header.Kid != "k1" || !bytes.Equal(payload, signTestPayload) {
+		t.Fatalf("Unexpected result %+v %q", header, payload)
+	}
+
+	// options apply to every token
+	v = NewVerifier(ProviderFromKey(key), WithDeniedAlgorithms(ALG_HS256))
+	if _, _, err := v.VerifyAndDecode(jws); !errors.Is(err, ErrAlgorithmDenied) {
+		t.Fatalf("Expected ErrAlgorithmDenied. Got %v", err)
+	}
+}
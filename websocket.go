@@ -0,0 +1,73 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// subprotocol prefix marking a token in Sec-WebSocket-Protocol
+const webSocketTokenPrefix = "jwt."
+
+// Extract a token offered as a "jwt."-prefixed subprotocol in the
+// Sec-WebSocket-Protocol header, for browser WebSocket clients that
+// can't set an Authorization header. The token may be URL encoded.
+func ExtractTokenFromWebSocket(r *http.Request) (string, error) {
+	for _, value := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(value, ",") {
+			protocol = strings.TrimSpace(protocol)
+			if !strings.HasPrefix(protocol, webSocketTokenPrefix) {
+				continue
+			}
+
+			token, err := url.PathUnescape(protocol[len(webSocketTokenPrefix):])
+			if err != nil {
+				return "", fmt.Errorf("Malformed WebSocket token: %v", err)
+			}
+			if token == "" {
+				return "", errors.New("Empty WebSocket token")
+			}
+			return token, nil
+		}
+	}
+
+	return "", errors.New("No token in Sec-WebSocket-Protocol")
+}
+
+// As Middleware, reading the token from the Sec-WebSocket-Protocol
+// header with ExtractTokenFromWebSocket. Tokens are verified, claims
+// included, by VerifyBearerToken. The next handler performs the
+// upgrade and chooses which subprotocol to accept.
+func WebSocketMiddleware(kp KeyProvider, opts ...VerifyOption) func(http.Handler) http.Handler {
+	extract := func(r *http.Request) (string, bool) {
+		token, err := ExtractTokenFromWebSocket(r)
+		return token, err == nil
+	}
+	return tokenMiddleware(extract, kp, opts)
+}
@@ -0,0 +1,108 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExtractTokenFromWebSocket(t *testing.T) {
+	tests := []struct {
+		protocols []string
+		token     string
+		ok        bool
+	}{
+		{[]string{"chat, jwt.aaa.bbb.ccc"}, "aaa.bbb.ccc", true},
+		{[]string{"chat", "jwt.aaa.bbb.ccc"}, "aaa.bbb.ccc", true},
+		{[]string{"jwt.aaa%2Ebbb.ccc"}, "aaa.bbb.ccc", true},
+		{[]string{"chat"}, "", false},
+		{nil, "", false},
+		{[]string{"jwt."}, "", false},
+		{[]string{"jwt.%zz"}, "", false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/ws", nil)
+		for _, protocol := range tt.protocols {
+			req.Header.Add("Sec-WebSocket-Protocol", protocol)
+		}
+
+		token, err := ExtractTokenFromWebSocket(req)
+		if tt.ok && (err != nil || token != tt.token) {
+			t.Fatalf("%q: expected %q. Got %q, %v", tt.protocols, tt.token, token, err)
+		}
+		if !tt.ok && err == nil {
+			t.Fatalf("%q: extracted %q", tt.protocols, token)
+		}
+	}
+}
+
+func TestWebSocketMiddleware(t *testing.T) {
+	key := []byte("websocket-secret")
+	token, err := SignWithHeader([]byte(`{"iss":"joe"}`), Header{Alg: ALG_HS256, Kid: "k1"}, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	var called bool
+	handler := WebSocketMiddleware(ProviderFromKey(key))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if header, ok := HeaderFromContext(r.Context()); !ok || header.Kid != "k1" {
+			t.Errorf("Unexpected header in context: %+v", header)
+		}
+	}))
+
+	// expired tokens are refused
+	expired, err := SignWithHeader(signTestPayload, Header{Alg: ALG_HS256, Kid: "k1"}, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	tests := []struct {
+		protocol string
+		status   int
+	}{
+		{"chat, jwt." + token, http.StatusOK},
+		{"jwt." + expired, http.StatusUnauthorized},
+		{"chat", http.StatusUnauthorized},
+		{"jwt." + token + "x", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		called = false
+		req := httptest.NewRequest("GET", "/ws", nil)
+		req.Header.Set("Sec-WebSocket-Protocol", tt.protocol)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Fatalf("%q: expected status %d. Got %d", tt.protocol, tt.status, rec.Code)
+		}
+		if called != (tt.status == http.StatusOK) {
+			t.Fatalf("%q: next handler called=%v", tt.protocol, called)
+		}
+	}
+}
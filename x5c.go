@@ -0,0 +1,167 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Parse and verify an x5c certificate chain (RFC 7515 Section 4.1.6),
+// returning the leaf certificate's public key. Each element is the
+// standard (not URL-safe) base64 encoding of a DER certificate, leaf
+// first. roots must be given: trusting the system roots would let any
+// web PKI certificate sign tokens. validateLeaf, if non-nil, checks the
+// identity of the verified leaf certificate.
+func verifyX5C(x5c []string, roots *x509.CertPool, validateLeaf func(leaf *x509.Certificate) error) (crypto.PublicKey, error) {
+	if roots == nil {
+		return nil, fmt.Errorf("%w: no trusted roots configured", ErrCertificateChainInvalid)
+	}
+
+	certs, err := parseX5C(x5c)
+	if err != nil {
+		return nil, err
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	// JWS signing certificates rarely carry a TLS extended key usage
+	_, err = certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCertificateChainInvalid, err)
+	}
+
+	if validateLeaf != nil {
+		if err := validateLeaf(certs[0]); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCertificateChainInvalid, err)
+		}
+	}
+
+	return certs[0].PublicKey, nil
+}
+
+// Create an x5c leaf validator accepting certificates valid for any of
+// the given DNS names, per their subject alternative names
+func MatchX5CLeafDNSName(names ...string) func(leaf *x509.Certificate) error {
+	return func(leaf *x509.Certificate) error {
+		for _, name := range names {
+			if leaf.VerifyHostname(name) == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("Leaf certificate %q is not valid for %q", leaf.Subject.CommonName, names)
+	}
+}
+
+// Encode a certificate chain, leaf first, as the JSON array carried in
+// the x5c header parameter
+func BuildX5CChain(certs []*x509.Certificate) (string, error) {
+	if len(certs) == 0 {
+		return "", errors.New("Empty certificate chain")
+	}
+	for i, cert := range certs {
+		if cert == nil || len(cert.Raw) == 0 {
+			return "", fmt.Errorf("Certificate %d has no DER encoding", i)
+		}
+	}
+
+	data, err := json.Marshal(encodeX5C(certs))
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode x5c: %v", err)
+	}
+	return string(data), nil
+}
+
+// Parse the JSON array form of an x5c header parameter, as produced by
+// BuildX5CChain. The chain is NOT verified.
+func ParseX5CChain(x5c string) ([]*x509.Certificate, error) {
+	var encoded []string
+	if err := json.Unmarshal([]byte(x5c), &encoded); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCertificateChainInvalid, err)
+	}
+	return parseX5C(encoded)
+}
+
+// RFC 7515 Section 4.1.6 uses standard, padded base64 for x5c
+func encodeX5C(certs []*x509.Certificate) []string {
+	x5c := make([]string, len(certs))
+	for i, cert := range certs {
+		x5c[i] = base64.StdEncoding.EncodeToString(cert.Raw)
+	}
+	return x5c
+}
+
+func parseX5C(x5c []string) ([]*x509.Certificate, error) {
+	if len(x5c) == 0 {
+		return nil, fmt.Errorf("%w: empty chain", ErrCertificateChainInvalid)
+	}
+
+	certs := make([]*x509.Certificate, len(x5c))
+	for i, encoded := range x5c {
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: certificate %d: %v", ErrCertificateChainInvalid, i, err)
+		}
+
+		certs[i], err = x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("%w: certificate %d: %v", ErrCertificateChainInvalid, i, err)
+		}
+	}
+	return certs, nil
+}
+
+// Create a provider for the public key in an X.509 certificate. If the
+// certificate restricts its key usage and does not allow digital
+// signatures, the provider is still returned, along with an error
+// wrapping ErrKeyUsageMismatch that callers may choose to ignore.
+func ProviderFromX509Certificate(cert *x509.Certificate) (KeyProvider, error) {
+	if cert == nil {
+		return nil, errors.New("No certificate")
+	}
+
+	key, err := NormalizeToPublicKey(cert.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	kp := ProviderFromKey(key)
+
+	// a zero KeyUsage means the certificate doesn't restrict it
+	if cert.KeyUsage != 0 && cert.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return kp, fmt.Errorf("%w: certificate %q does not allow digital signatures", ErrKeyUsageMismatch, cert.Subject.CommonName)
+	}
+	return kp, nil
+}
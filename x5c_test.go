@@ -0,0 +1,252 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// issue a certificate for a fresh P-256 key. A nil parent produces a
+// self-signed CA; other certificates are valid for name.example.
+func testCertificate(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	} else {
+		template.DNSNames = []string{name + ".example"}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal("CreateCertificate: ", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal("ParseCertificate: ", err)
+	}
+	return cert, key
+}
+
+func TestVerify_X5C(t *testing.T) {
+	caCert, caKey := testCertificate(t, "root", nil, nil)
+	leafCert, leafKey := testCertificate(t, "leaf", caCert, caKey)
+	otherCA, _ := testCertificate(t, "other root", nil, nil)
+
+	header := Header{
+		Alg: ALG_ES256,
+		X5c: []string{base64.StdEncoding.EncodeToString(leafCert.Raw)},
+	}
+	jws, err := SignWithHeader(signTestPayload, header, leafKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+
+	// the provider must not be consulted when the chain is used
+	kp := ProviderFromKey([]byte("unused"))
+	decoded, _, err := VerifyAndDecodeWithOptions(jws, kp, WithX5CVerification(roots))
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if len(decoded.X5c) != 1 || decoded.X5c[0] != header.X5c[0] {
+		t.Fatalf("x5c decoded incorrectly: %v", decoded.X5c)
+	}
+
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(otherCA)
+	_, _, err = VerifyAndDecodeWithOptions(jws, kp, WithX5CVerification(otherRoots))
+	if !errors.Is(err, ErrCertificateChainInvalid) {
+		t.Fatalf("Expected ErrCertificateChainInvalid. Got %v", err)
+	}
+
+	// the system roots are never trusted implicitly
+	_, _, err = VerifyAndDecodeWithOptions(jws, kp, WithX5CVerification(nil))
+	if !errors.Is(err, ErrCertificateChainInvalid) {
+		t.Fatalf("Expected ErrCertificateChainInvalid without roots. Got %v", err)
+	}
+
+	// the leaf identity check
+	_, _, err = VerifyAndDecodeWithOptions(jws, kp, WithX5CVerification(roots), WithX5CLeafValidator(MatchX5CLeafDNSName("other.example", "leaf.example")))
+	if err != nil {
+		t.Fatal("Verify with matching leaf name: ", err)
+	}
+	_, _, err = VerifyAndDecodeWithOptions(jws, kp, WithX5CVerification(roots), WithX5CLeafValidator(MatchX5CLeafDNSName("other.example")))
+	if !errors.Is(err, ErrCertificateChainInvalid) {
+		t.Fatalf("Expected ErrCertificateChainInvalid for a mismatched leaf. Got %v", err)
+	}
+
+	// without the option the provider's key is used
+	if _, err := VerifyAndDecode(jws, kp); err == nil {
+		t.Fatal("Verified with the wrong provider key")
+	}
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(&leafKey.PublicKey)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+}
+
+func TestVerify_X5C_WrongSigner(t *testing.T) {
+	caCert, caKey := testCertificate(t, "root", nil, nil)
+	leafCert, _ := testCertificate(t, "leaf", caCert, caKey)
+	_, otherKey := testCertificate(t, "other", caCert, caKey)
+
+	header := Header{
+		Alg: ALG_ES256,
+		X5c: []string{base64.StdEncoding.EncodeToString(leafCert.Raw)},
+	}
+	jws, err := SignWithHeader(signTestPayload, header, otherKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	if _, _, err := VerifyAndDecodeWithOptions(jws, nil, WithX5CVerification(roots)); err == nil {
+		t.Fatal("Verified a token not signed by the leaf certificate")
+	}
+
+	header.X5c = []string{"not base64!"}
+	jws, err = SignWithHeader(signTestPayload, header, otherKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	_, _, err = VerifyAndDecodeWithOptions(jws, nil, WithX5CVerification(roots))
+	if !errors.Is(err, ErrCertificateChainInvalid) {
+		t.Fatalf("Expected ErrCertificateChainInvalid. Got %v", err)
+	}
+}
+
+func TestProviderFromX509Certificate(t *testing.T) {
+	cert, key := testCertificate(t, "leaf", nil, nil)
+	jws, err := Sign(signTestPayload, ALG_ES256, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+
+	kp, err := ProviderFromX509Certificate(cert)
+	if err != nil {
+		t.Fatal("ProviderFromX509Certificate: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	// a key usage mismatch is a warning, not a failure
+	restricted := *cert
+	restricted.KeyUsage = x509.KeyUsageCertSign
+	kp, err = ProviderFromX509Certificate(&restricted)
+	if !errors.Is(err, ErrKeyUsageMismatch) {
+		t.Fatalf("Expected ErrKeyUsageMismatch. Got %v", err)
+	}
+	if kp == nil {
+		t.Fatal("No provider returned with the warning")
+	}
+	if _, err := VerifyAndDecode(jws, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	unrestricted := *cert
+	unrestricted.KeyUsage = 0
+	if _, err := ProviderFromX509Certificate(&unrestricted); err != nil {
+		t.Fatal("ProviderFromX509Certificate: ", err)
+	}
+
+	if _, err := ProviderFromX509Certificate(nil); err == nil {
+		t.Fatal("Expected an error for a nil certificate")
+	}
+}
+
+func TestX5CChain(t *testing.T) {
+	root, rootKey := testCertificate(t, "root", nil, nil)
+	leaf, _ := testCertificate(t, "leaf", root, rootKey)
+
+	x5c, err := BuildX5CChain([]*x509.Certificate{leaf, root})
+	if err != nil {
+		t.Fatal("BuildX5CChain: ", err)
+	}
+
+	var encoded []string
+	if err := json.Unmarshal([]byte(x5c), &encoded); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+	if len(encoded) != 2 || encoded[0] != base64.StdEncoding.EncodeToString(leaf.Raw) {
+		t.Fatalf("Unexpected x5c %s", x5c)
+	}
+
+	// the array drops straight into a header
+	var header Header
+	if err := json.Unmarshal([]byte(`{"alg":"ES256","x5c":`+x5c+`}`), &header); err != nil {
+		t.Fatal("Unmarshal header: ", err)
+	}
+	if len(header.X5c) != 2 {
+		t.Fatalf("Unexpected header x5c %v", header.X5c)
+	}
+
+	certs, err := ParseX5CChain(x5c)
+	if err != nil {
+		t.Fatal("ParseX5CChain: ", err)
+	}
+	if len(certs) != 2 || !certs[0].Equal(leaf) || !certs[1].Equal(root) {
+		t.Fatal("Chain did not round trip")
+	}
+
+	if _, err := BuildX5CChain(nil); err == nil {
+		t.Fatal("Built an empty chain")
+	}
+	if _, err := BuildX5CChain([]*x509.Certificate{leaf, nil}); err == nil {
+		t.Fatal("Built a chain with a nil certificate")
+	}
+	for _, bad := range []string{``, `[]`, `"abc"`, `["!!"]`, `["AAAA"]`} {
+		if _, err := ParseX5CChain(bad); !errors.Is(err, ErrCertificateChainInvalid) {
+			t.Fatalf("ParseX5CChain %q: expected ErrCertificateChainInvalid. Got %v", bad, err)
+		}
+	}
+}
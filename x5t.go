@@ -0,0 +1,73 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+)
+
+// Look up keys by the thumbprint of their X.509 certificate
+type X5TKeyStore interface {
+	GetKeyByThumbprint(thumbprint string) (crypto.PublicKey, error)
+}
+
+// Compute the x5t header value for a certificate: the base64url
+// encoded SHA-1 digest of its DER encoding (RFC 7515 Section 4.1.7)
+func X5TFromCert(cert *x509.Certificate) string {
+	digest := sha1.Sum(cert.Raw)
+	return safeEncode(digest[:])
+}
+
+// Compute the x5t#S256 header value for a certificate: the base64url
+// encoded SHA-256 digest of its DER encoding (RFC 7515 Section 4.1.8)
+func X5TS256FromCert(cert *x509.Certificate) string {
+	digest := sha256.Sum256(cert.Raw)
+	return safeEncode(digest[:])
+}
+
+// find the key for a token identified only by its thumbprint,
+// preferring the collision resistant x5t#S256 over x5t
+func lookupX5T(header Header, store X5TKeyStore) (crypto.PublicKey, bool) {
+	if store == nil || header.Kid != "" {
+		return nil, false
+	}
+
+	thumbprint := header.X5tS256
+	if thumbprint == "" {
+		thumbprint = header.X5t
+	}
+	if thumbprint == "" {
+		return nil, false
+	}
+
+	key, err := store.GetKeyByThumbprint(thumbprint)
+	if err != nil || key == nil {
+		return nil, false
+	}
+	return key, true
+}
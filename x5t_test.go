@@ -0,0 +1,130 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+// thumbprint store backed by a map
+type testThumbprintStore map[string]crypto.PublicKey
+
+func (s testThumbprintStore) GetKeyByThumbprint(thumbprint string) (crypto.PublicKey, error) {
+	key, ok := s[thumbprint]
+	if !ok {
+		return nil, errors.New("Unknown thumbprint")
+	}
+	return key, nil
+}
+
+func TestX5TFromCert(t *testing.T) {
+	cert, _ := testCertificate(t, "leaf", nil, nil)
+	digest := sha1.Sum(cert.Raw)
+	if got, expected := X5TFromCert(cert), base64.RawURLEncoding.EncodeToString(digest[:]); got != expected {
+		t.Fatalf("Expected %s. Got %s", expected, got)
+	}
+}
+
+func TestX5TS256FromCert(t *testing.T) {
+	cert, _ := testCertificate(t, "leaf", nil, nil)
+	digest := sha256.Sum256(cert.Raw)
+	if got, expected := X5TS256FromCert(cert), base64.RawURLEncoding.EncodeToString(digest[:]); got != expected {
+		t.Fatalf("Expected %s. Got %s", expected, got)
+	}
+}
+
+func TestVerify_X5TS256Preferred(t *testing.T) {
+	cert, key := testCertificate(t, "leaf", nil, nil)
+	otherCert, _ := testCertificate(t, "other", nil, nil)
+	store := testThumbprintStore{
+		X5TS256FromCert(cert): cert.PublicKey,
+		X5TFromCert(cert):     otherCert.PublicKey,
+	}
+
+	header := Header{Alg: ALG_ES256, X5t: X5TFromCert(cert), X5tS256: X5TS256FromCert(cert)}
+	jws, err := SignWithHeader(signTestPayload, header, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	decoded, _, err := VerifyAndDecodeWithHeader(jws, nil, WithX5TKeyStore(store))
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if decoded.X5tS256 != header.X5tS256 {
+		t.Fatalf("Unexpected x5t#S256 %q", decoded.X5tS256)
+	}
+
+	header.X5t = ""
+	jws, err = SignWithHeader(signTestPayload, header, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, nil, WithX5TKeyStore(store)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+}
+
+func TestVerify_X5TKeyStore(t *testing.T) {
+	cert, key := testCertificate(t, "leaf", nil, nil)
+	otherCert, otherKey := testCertificate(t, "other", nil, nil)
+	store := testThumbprintStore{X5TFromCert(cert): cert.PublicKey}
+
+	jws, err := SignWithHeader(signTestPayload, Header{Alg: ALG_ES256, X5t: X5TFromCert(cert)}, key)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, nil, WithX5TKeyStore(store)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if _, _, err := VerifyAndDecodeWithOptions(jws, nil, VerifyOptionsToFunctional(VerifyOptions{X5TKeyStore: store})...); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, nil); err == nil {
+		t.Fatal("Verified without a store or provider")
+	}
+
+	// a kid takes precedence over the thumbprint
+	jws, err = SignWithHeader(signTestPayload, Header{Alg: ALG_ES256, X5t: X5TFromCert(cert), Kid: "other"}, otherKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(otherCert.PublicKey), WithX5TKeyStore(store)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	// unknown thumbprints fall back to the provider
+	jws, err = SignWithHeader(signTestPayload, Header{Alg: ALG_ES256, X5t: X5TFromCert(otherCert)}, otherKey)
+	if err != nil {
+		t.Fatal("Sign: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(otherCert.PublicKey), WithX5TKeyStore(store)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+}